@@ -0,0 +1,281 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/migrate"
+	"github.com/okamuuu/go-user-app/internal/notify"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/seed"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+const usage = "usage: app [migrate up|down|status | seed | import csv|ndjson <file|-> | export [flags] | erase <user-id> <reason>]"
+
+// runCommand はサーバーを起動せずに管理用のサブコマンドを実行する
+func runCommand(db *gorm.DB, args []string) error {
+	switch args[0] {
+	case "migrate":
+		if len(args) != 2 {
+			return errors.New(usage)
+		}
+		return runMigrate(migrate.New(db, migrate.Migrations()), args[1])
+	case "seed":
+		// 開発用のダミーユーザーを投入する
+		seed.SeedUsers(db, 100)
+		return nil
+	case "import":
+		if len(args) != 3 {
+			return errors.New(usage)
+		}
+		return runImport(db, args[1], args[2])
+	case "export":
+		return runExport(db, args[1:])
+	case "erase":
+		if len(args) < 3 {
+			return errors.New(usage)
+		}
+		return runErase(db, args[1], strings.Join(args[2:], " "))
+	default:
+		return errors.New(usage)
+	}
+}
+
+func runMigrate(migrator *migrate.Migrator, action string) error {
+	switch action {
+	case "up":
+		applied, err := migrator.Up()
+		for _, m := range applied {
+			log.Printf("Applied migration %d_%s", m.Version, m.Name)
+		}
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			log.Println("Schema is up to date")
+		}
+		return nil
+	case "down":
+		m, err := migrator.Down()
+		if err != nil {
+			return err
+		}
+		log.Printf("Rolled back migration %d_%s", m.Version, m.Name)
+		return nil
+	case "status":
+		statuses, err := migrator.Status()
+		if err != nil {
+			return err
+		}
+		for _, s := range statuses {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = "applied at " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-32s %s\n", s.Version, s.Name, applied)
+		}
+		return nil
+	default:
+		return errors.New(usage)
+	}
+}
+
+// runImport はファイル（- なら標準入力）からユーザーを一括登録し、行ごとの結果を JSON で出力する
+func runImport(db *gorm.DB, format, path string) error {
+	var r io.Reader = os.Stdin
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	inviteExpiry, err := inviteExpiryFromEnv()
+	if err != nil {
+		return err
+	}
+	var notifier notify.Notifier = notify.NewLogNotifier()
+	if path := os.Getenv("NOTIFY_FILE"); path != "" {
+		notifier = notify.NewFileNotifier(path)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	invites := service.NewPasswordResetService(
+		userRepo,
+		repository.NewPasswordResetRepository(db),
+		auditService,
+		notifier,
+		os.Getenv("PASSWORD_RESET_URL"),
+		0,
+	)
+	importService := service.NewUserImportService(userRepo, invites, auditService, inviteExpiry)
+
+	report, err := importService.Import(context.Background(), format, r)
+	if report == nil {
+		return err
+	}
+	// 途中で止めた場合も、どこまで登録したかが分かるようにそれまでの結果を出力する
+	if err != nil {
+		report.Error = err.Error()
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if encErr := enc.Encode(report); encErr != nil {
+		return encErr
+	}
+	log.Printf("Imported %d users (created: %d, invited: %d, failed: %d)", report.Total, report.Created, report.Invited, report.Failed)
+	if err != nil {
+		return fmt.Errorf("import stopped after line %d: %w", lastImportLine(report), err)
+	}
+	return nil
+}
+
+// lastImportLine は登録を終えた最後の行の番号を返す（1行も登録していなければ 0）
+func lastImportLine(report *service.UserImportReport) int {
+	if len(report.Rows) == 0 {
+		return 0
+	}
+	return report.Rows[len(report.Rows)-1].Line
+}
+
+// inviteExpiryFromEnv は招待リンクの有効期限を返す（USER_INVITE_EXPIRE_HOURS、未指定なら72時間）
+func inviteExpiryFromEnv() (time.Duration, error) {
+	s := os.Getenv("USER_INVITE_EXPIRE_HOURS")
+	if s == "" {
+		return 72 * time.Hour, nil
+	}
+	hours, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid USER_INVITE_EXPIRE_HOURS: %w", err)
+	}
+	return time.Duration(hours) * time.Hour, nil
+}
+
+// runErase はユーザーの個人データを消去し、消去の記録を出力する（取り消しはできない）。
+// 例: app erase 42 "privacy request #1234"
+func runErase(db *gorm.DB, id, reason string) error {
+	userID, err := strconv.ParseUint(id, 10, 32)
+	if err != nil {
+		return fmt.Errorf("invalid user id: %s", id)
+	}
+
+	userRepo := repository.NewUserRepository(db)
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	privacyService := service.NewPrivacyService(
+		userRepo,
+		repository.NewRefreshTokenRepository(db),
+		repository.NewErasureRepository(db),
+		service.NewMFAService(userRepo, repository.NewMFARepository(db), auditService, ""),
+		auditService,
+	)
+	record, err := privacyService.Erase(context.Background(), uint(userID), reason)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(record); err != nil {
+		return err
+	}
+	log.Printf("Erased user %d (erasure record: %d)", record.UserID, record.ID)
+	return nil
+}
+
+// runExport は条件に合うユーザーをファイル（- なら標準出力）に書き出す。
+// 例: app export -format parquet -columns id,email,created_at -role member -o users.parquet
+func runExport(db *gorm.DB, args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := flags.String("format", service.ExportFormatCSV, "output format (csv, ndjson, parquet)")
+	columns := flags.String("columns", "", "comma-separated columns (default all): "+strings.Join(service.UserExportColumnNames(), ", "))
+	output := flags.String("o", "-", "output file (- for stdout)")
+	sortField := flags.String("sort", "", "sort field (created_at, name, email)")
+	desc := flags.Bool("desc", false, "sort in descending order")
+	query := flags.String("q", "", "partial match on name or email")
+	emailDomain := flags.String("email-domain", "", "email domain (e.g. example.com)")
+	role := flags.String("role", "", "role (admin, support, member)")
+	verified := flags.String("verified", "", "email verified (true, false)")
+	createdFrom := flags.String("created-from", "", "created at or after (RFC3339)")
+	createdTo := flags.String("created-to", "", "created before (RFC3339)")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	params := service.UserExportParams{
+		Format: *format,
+		Filter: domain.UserFilter{Query: *query, EmailDomain: *emailDomain, Role: domain.Role(*role)},
+		Sort:   domain.UserSort{Desc: *desc},
+	}
+	if *columns != "" {
+		for _, name := range strings.Split(*columns, ",") {
+			params.Columns = append(params.Columns, strings.TrimSpace(name))
+		}
+	}
+	field, ok := domain.ParseUserSortField(*sortField)
+	if !ok {
+		return fmt.Errorf("invalid -sort: %s", *sortField)
+	}
+	params.Sort.Field = field
+	if *role != "" {
+		if _, err := domain.ParseRole(*role); err != nil {
+			return fmt.Errorf("invalid -role: %w", err)
+		}
+	}
+	if *verified != "" {
+		v, err := strconv.ParseBool(*verified)
+		if err != nil {
+			return fmt.Errorf("invalid -verified: %w", err)
+		}
+		params.Filter.Verified = &v
+	}
+	for _, f := range []struct {
+		name  string
+		value string
+		dest  **time.Time
+	}{{"created-from", *createdFrom, &params.Filter.CreatedFrom}, {"created-to", *createdTo, &params.Filter.CreatedTo}} {
+		if f.value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, f.value)
+		if err != nil {
+			return fmt.Errorf("invalid -%s: %w", f.name, err)
+		}
+		*f.dest = &t
+	}
+	if err := params.Validate(); err != nil {
+		return err
+	}
+
+	w := os.Stdout
+	if *output != "-" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	exportService := service.NewUserExportService(repository.NewUserRepository(db), service.NewAuditService(repository.NewAuditRepository(db)))
+	count, err := exportService.Export(context.Background(), params, w)
+	if err != nil {
+		return err
+	}
+	log.Printf("Exported %d users", count)
+	return nil
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"os"
@@ -11,16 +12,20 @@ import (
 	"github.com/joho/godotenv"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
-	"gorm.io/driver/sqlite"
-	"gorm.io/gorm"
 
 	docs "github.com/okamuuu/go-user-app/cmd/docs"
+	"github.com/okamuuu/go-user-app/internal/cursor"
 	"github.com/okamuuu/go-user-app/internal/domain"
 	"github.com/okamuuu/go-user-app/internal/handler"
+	"github.com/okamuuu/go-user-app/internal/job"
 	"github.com/okamuuu/go-user-app/internal/middleware"
+	"github.com/okamuuu/go-user-app/internal/migrate"
+	"github.com/okamuuu/go-user-app/internal/notify"
+	"github.com/okamuuu/go-user-app/internal/policy"
 	"github.com/okamuuu/go-user-app/internal/repository"
 	"github.com/okamuuu/go-user-app/internal/seed"
 	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/okamuuu/go-user-app/internal/signing"
 )
 
 // @title           Go User App API
@@ -48,59 +53,259 @@ func main() {
 		log.Println("No .env file found, proceeding with environment variables")
 	}
 
+	// DB接続（DATABASE_URL が無ければ SQLite の app.db）
+	db, err := repository.Open(os.Getenv("DATABASE_URL"))
+	if err != nil {
+		log.Fatal("failed to connect database:", err)
+	}
+
+	// サブコマンド（migrate up|down|status, seed, import, export）
+	if len(os.Args) > 1 {
+		if err := runCommand(db, os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	// スキーマが最新でなければ起動しない
+	pending, err := migrate.New(db, migrate.Migrations()).Pending()
+	if err != nil {
+		log.Fatal("failed to check migrations:", err)
+	}
+	if len(pending) > 0 {
+		log.Fatalf("database schema is behind: %d pending migrations, run `migrate up` first", len(pending))
+	}
+	// FTS5 なしのビルドでマイグレーションを適用していた場合に備えて、検索の索引を作り直す
+	if err := migrate.EnsureUserSearchIndex(db); err != nil {
+		log.Fatal("failed to create user search index:", err)
+	}
+
 	jwtSecret := os.Getenv("JWT_SECRET")
-	expireHoursStr := os.Getenv("JWT_EXPIRE_HOURS")
-	expireHours, err := strconv.Atoi(expireHoursStr)
+	expireMinutes, err := strconv.Atoi(os.Getenv("JWT_EXPIRE_MINUTES"))
 	if err != nil {
-		log.Fatalf("Invalid JWT_EXPIRE_HOURS: %v", err)
+		log.Fatalf("Invalid JWT_EXPIRE_MINUTES: %v", err)
+	}
+	refreshExpireHours, err := strconv.Atoi(os.Getenv("REFRESH_TOKEN_EXPIRE_HOURS"))
+	if err != nil {
+		log.Fatalf("Invalid REFRESH_TOKEN_EXPIRE_HOURS: %v", err)
 	}
 
-	// DB接続
-	db, err := gorm.Open(sqlite.Open("app.db"), &gorm.Config{})
+	resetExpireMinutes, err := strconv.Atoi(os.Getenv("PASSWORD_RESET_EXPIRE_MINUTES"))
 	if err != nil {
-		log.Fatal("failed to connect database:", err)
+		log.Fatalf("Invalid PASSWORD_RESET_EXPIRE_MINUTES: %v", err)
+	}
+
+	verifyExpireHours, err := strconv.Atoi(os.Getenv("EMAIL_VERIFY_EXPIRE_HOURS"))
+	if err != nil {
+		log.Fatalf("Invalid EMAIL_VERIFY_EXPIRE_HOURS: %v", err)
+	}
+
+	deleteGraceDays, err := strconv.Atoi(os.Getenv("USER_DELETE_GRACE_DAYS"))
+	if err != nil {
+		log.Fatalf("Invalid USER_DELETE_GRACE_DAYS: %v", err)
+	}
+
+	// 一覧のカーソルに署名する鍵。RS256/EdDSA では JWT_SECRET が無いこともあるので、代わりには使わず必須にする
+	cursorSecret := []byte(os.Getenv("CURSOR_SECRET"))
+	if len(cursorSecret) == 0 {
+		log.Fatal("CURSOR_SECRET is required")
+	}
+
+	// 認可ポリシー（POLICY_FILE が無ければ組み込みのデフォルトポリシー）
+	policyEngine := policy.Default()
+	if path := os.Getenv("POLICY_FILE"); path != "" {
+		policyEngine, err = policy.Load(path)
+		if err != nil {
+			log.Fatalf("Invalid POLICY_FILE: %v", err)
+		}
 	}
 
-	// 起動時に全テーブルをドロップしてから再作成
-	// 学習用なので都度DBをリセットしている
-	db.Migrator().DropTable(&domain.User{})
-	if err := db.AutoMigrate(&domain.User{}); err != nil {
-		log.Fatal("failed to migrate database:", err)
+	// Idempotency-Key で再送されたリクエストに、保存したレスポンスを返す期間（未指定なら24時間）
+	idempotencyTTL := 24 * time.Hour
+	if s := os.Getenv("IDEMPOTENCY_TTL_HOURS"); s != "" {
+		hours, err := strconv.Atoi(s)
+		if err != nil {
+			log.Fatalf("Invalid IDEMPOTENCY_TTL_HOURS: %v", err)
+		}
+		idempotencyTTL = time.Duration(hours) * time.Hour
+	}
+
+	// 一括登録で送る招待リンクの有効期限
+	inviteExpiry, err := inviteExpiryFromEnv()
+	if err != nil {
+		log.Fatal(err)
 	}
-	seed.SeedUsers(db, 100)
 
-	// リポジトリ、サービス、ハンドラー初期化
+	// 認証アプリに表示されるサービス名
+	mfaIssuer := os.Getenv("MFA_ISSUER")
+	if mfaIssuer == "" {
+		mfaIssuer = "Go User App"
+	}
+
+	// JWT署名鍵
+	// JWT_SIGNING_KEY_FILE があれば RSA/Ed25519 で署名し、なければ JWT_SECRET による HS256 で署名する
+	var keys *signing.KeySet
+	if keyFile := os.Getenv("JWT_SIGNING_KEY_FILE"); keyFile != "" {
+		kid := os.Getenv("JWT_SIGNING_KEY_ID")
+		if kid == "" {
+			kid = "default"
+		}
+		keys, err = signing.LoadKeySet(kid, keyFile, os.Getenv("JWT_VERIFICATION_KEY_FILES"))
+		if err != nil {
+			log.Fatalf("Invalid JWT signing keys: %v", err)
+		}
+	} else {
+		keys = signing.NewHMACKeySet([]byte(jwtSecret))
+	}
+
+	// 初期管理者（ADMIN_EMAIL / ADMIN_PASSWORD が設定されている場合のみ）
+	if email := os.Getenv("ADMIN_EMAIL"); email != "" {
+		seed.SeedAdmin(db, email, os.Getenv("ADMIN_PASSWORD"))
+	}
+
+	// リポジトリ初期化
 	userRepo := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepo)
-	authService := service.NewAuthService(userRepo, []byte(jwtSecret), time.Duration(expireHours)*time.Hour)
-	userHandler := handler.NewUserHandler(userService)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revocationRepo := repository.NewRevocationRepository(db)
+	passwordResetRepo := repository.NewPasswordResetRepository(db)
+	emailVerificationRepo := repository.NewEmailVerificationRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	idempotencyRepo := repository.NewIdempotencyRepository(db)
+	erasureRepo := repository.NewErasureRepository(db)
+
+	// 通知の送信先（NOTIFY_FILE があればファイル、なければログに出力）
+	var notifier notify.Notifier = notify.NewLogNotifier()
+	if path := os.Getenv("NOTIFY_FILE"); path != "" {
+		notifier = notify.NewFileNotifier(path)
+	}
+
+	// サービス、ハンドラー初期化
+	auditService := service.NewAuditService(auditRepo)
+	emailVerificationService := service.NewEmailVerificationService(
+		userRepo,
+		emailVerificationRepo,
+		auditService,
+		notifier,
+		os.Getenv("EMAIL_VERIFY_URL"),
+		time.Duration(verifyExpireHours)*time.Hour,
+	)
+	mfaService := service.NewMFAService(userRepo, mfaRepo, auditService, mfaIssuer)
+	authService := service.NewAuthService(
+		userRepo,
+		emailVerificationService,
+		mfaService,
+		auditService,
+		refreshTokenRepo,
+		revocationRepo,
+		keys,
+		time.Duration(expireMinutes)*time.Minute,
+		time.Duration(refreshExpireHours)*time.Hour,
+	)
+	userService := service.NewUserService(userRepo, emailVerificationService, authService, auditService, cursor.NewCodec(cursorSecret), time.Duration(deleteGraceDays)*24*time.Hour)
+	passwordResetService := service.NewPasswordResetService(
+		userRepo,
+		passwordResetRepo,
+		auditService,
+		notifier,
+		os.Getenv("PASSWORD_RESET_URL"),
+		time.Duration(resetExpireMinutes)*time.Minute,
+	)
+	userImportService := service.NewUserImportService(userRepo, passwordResetService, auditService, inviteExpiry)
+	userExportService := service.NewUserExportService(userRepo, auditService)
+	privacyService := service.NewPrivacyService(userRepo, refreshTokenRepo, erasureRepo, mfaService, auditService)
+	userHandler := handler.NewUserHandler(userService, policyEngine)
+	userImportHandler := handler.NewUserImportHandler(userImportService, policyEngine)
+	userExportHandler := handler.NewUserExportHandler(userExportService, policyEngine)
+	privacyHandler := handler.NewPrivacyHandler(privacyService, policyEngine)
 	authHandler := handler.NewAuthHandler(authService)
+	jwksHandler := handler.NewJWKSHandler(keys)
+	passwordHandler := handler.NewPasswordHandler(passwordResetService)
+	emailHandler := handler.NewEmailHandler(emailVerificationService)
+	mfaHandler := handler.NewMFAHandler(mfaService)
+	policyHandler := handler.NewPolicyHandler(policyEngine, userService)
+	auditHandler := handler.NewAuditHandler(auditService)
+
+	// バックグラウンドジョブ
+	go job.Every(context.Background(), "delete-expired-revoked-tokens", time.Hour, func() error {
+		// 有効期限を過ぎた失効済みトークンは保持不要なので掃除しておく
+		return revocationRepo.DeleteExpired(time.Now())
+	})
+	go job.Every(context.Background(), "delete-expired-idempotency-keys", time.Hour, func() error {
+		// 保存期間を過ぎたレスポンスは再送に使わないので掃除しておく
+		_, err := idempotencyRepo.DeleteExpired(time.Now())
+		return err
+	})
+	go job.Every(context.Background(), "purge-deleted-users", time.Hour, func() error {
+		// 猶予期間を過ぎた論理削除済みユーザーを物理削除する
+		purged, err := userService.PurgeDeletedUsers(context.Background())
+		if purged > 0 {
+			log.Printf("Purged %d deleted users", purged)
+		}
+		return err
+	})
 
 	// Ginルーター作成
 	r := gin.Default()
 	docs.SwaggerInfo.BasePath = "/api"
 
+	// 他サービスがトークンを検証するための公開鍵
+	r.GET("/.well-known/jwks.json", jwksHandler.JWKS)
+
 	api := r.Group("/api")
 
-	// 認証不要ルート（サインアップ・ログイン）
-	api.POST("/signup", authHandler.Signup)
+	// 再送されても一度だけ処理する（Idempotency-Key ヘッダーがある場合）
+	idempotent := middleware.Idempotency(idempotencyRepo, idempotencyTTL)
+
+	// 認証不要ルート（サインアップ・ログイン・トークンリフレッシュ・パスワード再設定・メールアドレス確認）
+	api.POST("/signup", idempotent, authHandler.Signup)
 	api.POST("/login", authHandler.Login)
+	api.POST("/login/mfa", authHandler.LoginMFA)
+	api.POST("/token/refresh", authHandler.Refresh)
+	api.POST("/password/forgot", passwordHandler.Forgot)
+	api.POST("/password/reset", passwordHandler.Reset)
+	api.POST("/email/verify", emailHandler.Verify)
 
 	// 認証必要ルート
 	authorized := api.Group("/")
-	authorized.Use(middleware.AuthMiddleware([]byte(jwtSecret)))
+	authorized.Use(middleware.AuthMiddleware(keys, authService))
 	authorized.GET("/me", userHandler.Me)
+	authorized.GET("/me/export", privacyHandler.ExportMe)
+	authorized.POST("/logout", authHandler.Logout)
+	authorized.POST("/logout/all", authHandler.LogoutAll)
+	authorized.POST("/email/verify/resend", emailHandler.Resend)
+	authorized.POST("/mfa/totp/enroll", mfaHandler.Enroll)
+	authorized.POST("/mfa/totp/activate", mfaHandler.Activate)
+	authorized.POST("/mfa/totp/disable", mfaHandler.Disable)
 
-	// ユーザーCRUDルート
+	// ユーザーCRUDルート（認可はハンドラー内でポリシーに基づいて行う）
 	userRoutes := authorized.Group("/users")
 	{
+		userRoutes.GET("/search", userHandler.SearchUsers)
+		userRoutes.POST("/import", userImportHandler.Import)
+		userRoutes.GET("/export", userExportHandler.Export)
 		userRoutes.GET("/:id", userHandler.GetUser)
 		userRoutes.PUT("/:id", userHandler.UpdateUser)
+		userRoutes.PATCH("/:id", userHandler.PatchUser)
 		userRoutes.DELETE("/:id", userHandler.DeleteUser)
 		userRoutes.GET("", userHandler.GetUsers)
-		userRoutes.POST("", userHandler.CreateUser)
+		userRoutes.POST("", idempotent, userHandler.CreateUser)
+		userRoutes.POST("/:id/restore", userHandler.RestoreUser)
+		userRoutes.POST("/:id/erase", privacyHandler.EraseUser)
+
+		// ロール変更（admin）
+		userRoutes.PUT("/:id/role", middleware.RequirePermission(domain.PermUsersManageRoles), authHandler.ChangeRole)
+		// 乗っ取られたアカウントのセッション失効（admin, support）
+		userRoutes.POST("/:id/logout-all", middleware.RequirePermission(domain.PermSessionsRevoke), authHandler.RevokeUserSessions)
 	}
 
+	// 認可判定のドライラン（admin）
+	authorized.POST("/policy/explain", middleware.RequireRole(domain.RoleAdmin), policyHandler.Explain)
+
+	// 監査ログ（admin）
+	authorized.GET("/audit", middleware.RequirePermission(domain.PermAuditRead), auditHandler.List)
+	authorized.GET("/erasures", middleware.RequirePermission(domain.PermAuditRead), privacyHandler.ListErasures)
+
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	// サーバー起動
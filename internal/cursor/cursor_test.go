@@ -0,0 +1,48 @@
+package cursor
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type position struct {
+	ID uint `json:"id"`
+}
+
+func TestEncodeDecode(t *testing.T) {
+	codec := NewCodec([]byte("secret"))
+
+	s, err := codec.Encode(position{ID: 42})
+	require.NoError(t, err)
+
+	var got position
+	require.NoError(t, codec.Decode(s, &got))
+	assert.Equal(t, uint(42), got.ID)
+}
+
+func TestDecodeRejectsTampering(t *testing.T) {
+	codec := NewCodec([]byte("secret"))
+	s, err := codec.Encode(position{ID: 42})
+	require.NoError(t, err)
+
+	forged, err := codec.Encode(position{ID: 1})
+	require.NoError(t, err)
+	body, _, _ := strings.Cut(forged, ".")
+	_, sig, _ := strings.Cut(s, ".")
+
+	var got position
+	for _, tt := range []string{
+		"",
+		"not-a-cursor",
+		body + "." + sig, // 他のカーソルの署名を付け替えたもの
+		s + "x",
+	} {
+		assert.ErrorIs(t, codec.Decode(tt, &got), ErrInvalid, tt)
+	}
+
+	// 別の鍵で署名されたカーソルも受け付けない
+	assert.ErrorIs(t, NewCodec([]byte("other")).Decode(s, &got), ErrInvalid)
+}
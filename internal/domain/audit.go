@@ -0,0 +1,62 @@
+package domain
+
+import "time"
+
+// 監査ログに記録する操作
+const (
+	AuditUserCreated  = "user.created"
+	AuditUserUpdated  = "user.updated"
+	AuditUserDeleted  = "user.deleted"
+	AuditUserRestored = "user.restored"
+	AuditUserPurged   = "user.purged"
+	AuditRoleChanged  = "user.role_changed"
+	// AuditUsersExported はユーザーの一覧を書き出した操作（対象のユーザーは記録しない）
+	AuditUsersExported = "users.exported"
+	// AuditUserDataExported は本人が自分のデータを書き出した操作
+	AuditUserDataExported = "user.data_exported"
+	// AuditUserErased はユーザーの個人データを消去した操作（変更前後の値は記録しない）
+	AuditUserErased = "user.erased"
+	// AuditEmailChanged は確認リンクで新しいメールアドレスに切り替わった操作
+	AuditEmailChanged = "user.email_changed"
+
+	AuditSignup             = "auth.signup"
+	AuditLogin              = "auth.login"
+	AuditLoginFailed        = "auth.login_failed"
+	AuditMFAChallenged      = "auth.mfa_challenged"
+	AuditMFALogin           = "auth.mfa_login"
+	AuditTokenRefreshed     = "auth.token_refreshed"
+	AuditRefreshTokenReused = "auth.refresh_token_reused"
+	AuditLogout             = "auth.logout"
+	AuditLogoutAll          = "auth.logout_all"
+	AuditPasswordReset      = "auth.password_reset"
+	AuditMFAEnabled         = "auth.mfa_enabled"
+	AuditMFADisabled        = "auth.mfa_disabled"
+)
+
+// AuditTargetUser は監査ログの対象種別（ユーザー）
+const AuditTargetUser = "user"
+
+// AuditEvent は誰がいつどのユーザーに対して何をしたかの記録
+type AuditEvent struct {
+	ID         uint
+	ActorID    *uint // 操作したユーザー（未認証のリクエストやシステムによる操作なら nil）
+	Action     string
+	TargetType string
+	TargetID   *uint
+	IP         string
+	UserAgent  string
+	// 変更前後の値（変更のあった項目のみ。パスワードなどの秘密情報は伏せ字にする）
+	Before    map[string]interface{}
+	After     map[string]interface{}
+	CreatedAt time.Time
+}
+
+// AuditFilter は監査ログの検索条件（ゼロ値の項目は条件に含めない）
+type AuditFilter struct {
+	ActorID  *uint
+	TargetID *uint
+	UserID   *uint // 操作したユーザー、または対象のユーザーのどちらか
+	Action   string
+	From     *time.Time
+	To       *time.Time
+}
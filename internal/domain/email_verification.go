@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// EmailVerificationToken はメールアドレスの所有確認用の一度きりのトークン
+// Email は確認対象のアドレスで、メールアドレス変更時は変更後のアドレスになる
+type EmailVerificationToken struct {
+	ID        uint
+	UserID    uint
+	Email     string
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// IsUsable は未使用かつ有効期限内かどうかを返す
+func (t *EmailVerificationToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
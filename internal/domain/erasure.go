@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// ErasureRecord はユーザーのデータを消去した記録。
+// 消去したこと自体を後から説明できるよう、個人を特定できる情報を含めずに保存し続ける
+type ErasureRecord struct {
+	ID          uint
+	UserID      uint   // 消去したユーザーの ID（ユーザーの行はもう無い）
+	RequestedBy *uint  // 消去を実行した管理者（CLI からの実行なら nil）
+	Reason      string // 消去の根拠（請求の受付番号など。個人情報は書かない）
+	// テーブルごとに削除した行数と、匿名化した行数
+	Deleted    map[string]int64
+	Anonymized map[string]int64
+	ErasedAt   time.Time
+}
+
+// ErasedValue は匿名化した監査ログで、元の値の代わりに入れる値
+const ErasedValue = "[ERASED]"
@@ -0,0 +1,69 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+)
+
+// エラーの種類。個々のエラーは errors.Is でいずれかの種類に一致し、
+// ハンドラーはこの種類から HTTP のステータスを決める
+var (
+	ErrNotFound     = errors.New("not found")
+	ErrConflict     = errors.New("conflict")
+	ErrForbidden    = errors.New("forbidden")
+	ErrValidation   = errors.New("validation failed")
+	ErrUnauthorized = errors.New("unauthorized")
+	// ErrPreconditionFailed は更新の前提（読み込んだ時点のバージョンなど）が満たされない場合
+	ErrPreconditionFailed = errors.New("precondition failed")
+	// ErrTooManyRequests は失敗が続いたなどの理由で、しばらく受け付けない場合
+	ErrTooManyRequests = errors.New("too many requests")
+)
+
+// Error は種類と機械可読なコードを持つエラー
+type Error struct {
+	Kind    error  // ErrNotFound などのエラーの種類
+	Code    string // クライアントがエラーを判別するための変わらないコード（例: user_not_found）
+	Message string
+}
+
+func NewError(kind error, code, message string) *Error {
+	return &Error{Kind: kind, Code: code, Message: message}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap により errors.Is(err, ErrNotFound) のように種類で判定できる
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// FieldError は入力項目ごとの検証エラー
+type FieldError struct {
+	Field   string // リクエストでの項目名（例: email）
+	Code    string // 検証の種類（例: required, email, min）
+	Message string
+}
+
+// ValidationError は入力の検証エラー。errors.Is で ErrValidation に一致する
+type ValidationError struct {
+	Fields []FieldError
+}
+
+// NewValidationError は1つの項目についての検証エラーを作る
+func NewValidationError(field, code, message string) *ValidationError {
+	return &ValidationError{Fields: []FieldError{{Field: field, Code: code, Message: message}}}
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Fields))
+	for _, f := range e.Fields {
+		msgs = append(msgs, f.Field+": "+f.Message)
+	}
+	return "validation failed: " + strings.Join(msgs, ", ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord は Idempotency-Key を付けたリクエストの処理状況と、そのレスポンス。
+// 同じキーで再送されたリクエストは処理せずに、保存したレスポンスをそのまま返す
+type IdempotencyRecord struct {
+	KeyHash     string // キーとそのスコープ（メソッド・パス・ユーザー）のハッシュ
+	Fingerprint string // リクエストの内容（メソッド・URL・ボディ）のハッシュ
+	StatusCode  int    // 処理中は 0
+	Header      map[string][]string
+	Body        []byte
+	// 処理中は最初のリクエストが終わるのを待つ期限、完了後はレスポンスを保存しておく期限
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// IsCompleted は最初のリクエストの処理が終わり、レスポンスが保存されているかどうかを返す
+func (r *IdempotencyRecord) IsCompleted() bool {
+	return r.StatusCode != 0
+}
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+// MFACredential はユーザーの TOTP 設定
+// EnabledAt が nil の間は登録途中（コード確認待ち）で、ログイン時には要求しない
+type MFACredential struct {
+	UserID       uint
+	Secret       string // Base32 エンコードされた TOTP シークレット
+	EnabledAt    *time.Time
+	LastUsedStep int64 // 同じコードの再利用を防ぐため、最後に使われたタイムステップを保持する
+	// 続けてコードを間違えた回数と、間違いが続いたためにコードを受け付けない期限
+	FailedAttempts int
+	LockedUntil    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// IsEnabled は MFA が有効化済みかどうかを返す
+func (c *MFACredential) IsEnabled() bool {
+	return c.EnabledAt != nil
+}
+
+// IsLocked はコードの間違いが続いたため、コードを受け付けない期間中かどうかを返す
+func (c *MFACredential) IsLocked(now time.Time) bool {
+	return c.LockedUntil != nil && now.Before(*c.LockedUntil)
+}
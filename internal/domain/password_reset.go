@@ -0,0 +1,19 @@
+package domain
+
+import "time"
+
+// PasswordResetToken はパスワード再設定用の一度きりのトークン
+// 平文のトークンはメール等で本人にのみ送り、DBにはハッシュだけを保存する
+type PasswordResetToken struct {
+	ID        uint
+	UserID    uint
+	TokenHash string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// IsUsable は未使用かつ有効期限内かどうかを返す
+func (t *PasswordResetToken) IsUsable(now time.Time) bool {
+	return t.UsedAt == nil && now.Before(t.ExpiresAt)
+}
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// RefreshToken はアクセストークン再発行用のリフレッシュトークン
+// 平文のトークンはクライアントにのみ渡し、DBにはハッシュだけを保存する
+type RefreshToken struct {
+	ID           uint
+	UserID       uint
+	FamilyID     string // 同じログインから派生したトークンの系列
+	TokenHash    string
+	ExpiresAt    time.Time
+	RevokedAt    *time.Time
+	ReplacedByID *uint
+	CreatedAt    time.Time
+}
+
+// IsExpired は有効期限切れかどうかを返す
+func (t *RefreshToken) IsExpired(now time.Time) bool {
+	return !now.Before(t.ExpiresAt)
+}
+
+// IsRevoked は失効済み（ローテーション済みを含む）かどうかを返す
+func (t *RefreshToken) IsRevoked() bool {
+	return t.RevokedAt != nil
+}
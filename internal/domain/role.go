@@ -0,0 +1,54 @@
+package domain
+
+import "fmt"
+
+// Role はユーザーの役割
+type Role string
+
+const (
+	RoleAdmin   Role = "admin"
+	RoleSupport Role = "support"
+	RoleMember  Role = "member"
+)
+
+// Permission はロールに付与される操作権限
+// ユーザーの参照・作成・更新・削除は権限ではなくポリシー（internal/policy）で認可します。
+type Permission string
+
+const (
+	PermUsersManageRoles Permission = "users:manage_roles"
+	PermSessionsRevoke   Permission = "sessions:revoke"
+	PermAuditRead        Permission = "audit:read"
+)
+
+// rolePermissions はロールごとの権限
+var rolePermissions = map[Role][]Permission{
+	RoleAdmin: {
+		PermUsersManageRoles,
+		PermSessionsRevoke,
+		PermAuditRead,
+	},
+	RoleSupport: {
+		PermSessionsRevoke,
+	},
+	RoleMember: {},
+}
+
+// ParseRole は文字列をロールに変換する
+func ParseRole(s string) (Role, error) {
+	role := Role(s)
+	if _, ok := rolePermissions[role]; !ok {
+		return "", fmt.Errorf("unknown role: %q", s)
+	}
+	return role, nil
+}
+
+// Can はロールが権限を持っているかどうかを返す
+func (r Role) Can(p Permission) bool {
+	for _, granted := range rolePermissions[r] {
+		if granted == p {
+			return true
+		}
+	}
+	return false
+}
@@ -1,7 +1,9 @@
 package domain
 
 import (
-	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
 	"time"
 )
 
@@ -10,21 +12,89 @@ type User struct {
 	Name      string
 	Email     string
 	Password  string // 本当はハッシュ化して扱う想定
+	Role      Role
 	CreatedAt time.Time
 	UpdatedAt time.Time
+
+	// メールアドレスの所有確認が済んだ日時（未確認なら nil）
+	EmailVerifiedAt *time.Time
+	// 論理削除された日時（削除されていなければ nil）
+	DeletedAt *time.Time
+	// 楽観的排他制御のバージョン。ユーザーの行を更新するたびに1つ増える（ETag に使う）
+	Version uint
+}
+
+// IsEmailVerified はメールアドレスの所有確認が済んでいるかどうかを返す
+func (u *User) IsEmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// UserPatch はユーザーの部分更新。nil のフィールドは変更しない
+type UserPatch struct {
+	Name     *string
+	Email    *string
+	Password *string // 保存する形（ハッシュ化済み）
+}
+
+// IsEmpty は変更するフィールドが無いかどうかを返す
+func (p UserPatch) IsEmpty() bool {
+	return p.Name == nil && p.Email == nil && p.Password == nil
+}
+
+// NormalizeEmail はメールアドレスを保存・比較する形（前後の空白を除いた小文字）にする。
+// メールアドレスは大文字小文字を区別せずに一意とするため、保存・検索の前に必ず通す
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
 }
 
+// MinPasswordLength はパスワードの最小の長さ
+const MinPasswordLength = 6
+
+// MaxPasswordBytes はパスワードの最大の長さ（バイト数）。bcrypt は 72 バイトより長いパスワードを扱えない
+const MaxPasswordBytes = 72
+
+// ErrPasswordTooLong はパスワードが MaxPasswordBytes より長い場合の検証エラー
+var ErrPasswordTooLong = NewValidationError("password", "max", fmt.Sprintf("must be at most %d bytes", MaxPasswordBytes))
+
 // 新しいユーザーを作成するファクトリ関数
+// 名前・メールアドレス・パスワードを検証し、不正な項目があれば *ValidationError を返す。メールアドレスは正規化する
 func NewUser(name, email, password string) (*User, error) {
-	if name == "" || email == "" || password == "" {
-		return nil, errors.New("all fields are required")
+	ve := &ValidationError{}
+	if strings.TrimSpace(name) == "" {
+		ve.Fields = append(ve.Fields, FieldError{Field: "name", Code: "required", Message: "is required"})
+	}
+	email = NormalizeEmail(email)
+	if email == "" {
+		ve.Fields = append(ve.Fields, FieldError{Field: "email", Code: "required", Message: "is required"})
+	} else if !isEmail(email) {
+		ve.Fields = append(ve.Fields, FieldError{Field: "email", Code: "email", Message: "must be a valid email address"})
+	}
+	if password == "" {
+		ve.Fields = append(ve.Fields, FieldError{Field: "password", Code: "required", Message: "is required"})
+	} else if len(password) < MinPasswordLength {
+		ve.Fields = append(ve.Fields, FieldError{Field: "password", Code: "min",
+			Message: fmt.Sprintf("must be at least %d characters", MinPasswordLength)})
+	} else if len(password) > MaxPasswordBytes {
+		ve.Fields = append(ve.Fields, FieldError{Field: "password", Code: "max",
+			Message: fmt.Sprintf("must be at most %d bytes", MaxPasswordBytes)})
 	}
+	if len(ve.Fields) > 0 {
+		return nil, ve
+	}
+
 	return &User{
 		ID:        0,
 		Name:      name,
 		Email:     email,
 		Password:  password,
+		Role:      RoleMember,
 		CreatedAt: time.Now(),
 		UpdatedAt: time.Now(),
 	}, nil
 }
+
+// isEmail は表示名などを含まない、アドレスだけのメールアドレスかどうかを返す
+func isEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email
+}
@@ -0,0 +1,137 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// UserSortField はユーザー一覧を並び替えられる項目
+type UserSortField string
+
+const (
+	UserSortCreatedAt UserSortField = "created_at"
+	UserSortName      UserSortField = "name"
+	UserSortEmail     UserSortField = "email"
+)
+
+// UserSortFields は並び替えに使える項目の一覧
+var UserSortFields = []UserSortField{UserSortCreatedAt, UserSortName, UserSortEmail}
+
+// UserSort はユーザー一覧の並び順。値が同じユーザーは ID 順に並ぶ（Desc なら ID も降順）。
+// ゼロ値は作成日時の昇順
+type UserSort struct {
+	Field UserSortField
+	Desc  bool
+}
+
+// ParseUserSortField は文字列を並び替えの項目に変換する（空なら作成日時）
+func ParseUserSortField(s string) (UserSortField, bool) {
+	if s == "" {
+		return UserSortCreatedAt, true
+	}
+	for _, f := range UserSortFields {
+		if string(f) == s {
+			return f, true
+		}
+	}
+	return "", false
+}
+
+// UserFilter はユーザー一覧の絞り込み条件。ゼロ値の項目は条件にしない
+type UserFilter struct {
+	EmailDomain string     // メールアドレスのドメイン（大文字小文字を区別しない完全一致）
+	CreatedFrom *time.Time // この日時以降に作成
+	CreatedTo   *time.Time // この日時より前に作成
+	Role        Role
+	Verified    *bool  // メールアドレスの確認済み・未確認
+	Query       string // 名前・メールアドレスの部分一致（大文字小文字を区別しない）
+}
+
+// Match はユーザーが条件に合うかを返す
+func (f UserFilter) Match(u *User) bool {
+	if f.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(u.Email), "@"+strings.ToLower(f.EmailDomain)) {
+		return false
+	}
+	if f.CreatedFrom != nil && u.CreatedAt.Before(*f.CreatedFrom) {
+		return false
+	}
+	if f.CreatedTo != nil && !u.CreatedAt.Before(*f.CreatedTo) {
+		return false
+	}
+	if f.Role != "" && u.Role != f.Role {
+		return false
+	}
+	if f.Verified != nil && u.IsEmailVerified() != *f.Verified {
+		return false
+	}
+	if f.Query != "" {
+		q := strings.ToLower(f.Query)
+		if !strings.Contains(strings.ToLower(u.Name), q) && !strings.Contains(strings.ToLower(u.Email), q) {
+			return false
+		}
+	}
+	return true
+}
+
+// UserListQuery は FindAll の取得条件
+type UserListQuery struct {
+	Filter UserFilter
+	Sort   UserSort
+	Offset int
+	Limit  int
+}
+
+// UserKey は並び順の上でのユーザーの位置。並び替えの項目に対応する値と ID だけを使う
+type UserKey struct {
+	CreatedAt time.Time
+	Name      string
+	Email     string
+	ID        uint
+}
+
+// KeyOf はユーザーの並び順の上での位置を返す
+func KeyOf(u *User) UserKey {
+	return UserKey{CreatedAt: u.CreatedAt, Name: u.Name, Email: u.Email, ID: u.ID}
+}
+
+// Less は並び順 sort で k が other より前にあるかを返す
+func (k UserKey) Less(other UserKey, sort UserSort) bool {
+	c := k.compare(other, sort.Field)
+	if sort.Desc {
+		return c > 0
+	}
+	return c < 0
+}
+
+func (k UserKey) compare(other UserKey, field UserSortField) int {
+	var c int
+	switch field {
+	case UserSortName:
+		c = strings.Compare(k.Name, other.Name)
+	case UserSortEmail:
+		c = strings.Compare(k.Email, other.Email)
+	default:
+		c = k.CreatedAt.Compare(other.CreatedAt)
+	}
+	if c != 0 {
+		return c
+	}
+	switch {
+	case k.ID < other.ID:
+		return -1
+	case k.ID > other.ID:
+		return 1
+	}
+	return 0
+}
+
+// UserPageQuery は FindPage の取得条件。
+// After を指定するとその位置より後の先頭 Limit 件、Before を指定するとその位置より前の末尾 Limit 件を返す。
+// どちらの場合も結果は Sort の順に並ぶ
+type UserPageQuery struct {
+	Filter UserFilter
+	Sort   UserSort
+	After  *UserKey
+	Before *UserKey
+	Limit  int
+}
@@ -0,0 +1,43 @@
+package domain
+
+import (
+	"strings"
+	"unicode"
+)
+
+// 1回の検索で使う単語の上限
+const maxSearchTerms = 8
+
+// UserSearchHit は全文検索でヒットしたユーザー
+type UserSearchHit struct {
+	User *User
+	// Score は大きいほど検索語によく合っている（尺度は DB によって異なるため、並び順にだけ使う）
+	Score float64
+	// NameHighlight / EmailHighlight は HTML エスケープした上で、一致した単語を <mark> で囲んだもの
+	NameHighlight  string
+	EmailHighlight string
+}
+
+// IsSearchWordRune は検索で単語を構成する文字かを返す（それ以外の文字は単語の区切りになる）
+func IsSearchWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// SearchTerms は検索語を小文字の単語に分割する。
+// 各単語は前方一致で検索され、すべての単語に一致したユーザーがヒットする
+func SearchTerms(q string) []string {
+	words := strings.FieldsFunc(strings.ToLower(q), func(r rune) bool { return !IsSearchWordRune(r) })
+	seen := map[string]bool{}
+	var terms []string
+	for _, w := range words {
+		if seen[w] {
+			continue
+		}
+		seen[w] = true
+		terms = append(terms, w)
+		if len(terms) == maxSearchTerms {
+			break
+		}
+	}
+	return terms
+}
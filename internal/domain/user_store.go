@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+var (
+	// ErrUserNotFound はユーザーが存在しない（論理削除済みを含む）場合のエラー
+	ErrUserNotFound = NewError(ErrNotFound, "user_not_found", "user not found")
+	// ErrEmailTaken はメールアドレスが既に他のユーザーに使われている場合のエラー。
+	// 論理削除済みのユーザーのアドレスも、物理削除されるまでは使えない
+	ErrEmailTaken = NewError(ErrConflict, "email_taken", "email already taken")
+	// ErrVersionMismatch は読み込んだ後に他の更新でユーザーのバージョンが変わっていた場合のエラー
+	ErrVersionMismatch = NewError(ErrPreconditionFailed, "version_mismatch", "user has been modified since it was read")
+)
+
+// UserStore はユーザーの永続化を担う。
+// 論理削除されたユーザーは FindDeletedByID / Restore / Purge 以外からは見えない。
+// ユーザーの行を変更する操作はすべて Version を1つ進める。
+type UserStore interface {
+	// FindAll は条件に合うユーザーを並び順のオフセットで返す（パスワードは含まない）
+	FindAll(query UserListQuery) ([]*User, error)
+	// FindPage は条件に合うユーザーをキーセットページネーションで返す（パスワードは含まない）
+	FindPage(query UserPageQuery) ([]*User, error)
+	// Count は条件に合うユーザーの数を返す
+	Count(filter UserFilter) (int64, error)
+	// Search は名前・メールアドレスの単語がすべての terms で前方一致するユーザーを、よく合う順に返す
+	Search(terms []string, limit int) ([]*UserSearchHit, error)
+	// Create はユーザーを登録し、採番した ID と作成日時を user に書き戻す
+	Create(user *User) error
+	// CreateBatch は users を1つのトランザクションで登録し、ユーザーごとのエラー（登録できれば nil）を返す。
+	// 登録できないユーザーがいても他のユーザーは登録する。err はトランザクション自体の失敗で、このときはどのユーザーも登録されない
+	CreateBatch(users []*User) ([]error, error)
+	FindByEmail(email string) (*User, error)
+	FindByID(id uint) (*User, error)
+	// Update は名前・メールアドレス・パスワードを更新する。
+	// user.Version が保存されているバージョンと異なれば ErrVersionMismatch を返し、更新すると user.Version を進める
+	Update(user *User) error
+	// Patch はバージョンが version のユーザーの、patch で指定したフィールドだけを更新する（異なれば ErrVersionMismatch）。
+	// 指定していない列には書き込まない
+	Patch(id uint, version uint, patch UserPatch) error
+	UpdateRole(id uint, role Role) error
+	// Delete はバージョンが version のユーザーを論理削除する（異なれば ErrVersionMismatch）
+	Delete(id uint, version uint) error
+	FindDeletedByID(id uint) (*User, error)
+	// Restore は deletedAfter より後に論理削除されたユーザーを復元する
+	Restore(id uint, deletedAfter time.Time) error
+	// Purge は deletedBefore より前に論理削除されたユーザーを物理削除し、そのIDを返す
+	Purge(deletedBefore time.Time) ([]uint, error)
+}
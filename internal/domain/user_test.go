@@ -1,6 +1,10 @@
 package domain
 
-import "testing"
+import (
+	"errors"
+	"strings"
+	"testing"
+)
 
 func TestNewUser(t *testing.T) {
 	user, err := NewUser("Name", "email@example.com", "password")
@@ -12,3 +16,32 @@ func TestNewUser(t *testing.T) {
 		t.Errorf("expected ID 1, ogt %v", user.ID)
 	}
 }
+
+func TestNewUser_Validation(t *testing.T) {
+	user, err := NewUser("Name", " Email@Example.com", "password")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if user.Email != "email@example.com" {
+		t.Errorf("expected normalized email, got %q", user.Email)
+	}
+
+	_, err = NewUser(" ", "Name <name@example.com>", "short")
+	var ve *ValidationError
+	if !errors.As(err, &ve) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	var codes []string
+	for _, f := range ve.Fields {
+		codes = append(codes, f.Field+":"+f.Code)
+	}
+	if got := strings.Join(codes, ","); got != "name:required,email:email,password:min" {
+		t.Errorf("unexpected field errors: %s", got)
+	}
+
+	// bcrypt は 72 バイトより長いパスワードを扱えない
+	_, err = NewUser("Name", "email@example.com", strings.Repeat("a", MaxPasswordBytes+1))
+	if !errors.As(err, &ve) || len(ve.Fields) != 1 || ve.Fields[0].Field != "password" || ve.Fields[0].Code != "max" {
+		t.Errorf("expected password:max, got %v", err)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{auditService: auditService}
+}
+
+// auditContext はリクエストの操作者・IP・User-Agent を監査ログ用に context に載せる
+func auditContext(c *gin.Context) context.Context {
+	actor := service.Actor{
+		IP:        c.ClientIP(),
+		UserAgent: c.Request.UserAgent(),
+	}
+	if userID, ok := c.Get("userID"); ok {
+		if id, ok := userID.(uint); ok {
+			actor.UserID = &id
+		}
+	}
+	return service.WithActor(c.Request.Context(), actor)
+}
+
+// List godoc
+// @Summary 監査ログの取得
+// @Description ユーザー操作・認証に関する監査ログを新しい順に取得します。（admin のみ）
+// @Tags Audit
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "ページ番号" default(1)
+// @Param limit query int false "1ページあたりの件数（最大100）" default(20)
+// @Param actor_id query int false "操作したユーザーのID"
+// @Param target_id query int false "操作対象のユーザーのID"
+// @Param action query string false "操作の種類（例: user.updated, auth.login）"
+// @Param from query string false "この日時以降（RFC3339）"
+// @Param to query string false "この日時より前（RFC3339）"
+// @Success 200 {array} domain.AuditEvent
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Router /audit [get]
+func (h *AuditHandler) List(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	filter := domain.AuditFilter{Action: c.Query("action")}
+	var err error
+	if filter.ActorID, err = queryUint(c, "actor_id"); err != nil {
+		problem.Error(c, domain.NewValidationError("actor_id", "type", "must be a positive integer"))
+		return
+	}
+	if filter.TargetID, err = queryUint(c, "target_id"); err != nil {
+		problem.Error(c, domain.NewValidationError("target_id", "type", "must be a positive integer"))
+		return
+	}
+	if filter.From, err = queryTime(c, "from"); err != nil {
+		problem.Error(c, domain.NewValidationError("from", "datetime", "must be an RFC3339 datetime"))
+		return
+	}
+	if filter.To, err = queryTime(c, "to"); err != nil {
+		problem.Error(c, domain.NewValidationError("to", "datetime", "must be an RFC3339 datetime"))
+		return
+	}
+
+	events, err := h.auditService.List(filter, page, limit)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, events)
+}
+
+// queryUint はクエリパラメータを ID として読む（指定されていなければ nil）
+func queryUint(c *gin.Context, key string) (*uint, error) {
+	s := c.Query(key)
+	if s == "" {
+		return nil, nil
+	}
+	v, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return nil, err
+	}
+	id := uint(v)
+	return &id, nil
+}
+
+// queryTime はクエリパラメータを RFC3339 の日時として読む（指定されていなければ nil）
+func queryTime(c *gin.Context, key string) (*time.Time, error) {
+	s := c.Query(key)
+	if s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
@@ -1,10 +1,15 @@
 package handler
 
 import (
+	"errors"
+	"io"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/problem"
 	"github.com/okamuuu/go-user-app/internal/service"
 )
 
@@ -19,18 +24,23 @@ func NewAuthHandler(authService *service.AuthService) *AuthHandler {
 // Signup godoc
 // @Summary サインアップ（ユーザー登録）
 // @Description 新しいユーザーを登録します。
+// @Description Idempotency-Key を付けると、同じキーで再送されたリクエストは処理せずに最初のレスポンスを返します（Idempotent-Replayed: true）。
 // @Tags Auth
 // @Accept json
 // @Produce json
-// @Param request body domain.User true "ユーザー登録情報"
-// @Success 201 {object} domain.User
-// @Failure 400 {object} handler.ErrorResponse
-// @Failure 500 {object} handler.ErrorResponse
+// @Param Idempotency-Key header string false "再送しても一度だけ処理するためのリクエストごとに一意なキー"
+// @Param request body handler.SignupRequest true "ユーザー登録情報"
+// @Success 201 {object} map[string]string "{"message": "User created successfully"}"
+// @Header 201 {string} Idempotent-Replayed "保存したレスポンスを返した場合は true"
+// @Failure 400 {object} problem.Details
+// @Failure 409 {object} problem.Details "email already taken or request with the same Idempotency-Key in progress"
+// @Failure 422 {object} problem.Details "Idempotency-Key reused for a different request"
+// @Failure 500 {object} problem.Details
 // @Router /signup [post]
 func (h *AuthHandler) Signup(c *gin.Context) {
 	var req SignupRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Binding(c, err)
 		return
 	}
 
@@ -40,8 +50,8 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 		Password: req.Password,
 	}
 
-	if err := h.authService.SignUp(user); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+	if err := h.authService.SignUp(auditContext(c), user); err != nil {
+		problem.Error(c, err)
 		return
 	}
 
@@ -51,26 +61,217 @@ func (h *AuthHandler) Signup(c *gin.Context) {
 // Login godoc
 // @Summary ログイン
 // @Description メールアドレスとパスワードでログインします。
+// @Description MFA が有効なユーザーの場合は mfa_required と mfa_token を返すので、/login/mfa でコードを送信してください。
 // @Tags Auth
 // @Accept json
 // @Produce json
 // @Param request body handler.LoginRequest true "ログイン情報"
 // @Success 200 {object} handler.LoginResponse
-// @Failure 400 {object} handler.ErrorResponse
-// @Failure 401 {object} handler.ErrorResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
 // @Router /login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var req LoginRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		problem.Binding(c, err)
 		return
 	}
 
-	token, err := h.authService.Login(req.Email, req.Password)
+	result, err := h.authService.Login(auditContext(c), req.Email, req.Password)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		problem.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"token": token})
+	// MFA が有効な場合はチャレンジトークンだけを返す
+	if result.MFARequired() {
+		c.JSON(http.StatusOK, LoginResponse{MFARequired: true, MFAToken: result.MFAChallenge})
+		return
+	}
+
+	c.JSON(http.StatusOK, newLoginResponse(result.Tokens))
+}
+
+// LoginMFA godoc
+// @Summary MFAコードによるログイン（2段階目）
+// @Description ログインで返された mfa_token と、認証アプリのコードまたはリカバリーコードを検証してトークンを発行します。
+// @Description mfa_token は1回しか使えません。コードを間違えた場合はログインからやり直してください。
+// @Description 続けて5回コードを間違えると、15分間はコードを受け付けず 429 を返します。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body handler.LoginMFARequest true "チャレンジトークンとMFAコード"
+// @Success 200 {object} handler.LoginResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 429 {object} problem.Details
+// @Router /login/mfa [post]
+func (h *AuthHandler) LoginMFA(c *gin.Context) {
+	var req LoginMFARequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+
+	tokens, err := h.authService.CompleteMFALogin(auditContext(c), req.MFAToken, req.Code)
+	if errors.Is(err, service.ErrInvalidMFACode) || errors.Is(err, service.ErrMFANotEnrolled) {
+		// ログインの2段階目ではコードの誤りも認証の失敗として 401 を返す
+		problem.Respond(c, http.StatusUnauthorized, service.ErrInvalidMFACode.Code, service.ErrInvalidMFACode.Message)
+		return
+	}
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newLoginResponse(tokens))
+}
+
+// Refresh godoc
+// @Summary トークンのリフレッシュ
+// @Description リフレッシュトークンをローテーションし、新しいアクセストークンとリフレッシュトークンを発行します。
+// @Description 使用済みのリフレッシュトークンが再利用された場合は、同じ系列のトークンをすべて失効させます。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body handler.RefreshRequest true "リフレッシュトークン"
+// @Success 200 {object} handler.LoginResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Router /token/refresh [post]
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req RefreshRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+
+	tokens, err := h.authService.Refresh(auditContext(c), req.RefreshToken)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newLoginResponse(tokens))
+}
+
+// Logout godoc
+// @Summary ログアウト
+// @Description 現在のアクセストークンを失効させます。リフレッシュトークンを指定した場合はその系列も失効させます。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body handler.LogoutRequest false "失効させるリフレッシュトークン"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	// ボディは任意
+	var req LogoutRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		problem.Binding(c, err)
+		return
+	}
+
+	userID := c.MustGet("userID").(uint)
+	jti := c.MustGet("jti").(string)
+	expiresAt, _ := c.Get("tokenExpiresAt")
+	exp, _ := expiresAt.(time.Time)
+
+	if err := h.authService.Logout(auditContext(c), userID, jti, exp, req.RefreshToken); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary 全端末からログアウト
+// @Description ログインユーザーのすべてのアクセストークンとリフレッシュトークンを失効させます。
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 204 {string} string "No Content"
+// @Failure 401 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /logout/all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	if err := h.authService.LogoutAll(auditContext(c), userID); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// RevokeUserSessions godoc
+// @Summary 指定ユーザーのセッションを全て失効
+// @Description 乗っ取られたアカウントなどに対し、指定したユーザーの全トークンを失効させます。
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ユーザーID"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} problem.Details "invalid ID"
+// @Failure 404 {object} problem.Details "user not found"
+// @Router /users/{id}/logout-all [post]
+func (h *AuthHandler) RevokeUserSessions(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		problem.Error(c, errInvalidUserID)
+		return
+	}
+
+	if err := h.authService.LogoutAll(auditContext(c), uint(id)); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ChangeRole godoc
+// @Summary ユーザーのロール変更
+// @Description 指定したユーザーのロールを変更します。変更を即座に反映させるため、対象ユーザーのセッションは失効します。
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path int true "ユーザーID"
+// @Param request body handler.ChangeRoleRequest true "新しいロール"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} problem.Details "invalid request or ID"
+// @Failure 403 {object} problem.Details "permission denied"
+// @Failure 404 {object} problem.Details "user not found"
+// @Router /users/{id}/role [put]
+func (h *AuthHandler) ChangeRole(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		problem.Error(c, errInvalidUserID)
+		return
+	}
+
+	var req ChangeRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+	role, err := domain.ParseRole(req.Role)
+	if err != nil {
+		problem.Error(c, domain.NewValidationError("role", "oneof", "must be one of admin, support, member"))
+		return
+	}
+
+	if err := h.authService.ChangeRole(auditContext(c), uint(id), role); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
 }
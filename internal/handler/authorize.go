@@ -0,0 +1,52 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/middleware"
+	"github.com/okamuuu/go-user-app/internal/policy"
+	"github.com/okamuuu/go-user-app/internal/problem"
+)
+
+// ポリシーで判定するユーザー操作
+const (
+	actionUsersList    = "users:list"
+	actionUsersRead    = "users:read"
+	actionUsersCreate  = "users:create"
+	actionUsersUpdate  = "users:update"
+	actionUsersDelete  = "users:delete"
+	actionUsersRestore = "users:restore"
+	actionUsersImport  = "users:import"
+	actionUsersExport  = "users:export"
+	actionUsersErase   = "users:erase"
+
+	resourceUser = "user"
+)
+
+// currentSubject は AuthMiddleware が context に保存した認証情報から主体を作る
+func currentSubject(c *gin.Context) policy.Subject {
+	userID, _ := c.Get("userID")
+	id, _ := userID.(uint)
+	return policy.Subject{ID: id, Role: string(middleware.CurrentRole(c))}
+}
+
+// userResource は操作対象のユーザーを表すリソース
+func userResource(id uint) policy.Resource {
+	return policy.Resource{Type: resourceUser, ID: id, OwnerID: id}
+}
+
+// authorize はポリシーに基づいて操作を認可する。拒否した場合は 403 を返して false を返す
+func authorize(c *gin.Context, engine *policy.Engine, action string, resource policy.Resource, fields ...string) bool {
+	decision := engine.Evaluate(policy.Request{
+		Subject:  currentSubject(c),
+		Action:   action,
+		Resource: resource,
+		Fields:   fields,
+	})
+	if !decision.Allowed {
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, decision.Reason)
+		return false
+	}
+	return true
+}
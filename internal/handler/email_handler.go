@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+type EmailHandler struct {
+	verificationService *service.EmailVerificationService
+}
+
+func NewEmailHandler(verificationService *service.EmailVerificationService) *EmailHandler {
+	return &EmailHandler{verificationService: verificationService}
+}
+
+// Verify godoc
+// @Summary メールアドレスの確認
+// @Description 確認用トークンを検証してメールアドレスを確認済みにします。
+// @Description メールアドレス変更の確認であれば、このタイミングで新しいアドレスに切り替わります。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body handler.VerifyEmailRequest true "確認用トークン"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} problem.Details
+// @Failure 409 {object} problem.Details "email already exists"
+// @Failure 500 {object} problem.Details
+// @Router /email/verify [post]
+func (h *EmailHandler) Verify(c *gin.Context) {
+	var req VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+
+	if err := h.verificationService.Confirm(auditContext(c), req.Token); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// Resend godoc
+// @Summary 確認メールの再送
+// @Description ログインユーザーのメールアドレスが未確認の場合、確認用のリンクを再送します。
+// @Tags Auth
+// @Produce json
+// @Security BearerAuth
+// @Success 202 {string} string "Accepted"
+// @Failure 401 {object} problem.Details
+// @Failure 409 {object} problem.Details "email already verified"
+// @Failure 500 {object} problem.Details
+// @Router /email/verify/resend [post]
+func (h *EmailHandler) Resend(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	if err := h.verificationService.Resend(userID); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/problem"
+)
+
+// userETag はユーザーのバージョンから強い ETag を作る
+func userETag(user *domain.User) string {
+	return `"` + strconv.FormatUint(uint64(user.Version), 10) + `"`
+}
+
+// setUserETag はレスポンスにユーザーの ETag を付ける
+func setUserETag(c *gin.Context, user *domain.User) {
+	c.Header("ETag", userETag(user))
+}
+
+// requireIfMatch は If-Match ヘッダーを読む。無ければ 428 を返して false を返す
+func requireIfMatch(c *gin.Context) (string, bool) {
+	ifMatch := strings.TrimSpace(c.GetHeader("If-Match"))
+	if ifMatch == "" {
+		problem.Respond(c, http.StatusPreconditionRequired, problem.CodePreconditionRequired,
+			"If-Match header with the user's ETag is required")
+		return "", false
+	}
+	return ifMatch, true
+}
+
+// matchVersion は If-Match がユーザーの現在の ETag と一致すれば、そのバージョンを返す。
+// 比較は強い比較で、弱い ETag（W/"..."）は一致しない。一致しなければ domain.ErrVersionMismatch を返す
+func matchVersion(ifMatch string, user *domain.User) (uint, error) {
+	if ifMatch == "*" {
+		return user.Version, nil
+	}
+	current := userETag(user)
+	for _, tag := range strings.Split(ifMatch, ",") {
+		if strings.TrimSpace(tag) == current {
+			return user.Version, nil
+		}
+	}
+	return 0, domain.ErrVersionMismatch
+}
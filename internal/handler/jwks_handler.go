@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/signing"
+)
+
+type JWKSHandler struct {
+	keys *signing.KeySet
+}
+
+func NewJWKSHandler(keys *signing.KeySet) *JWKSHandler {
+	return &JWKSHandler{keys: keys}
+}
+
+// JWKS は他のサービスがトークンを検証するための公開鍵一覧を返す
+// GET /.well-known/jwks.json
+func (h *JWKSHandler) JWKS(c *gin.Context) {
+	// 鍵のローテーションを反映させるため、キャッシュは短めにする
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, h.keys.JWKS())
+}
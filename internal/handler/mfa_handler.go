@@ -0,0 +1,103 @@
+package handler
+
+import (
+	"encoding/base64"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+type MFAHandler struct {
+	mfaService *service.MFAService
+}
+
+func NewMFAHandler(mfaService *service.MFAService) *MFAHandler {
+	return &MFAHandler{mfaService: mfaService}
+}
+
+// Enroll godoc
+// @Summary TOTP の登録開始
+// @Description 新しい TOTP シークレットを発行し、認証アプリに登録するための URI と QR コード（PNG）を返します。
+// @Description /mfa/totp/activate でコードを確認するまで MFA は有効になりません。
+// @Tags MFA
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} handler.TOTPEnrollmentResponse
+// @Failure 401 {object} problem.Details
+// @Failure 409 {object} problem.Details "MFA already enabled"
+// @Failure 500 {object} problem.Details
+// @Router /mfa/totp/enroll [post]
+func (h *MFAHandler) Enroll(c *gin.Context) {
+	userID := c.MustGet("userID").(uint)
+
+	enrollment, err := h.mfaService.Enroll(userID)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, TOTPEnrollmentResponse{
+		Secret:     enrollment.Secret,
+		OTPAuthURI: enrollment.URI,
+		QRCodePNG:  base64.StdEncoding.EncodeToString(enrollment.QRCode),
+	})
+}
+
+// Activate godoc
+// @Summary TOTP の有効化
+// @Description 認証アプリに表示されたコードを確認して MFA を有効にし、リカバリーコードを返します。
+// @Description リカバリーコードはこのレスポンスでしか取得できません。
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body handler.MFACodeRequest true "認証アプリのコード"
+// @Success 200 {object} handler.RecoveryCodesResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 409 {object} problem.Details "MFA already enabled"
+// @Router /mfa/totp/activate [post]
+func (h *MFAHandler) Activate(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+
+	codes, err := h.mfaService.Activate(auditContext(c), userID, req.Code)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, RecoveryCodesResponse{RecoveryCodes: codes})
+}
+
+// Disable godoc
+// @Summary TOTP の無効化
+// @Description 認証アプリのコードまたはリカバリーコードを確認して MFA を無効にします。
+// @Tags MFA
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body handler.MFACodeRequest true "認証アプリのコードまたはリカバリーコード"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Router /mfa/totp/disable [post]
+func (h *MFAHandler) Disable(c *gin.Context) {
+	var req MFACodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+	userID := c.MustGet("userID").(uint)
+
+	if err := h.mfaService.Disable(auditContext(c), userID, req.Code); err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
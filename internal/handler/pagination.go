@@ -0,0 +1,38 @@
+package handler
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// pageLink は Link ヘッダー（RFC 8288）に載せる1件のリンク
+type pageLink struct {
+	Rel string
+	URL string
+}
+
+// listLink はリクエストの URL のクエリパラメータを set で上書きし、remove を取り除いた URL を返す
+func listLink(c *gin.Context, set map[string]string, remove ...string) string {
+	u := *c.Request.URL
+	q := u.Query()
+	for key, value := range set {
+		q.Set(key, value)
+	}
+	for _, key := range remove {
+		q.Del(key)
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// setLinkHeader は前後のページへのリンクを Link ヘッダーに設定する
+func setLinkHeader(c *gin.Context, links []pageLink) {
+	values := make([]string, 0, len(links))
+	for _, l := range links {
+		values = append(values, "<"+l.URL+`>; rel="`+l.Rel+`"`)
+	}
+	if len(values) > 0 {
+		c.Header("Link", strings.Join(values, ", "))
+	}
+}
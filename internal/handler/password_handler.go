@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+type PasswordHandler struct {
+	resetService *service.PasswordResetService
+}
+
+func NewPasswordHandler(resetService *service.PasswordResetService) *PasswordHandler {
+	return &PasswordHandler{resetService: resetService}
+}
+
+// Forgot godoc
+// @Summary パスワード再設定リンクの送信
+// @Description 登録済みのメールアドレスにパスワード再設定用のリンクを送信します。
+// @Description メールアドレスの登録有無にかかわらず同じレスポンスを返します。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body handler.ForgotPasswordRequest true "メールアドレス"
+// @Success 202 {string} string "Accepted"
+// @Failure 400 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /password/forgot [post]
+func (h *PasswordHandler) Forgot(c *gin.Context) {
+	var req ForgotPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+
+	if err := h.resetService.RequestReset(req.Email); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusAccepted)
+}
+
+// Reset godoc
+// @Summary パスワードの再設定
+// @Description 再設定用トークンを検証して新しいパスワードを設定します。トークンは一度しか使えません。
+// @Tags Auth
+// @Accept json
+// @Produce json
+// @Param request body handler.ResetPasswordRequest true "再設定用トークンと新しいパスワード"
+// @Success 204 {string} string "No Content"
+// @Failure 400 {object} problem.Details
+// @Failure 500 {object} problem.Details
+// @Router /password/reset [post]
+func (h *PasswordHandler) Reset(c *gin.Context) {
+	var req ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+
+	if err := h.resetService.ResetPassword(auditContext(c), req.Token, req.Password); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,74 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/policy"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+type PolicyHandler struct {
+	policy      *policy.Engine
+	userService *service.UserService
+}
+
+func NewPolicyHandler(policy *policy.Engine, userService *service.UserService) *PolicyHandler {
+	return &PolicyHandler{policy: policy, userService: userService}
+}
+
+// Explain godoc
+// @Summary 認可判定のドライラン
+// @Description 指定した主体・操作・リソースに対する認可判定を実際の操作を行わずに評価し、各ルールの評価結果とともに返します。
+// @Description subject_id も role も省略した場合はログインユーザー自身として評価します。
+// @Tags Policy
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body handler.ExplainPolicyRequest true "評価する操作"
+// @Success 200 {object} policy.Decision
+// @Failure 400 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Failure 404 {object} problem.Details "subject not found"
+// @Router /policy/explain [post]
+func (h *PolicyHandler) Explain(c *gin.Context) {
+	var req ExplainPolicyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+
+	subject := currentSubject(c)
+	if req.SubjectID != 0 {
+		subject = policy.Subject{ID: req.SubjectID, Role: req.Role}
+		if req.Role == "" {
+			// ロールが指定されなければ実際のユーザーのロールで評価する
+			user, err := h.userService.GetUserByID(req.SubjectID)
+			if err != nil {
+				problem.Error(c, err)
+				return
+			}
+			subject.Role = string(user.Role)
+		}
+	} else if req.Role != "" {
+		subject.Role = req.Role
+	}
+	if _, err := domain.ParseRole(subject.Role); err != nil {
+		problem.Error(c, domain.NewValidationError("role", "oneof", "must be one of admin, support, member"))
+		return
+	}
+
+	resource := policy.Resource{Type: req.ResourceType, ID: req.ResourceID, OwnerID: req.ResourceID}
+	if resource.Type == "" {
+		resource.Type = resourceUser
+	}
+
+	c.JSON(http.StatusOK, h.policy.Evaluate(policy.Request{
+		Subject:  subject,
+		Action:   req.Action,
+		Resource: resource,
+		Fields:   req.Fields,
+	}))
+}
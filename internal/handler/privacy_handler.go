@@ -0,0 +1,134 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/policy"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+type PrivacyHandler struct {
+	service *service.PrivacyService
+	policy  *policy.Engine
+}
+
+func NewPrivacyHandler(service *service.PrivacyService, policy *policy.Engine) *PrivacyHandler {
+	return &PrivacyHandler{service: service, policy: policy}
+}
+
+// ExportMe godoc
+// @Summary      自分のデータのエクスポート
+// @Description  ログインユーザーのプロフィール・セッション・監査ログを ZIP で書き出します。
+// @Description  ZIP には profile.json、sessions.json、audit_events.json が入ります。パスワードやトークンのハッシュ、MFA のシークレットは含みません。
+// @Description  他のユーザーが操作した監査ログでは、そのユーザーの IP と User-Agent を含みません。
+// @Tags         Users
+// @Produce      application/zip
+// @Success      200  {file}    file  "ユーザーのデータ"
+// @Header       200  {string}  Content-Disposition  "attachment; filename=\"user-1-20240102T030405Z.zip\""
+// @Failure      401  {object}  problem.Details  "unauthorized"
+// @Router       /me/export [get]
+// @Security     BearerAuth
+func (h *PrivacyHandler) ExportMe(c *gin.Context) {
+	userID, exists := c.Get("userID")
+	if !exists {
+		problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "user not found in context")
+		return
+	}
+	id := userID.(uint)
+
+	// 途中でエラーになってもエラーを返せるよう、書き出し終えてから送る
+	var buf bytes.Buffer
+	if err := h.service.ExportUserData(auditContext(c), id, &buf); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("user-%d-%s.zip", id, time.Now().UTC().Format("20060102T150405Z"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/zip", buf.Bytes())
+}
+
+// EraseUser godoc
+// @Summary      ユーザーの個人データの消去
+// @Description  指定したユーザーの個人データをすべてのテーブルから削除・匿名化します。論理削除済みのユーザーも消去できます。取り消しはできません。（ポリシーで users:erase が許可されている必要があります）
+// @Description  ユーザーの行とトークン・MFA の行は削除し、監査ログは行を残して個人データ（IP・User-Agent・変更前後の値）を匿名化します。
+// @Description  消去したことは個人データを含まない消去の記録として残ります。
+// @Tags         users
+// @Accept       json
+// @Produce      json
+// @Param        id       path  int               true  "ユーザーID"
+// @Param        request  body  EraseUserRequest  true  "消去の根拠"
+// @Success      200  {object}  domain.ErasureRecord
+// @Failure      400  {object}  problem.Details  "invalid ID or reason"
+// @Failure      403  {object}  problem.Details  "forbidden"
+// @Failure      404  {object}  problem.Details  "user not found"
+// @Router       /users/{id}/erase [post]
+// @Security     BearerAuth
+func (h *PrivacyHandler) EraseUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		problem.Error(c, errInvalidUserID)
+		return
+	}
+	if !authorize(c, h.policy, actionUsersErase, userResource(uint(id))) {
+		return
+	}
+
+	var req EraseUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		problem.Binding(c, err)
+		return
+	}
+
+	record, err := h.service.Erase(auditContext(c), uint(id), req.Reason)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, record)
+}
+
+// ListErasures godoc
+// @Summary 消去の記録の取得
+// @Description ユーザーの個人データを消去した記録を新しい順に取得します。記録には個人データを含みません。（admin のみ）
+// @Tags Audit
+// @Produce json
+// @Security BearerAuth
+// @Param page query int false "ページ番号" default(1)
+// @Param limit query int false "1ページあたりの件数（最大100）" default(20)
+// @Param user_id query int false "消去したユーザーのID"
+// @Success 200 {array} domain.ErasureRecord
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Router /erasures [get]
+func (h *PrivacyHandler) ListErasures(c *gin.Context) {
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	userID, err := queryUint(c, "user_id")
+	if err != nil {
+		problem.Error(c, domain.NewValidationError("user_id", "type", "must be a positive integer"))
+		return
+	}
+
+	records, err := h.service.ListErasures(userID, page, limit)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, records)
+}
@@ -4,7 +4,15 @@ package handler
 type SignupRequest struct {
 	Name     string `json:"name" binding:"required"`
 	Email    string `json:"email" binding:"required,email"`
-	Password string `json:"password" binding:"required,min=6"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
+// CreateUserRequest はユーザー作成用のリクエストボディ構造体
+type CreateUserRequest struct {
+	Name     string `json:"name" binding:"required" example:"Alice"`
+	Email    string `json:"email" binding:"required,email" example:"alice@example.com"`
+	Password string `json:"password" binding:"required,min=6,max=72" example:"password123"`
+	Role     string `json:"role" binding:"omitempty,oneof=admin support member" example:"member"` // 省略時は member
 }
 
 // LoginRequest はログイン用のリクエストボディ構造体
@@ -12,3 +20,60 @@ type LoginRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required"`
 }
+
+// RefreshRequest はトークンリフレッシュ用のリクエストボディ構造体
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required"`
+}
+
+// LogoutRequest はログアウト用のリクエストボディ構造体（任意）
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// ForgotPasswordRequest はパスワード再設定リンク送信用のリクエストボディ構造体
+type ForgotPasswordRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest はパスワード再設定用のリクエストボディ構造体
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
+// VerifyEmailRequest はメールアドレス確認用のリクエストボディ構造体
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// LoginMFARequest は MFA によるログイン（2段階目）用のリクエストボディ構造体
+type LoginMFARequest struct {
+	MFAToken string `json:"mfa_token" binding:"required"`
+	Code     string `json:"code" binding:"required"`
+}
+
+// MFACodeRequest は MFA コードの確認用のリクエストボディ構造体
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// ChangeRoleRequest はロール変更用のリクエストボディ構造体
+type ChangeRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin support member" example:"support"`
+}
+
+// ExplainPolicyRequest は認可判定のドライラン用のリクエストボディ構造体
+type ExplainPolicyRequest struct {
+	SubjectID    uint     `json:"subject_id" example:"2"`
+	Role         string   `json:"role" example:"support"`
+	Action       string   `json:"action" binding:"required" example:"users:update"`
+	ResourceType string   `json:"resource_type" example:"user"`
+	ResourceID   uint     `json:"resource_id" example:"3"`
+	Fields       []string `json:"fields" example:"name"`
+}
+
+// EraseUserRequest はユーザーの個人データ消去用のリクエストボディ構造体
+type EraseUserRequest struct {
+	Reason string `json:"reason" binding:"required" example:"privacy request #1234"` // 消去の根拠（個人情報は書かない）
+}
@@ -1,13 +1,174 @@
 // internal/handler/response.go
 package handler
 
-// ErrorResponse はエラーレスポンスの共通構造です。
-// swagger:response ErrorResponse
-type ErrorResponse struct {
-	Error string `json:"error" example:"invalid request"`
-}
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+// エラーレスポンスは problem パッケージの problem details（application/problem+json）で返します。
+
+// errInvalidUserID はパスのユーザーIDが正の整数でない場合の検証エラー
+var errInvalidUserID = domain.NewValidationError("id", "type", "must be a positive integer")
 
-// LoginResponse はログイン成功時のレスポンスです。
+// LoginResponse はログイン・トークンリフレッシュ成功時のレスポンスです。
+// MFA が必要な場合はトークンの代わりに mfa_required と mfa_token を返します。
 type LoginResponse struct {
-	Token string `json:"token" example:"your-jwt-token"`
+	Token        string `json:"token,omitempty" example:"your-jwt-token"`
+	RefreshToken string `json:"refresh_token,omitempty" example:"your-refresh-token"`
+	ExpiresIn    int64  `json:"expires_in,omitempty" example:"900"`
+	MFARequired  bool   `json:"mfa_required,omitempty" example:"false"`
+	MFAToken     string `json:"mfa_token,omitempty" example:""`
+}
+
+func newLoginResponse(tokens *service.TokenPair) LoginResponse {
+	return LoginResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}
+}
+
+// TOTPEnrollmentResponse は TOTP 登録開始時のレスポンスです。
+type TOTPEnrollmentResponse struct {
+	Secret     string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	OTPAuthURI string `json:"otpauth_uri" example:"otpauth://totp/Go%20User%20App:user@example.com?secret=JBSWY3DPEHPK3PXP"`
+	QRCodePNG  string `json:"qr_code_png" example:"iVBORw0KGgo..."` // Base64 エンコードされた PNG
+}
+
+// RecoveryCodesResponse は MFA 有効化時に一度だけ返すリカバリーコードです。
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes" example:"ABCDE-FGHJK"`
+}
+
+// UserResponse はユーザー1人分のレスポンスです。
+// 項目名は domain.User と同じで、パスワード（ハッシュ）だけを含めません。
+type UserResponse struct {
+	ID              uint
+	Name            string
+	Email           string
+	Role            domain.Role
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	EmailVerifiedAt *time.Time
+	DeletedAt       *time.Time
+	Version         uint
+}
+
+func newUserResponse(u *domain.User) UserResponse {
+	return UserResponse{
+		ID:              u.ID,
+		Name:            u.Name,
+		Email:           u.Email,
+		Role:            u.Role,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		DeletedAt:       u.DeletedAt,
+		Version:         u.Version,
+	}
+}
+
+// UserListResponse はユーザー一覧のレスポンスです。
+// next / prev は前後のページを取得する URL で、ページが無い場合は省略されます（Link ヘッダーと同じ値）。
+type UserListResponse struct {
+	Items   []UserResponse `json:"items"`
+	Total   *int64         `json:"total,omitempty" example:"42"` // count=false の場合は省略
+	Page    int            `json:"page,omitempty" example:"1"`   // オフセット方式の場合のみ
+	Limit   int            `json:"limit" example:"10"`
+	HasMore bool           `json:"has_more" example:"true"`
+	Next    string         `json:"next,omitempty" example:"/api/users?limit=10&page=2"`
+	Prev    string         `json:"prev,omitempty" example:""`
+}
+
+// newOffsetUserListResponse はオフセット方式の一覧のレスポンスを作り、Link ヘッダーを付ける
+func newOffsetUserListResponse(c *gin.Context, result *service.UserPage, page, limit int) UserListResponse {
+	res := UserListResponse{Items: newUserResponses(result.Users), Total: result.Total, Page: page, Limit: limit, HasMore: result.HasMore}
+	pageURL := func(page int) string {
+		return listLink(c, map[string]string{"page": strconv.Itoa(page), "limit": strconv.Itoa(limit)}, "cursor")
+	}
+
+	links := []pageLink{{Rel: "first", URL: pageURL(1)}}
+	if page > 1 {
+		res.Prev = pageURL(page - 1)
+		links = append(links, pageLink{Rel: "prev", URL: res.Prev})
+	}
+	if result.HasMore {
+		res.Next = pageURL(page + 1)
+		links = append(links, pageLink{Rel: "next", URL: res.Next})
+	}
+	if result.Total != nil {
+		last := int((*result.Total + int64(limit) - 1) / int64(limit))
+		links = append(links, pageLink{Rel: "last", URL: pageURL(max(last, 1))})
+	}
+	setLinkHeader(c, links)
+	return res.withItems()
+}
+
+// newCursorUserListResponse はカーソル方式の一覧のレスポンスを作り、Link ヘッダーを付ける
+func newCursorUserListResponse(c *gin.Context, result *service.UserPage, limit int) UserListResponse {
+	res := UserListResponse{Items: newUserResponses(result.Users), Total: result.Total, Limit: limit, HasMore: result.HasMore}
+	cursorURL := func(cursor string) string {
+		return listLink(c, map[string]string{"cursor": cursor, "limit": strconv.Itoa(limit)}, "page")
+	}
+
+	links := []pageLink{{Rel: "first", URL: listLink(c, map[string]string{"limit": strconv.Itoa(limit)}, "cursor", "page")}}
+	if result.PrevCursor != "" {
+		res.Prev = cursorURL(result.PrevCursor)
+		links = append(links, pageLink{Rel: "prev", URL: res.Prev})
+	}
+	if result.NextCursor != "" {
+		res.Next = cursorURL(result.NextCursor)
+		links = append(links, pageLink{Rel: "next", URL: res.Next})
+	}
+	setLinkHeader(c, links)
+	return res.withItems()
+}
+
+// newUserResponses は一覧の各ユーザーをレスポンスに変換する
+func newUserResponses(users []*domain.User) []UserResponse {
+	items := make([]UserResponse, 0, len(users))
+	for _, u := range users {
+		items = append(items, newUserResponse(u))
+	}
+	return items
+}
+
+// withItems はユーザーがいない場合も items を空の配列として返すようにする
+func (r UserListResponse) withItems() UserListResponse {
+	if r.Items == nil {
+		r.Items = []UserResponse{}
+	}
+	return r
+}
+
+// UserSearchResult は検索でヒットしたユーザーです。
+// ハイライトは HTML エスケープした上で、一致した単語を <mark> で囲んでいます。
+type UserSearchResult struct {
+	User           UserResponse `json:"user"`
+	Score          float64      `json:"score" example:"1.5"`
+	NameHighlight  string       `json:"name_highlight" example:"Anna <mark>Smith</mark>"`
+	EmailHighlight string       `json:"email_highlight" example:"anna@example.com"`
+}
+
+// UserSearchResponse はユーザー検索のレスポンスです（よく合う順）。
+type UserSearchResponse struct {
+	Items []UserSearchResult `json:"items"`
+}
+
+func newUserSearchResponse(hits []*domain.UserSearchHit) UserSearchResponse {
+	res := UserSearchResponse{Items: make([]UserSearchResult, 0, len(hits))}
+	for _, hit := range hits {
+		res.Items = append(res.Items, UserSearchResult{
+			User:           newUserResponse(hit.User),
+			Score:          hit.Score,
+			NameHighlight:  hit.NameHighlight,
+			EmailHighlight: hit.EmailHighlight,
+		})
+	}
+	return res
 }
@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/policy"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+// exportContentTypes は出力形式ごとのレスポンスの Content-Type
+var exportContentTypes = map[string]string{
+	service.ExportFormatCSV:     "text/csv; charset=utf-8",
+	service.ExportFormatNDJSON:  "application/x-ndjson",
+	service.ExportFormatParquet: "application/vnd.apache.parquet",
+}
+
+type UserExportHandler struct {
+	service *service.UserExportService
+	policy  *policy.Engine
+}
+
+func NewUserExportHandler(service *service.UserExportService, policy *policy.Engine) *UserExportHandler {
+	return &UserExportHandler{service: service, policy: policy}
+}
+
+// Export godoc
+// @Summary      ユーザーのエクスポート
+// @Description  条件に合うすべてのユーザーを CSV・NDJSON・Parquet で書き出します。（ポリシーで users:export が許可されている必要があります）
+// @Description  件数の上限はなく、読み込みながら送るのでレスポンスは chunked で返ります。パスワードは含みません。
+// @Description  絞り込み・並び替えの条件は一覧（GET /users）と同じです。送信を始めた後にエラーになった場合、レスポンスは途中で終わります。
+// @Description  CSV では、表計算ソフトで数式として扱われないよう、=・+・-・@・タブ・CR で始まる値の前に ' を付けます。
+// @Tags         users
+// @Produce      text/csv,application/x-ndjson,application/vnd.apache.parquet
+// @Param        format        query  string  false  "出力形式"  Enums(csv, ndjson, parquet)  default(csv)
+// @Param        columns       query  string  false  "出力する列（カンマ区切り、省略時はすべて）: id, name, email, role, email_verified_at, created_at, updated_at, version"
+// @Param        sort          query  string  false  "並び替えの項目"  Enums(created_at, name, email)  default(created_at)
+// @Param        order         query  string  false  "並び順"  Enums(asc, desc)  default(asc)
+// @Param        q             query  string  false  "名前・メールアドレスの部分一致（大文字小文字を区別しない）"
+// @Param        email_domain  query  string  false  "メールアドレスのドメイン（例: example.com）"
+// @Param        role          query  string  false  "ロール"  Enums(admin, support, member)
+// @Param        verified      query  bool    false  "メールアドレスの確認済み（true）・未確認（false）"
+// @Param        created_from  query  string  false  "この日時以降に作成（RFC3339）"
+// @Param        created_to    query  string  false  "この日時より前に作成（RFC3339）"
+// @Success      200  {file}    file  "エクスポートしたユーザー"
+// @Header       200  {string}  Content-Disposition  "attachment; filename=\"users-20240102T030405Z.csv\""
+// @Failure      400  {object}  problem.Details  "invalid format, columns or filter"
+// @Failure      401  {object}  problem.Details  "unauthorized"
+// @Failure      403  {object}  problem.Details  "forbidden"
+// @Router       /users/export [get]
+// @Security     BearerAuth
+func (h *UserExportHandler) Export(c *gin.Context) {
+	if !authorize(c, h.policy, actionUsersExport, policy.Resource{Type: resourceUser}) {
+		return
+	}
+
+	filter, sort, err := parseUserListQuery(c)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	params := service.UserExportParams{
+		Format: c.DefaultQuery("format", service.ExportFormatCSV),
+		Filter: filter,
+		Sort:   sort,
+	}
+	if s := c.Query("columns"); s != "" {
+		for _, name := range strings.Split(s, ",") {
+			params.Columns = append(params.Columns, strings.TrimSpace(name))
+		}
+	}
+	// ヘッダーを書いた後はエラーを返せないので、先に検証する
+	if err := params.Validate(); err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	filename := fmt.Sprintf("users-%s.%s", time.Now().UTC().Format("20060102T150405Z"), params.Format)
+	c.Header("Content-Type", exportContentTypes[params.Format])
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	count, err := h.service.Export(auditContext(c), params, c.Writer)
+	if err != nil {
+		log.Printf("[ERROR] user export failed after %d users: %v", count, err)
+		c.Error(err)
+	}
+}
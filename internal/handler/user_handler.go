@@ -1,82 +1,252 @@
 package handler
 
 import (
-	"log"
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/policy"
+	"github.com/okamuuu/go-user-app/internal/problem"
 	"github.com/okamuuu/go-user-app/internal/service"
 )
 
 type UserHandler struct {
 	service *service.UserService
+	policy  *policy.Engine
 }
 
-func NewUserHandler(service *service.UserService) *UserHandler {
-	return &UserHandler{service: service}
-}
-
-func (h *UserHandler) RegisterRoutes(r *gin.Engine) {
-	r.GET("/users/:id", h.GetUsers)
-	r.POST("/users", h.CreateUser)
-	r.GET("/users/:id", h.GetUser)
-	r.PUT("/users/:id", h.UpdateUser)
-	r.DELETE("/users/:id", h.DeleteUser)
+func NewUserHandler(service *service.UserService, policy *policy.Engine) *UserHandler {
+	return &UserHandler{service: service, policy: policy}
 }
 
 // @Summary ユーザー一覧取得
-// @Description 登録されている全ユーザーを取得
+// @Description 条件に合うユーザーを並び替えて取得（ポリシーで users:list が許可されている必要があります）
+// @Description 既定ではカーソル方式で、レスポンスの next / prev（Link ヘッダーの rel="next" / rel="prev"）で前後のページを取得します。
+// @Description page を指定した場合はオフセット方式で取得し、Link ヘッダーに first / prev / next / last を返します。
+// @Description 件数の多いテーブルでは count=false で総数（total）の集計を省略できます。
 // @Tags users
 // @Accept json
 // @Produce json
+// @Param sort query string false "並び替えの項目" Enums(created_at, name, email) default(created_at)
+// @Param order query string false "並び順" Enums(asc, desc) default(asc)
+// @Param q query string false "名前・メールアドレスの部分一致（大文字小文字を区別しない）"
+// @Param email_domain query string false "メールアドレスのドメイン（例: example.com）"
+// @Param role query string false "ロール" Enums(admin, support, member)
+// @Param verified query bool false "メールアドレスの確認済み（true）・未確認（false）"
+// @Param created_from query string false "この日時以降に作成（RFC3339）"
+// @Param created_to query string false "この日時より前に作成（RFC3339）"
+// @Param cursor query string false "前後のページを指すカーソル（並び順を変えると使えません）"
+// @Param limit query int false "1ページの件数（1〜100、既定値 10）"
+// @Param page query int false "ページ番号（指定するとオフセット方式）"
+// @Param count query bool false "総数を集計するか" default(true)
 // @Security BearerAuth
-// @Success 200 {array} domain.User
-// @Failure 401 {object} handler.ErrorResponse
+// @Success 200 {object} handler.UserListResponse
+// @Header 200 {string} Link "前後のページの URL（RFC 8288）"
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
 // @Router /users [get]
 func (h *UserHandler) GetUsers(c *gin.Context) {
-	pageStr := c.DefaultQuery("page", "1")
-	limitStr := c.DefaultQuery("limit", "10")
+	if !authorize(c, h.policy, actionUsersList, policy.Resource{Type: resourceUser}) {
+		return
+	}
 
-	page, _ := strconv.Atoi(pageStr)
+	limitStr := c.DefaultQuery("limit", "10")
 	limit, _ := strconv.Atoi(limitStr)
-	if page < 1 {
-		page = 1
-	}
 	if limit < 1 || limit > 100 {
 		limit = 10
 	}
 
-	users, err := h.service.GetUsers(page, limit)
+	filter, sort, err := parseUserListQuery(c)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	withTotal := true
+	if s := c.Query("count"); s != "" {
+		if withTotal, err = strconv.ParseBool(s); err != nil {
+			problem.Error(c, domain.NewValidationError("count", "type", "must be true or false"))
+			return
+		}
+	}
+	params := service.UserListParams{Filter: filter, Sort: sort, Limit: limit, SkipTotal: !withTotal}
+
+	// page を指定した場合はオフセット方式
+	if pageStr, ok := c.GetQuery("page"); ok {
+		page, _ := strconv.Atoi(pageStr)
+		if page < 1 {
+			page = 1
+		}
+
+		result, err := h.service.GetUsers(params, page)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+
+		c.JSON(http.StatusOK, newOffsetUserListResponse(c, result, page, limit))
+		return
+	}
+
+	// カーソルが不正な場合は ErrInvalidCursor（400）になる
+	result, err := h.service.ListUsers(params, c.Query("cursor"))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to fetch users"})
+		problem.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	c.JSON(http.StatusOK, newCursorUserListResponse(c, result, limit))
+}
+
+// SearchUsers godoc
+// @Summary ユーザーの全文検索
+// @Description 名前・メールアドレスの単語の前方一致でユーザーを検索し、よく合う順に返します。（ポリシーで users:list が許可されている必要があります）
+// @Description 複数の単語を指定すると、すべてに一致するユーザーを返します。ハイライトは HTML エスケープ済みで、一致した単語を <mark> で囲みます。
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param q query string true "検索語（例: smi example）"
+// @Param limit query int false "件数（1〜100）" default(20)
+// @Success 200 {object} handler.UserSearchResponse
+// @Failure 400 {object} problem.Details
+// @Failure 401 {object} problem.Details
+// @Failure 403 {object} problem.Details
+// @Router /users/search [get]
+func (h *UserHandler) SearchUsers(c *gin.Context) {
+	if !authorize(c, h.policy, actionUsersList, policy.Resource{Type: resourceUser}) {
+		return
+	}
+
+	q := c.Query("q")
+	if len(q) > maxUserSearchLength {
+		problem.Error(c, errSearchTooLong)
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+
+	// 検索語が空の場合は ErrEmptySearchQuery（400）になる
+	hits, err := h.service.SearchUsers(q, limit)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, newUserSearchResponse(hits))
+}
+
+// 一覧の検索語・ドメインとして受け付ける最大の長さ
+const maxUserSearchLength = 100
+
+var errSearchTooLong = domain.NewValidationError("q", "max", fmt.Sprintf("must be at most %d characters", maxUserSearchLength))
+
+// parseUserListQuery は一覧の絞り込み・並び替えのクエリパラメータを検証して読む。
+// 不正なパラメータは項目ごとの検証エラー（domain.ValidationError）で返す
+func parseUserListQuery(c *gin.Context) (domain.UserFilter, domain.UserSort, error) {
+	var filter domain.UserFilter
+	var sort domain.UserSort
+
+	field, ok := domain.ParseUserSortField(c.Query("sort"))
+	if !ok {
+		return filter, sort, domain.NewValidationError("sort", "oneof", "must be one of "+joinSortFields())
+	}
+	sort.Field = field
+	switch c.DefaultQuery("order", "asc") {
+	case "asc":
+	case "desc":
+		sort.Desc = true
+	default:
+		return filter, sort, domain.NewValidationError("order", "oneof", "must be one of asc, desc")
+	}
+
+	filter.Query = c.Query("q")
+	if len(filter.Query) > maxUserSearchLength {
+		return filter, sort, errSearchTooLong
+	}
+	filter.EmailDomain = c.Query("email_domain")
+	if len(filter.EmailDomain) > maxUserSearchLength || strings.Contains(filter.EmailDomain, "@") {
+		return filter, sort, domain.NewValidationError("email_domain", "hostname", "must be a domain name without @")
+	}
+	if s := c.Query("role"); s != "" {
+		role, err := domain.ParseRole(s)
+		if err != nil {
+			return filter, sort, domain.NewValidationError("role", "oneof", "must be one of admin, support, member")
+		}
+		filter.Role = role
+	}
+	if s := c.Query("verified"); s != "" {
+		verified, err := strconv.ParseBool(s)
+		if err != nil {
+			return filter, sort, domain.NewValidationError("verified", "type", "must be true or false")
+		}
+		filter.Verified = &verified
+	}
+
+	var err error
+	if filter.CreatedFrom, err = queryTime(c, "created_from"); err != nil {
+		return filter, sort, domain.NewValidationError("created_from", "datetime", "must be an RFC3339 datetime")
+	}
+	if filter.CreatedTo, err = queryTime(c, "created_to"); err != nil {
+		return filter, sort, domain.NewValidationError("created_to", "datetime", "must be an RFC3339 datetime")
+	}
+	return filter, sort, nil
+}
+
+func joinSortFields() string {
+	names := make([]string, 0, len(domain.UserSortFields))
+	for _, f := range domain.UserSortFields {
+		names = append(names, string(f))
+	}
+	return strings.Join(names, ", ")
 }
 
 // CreateUser godoc
 // @Summary      ユーザーの新規作成
-// @Description  ユーザー情報を登録します。
+// @Description  ユーザー情報を登録します。（ポリシーで users:create が許可されている必要があります）
+// @Description  Idempotency-Key を付けると、同じキーで再送されたリクエストは処理せずに最初のレスポンスを返します（Idempotent-Replayed: true）。
 // @Tags         users
 // @Accept       json
 // @Produce      json
-// @Param        user  body      domain.User  true  "ユーザー情報"
+// @Param        Idempotency-Key header string false "再送しても一度だけ処理するためのリクエストごとに一意なキー"
+// @Param        user  body      CreateUserRequest  true  "ユーザー情報"
 // @Success      201   {string}  string       "Created"
-// @Failure      400   {object}  handler.ErrorResponse        "invalid request"
-// @Failure      500   {object}  handler.ErrorResponse        "internal server error"
+// @Header       201   {string}  Idempotent-Replayed  "保存したレスポンスを返した場合は true"
+// @Failure      400   {object}  problem.Details        "invalid request"
+// @Failure      403   {object}  problem.Details        "forbidden"
+// @Failure      409   {object}  problem.Details        "email already taken or request with the same Idempotency-Key in progress"
+// @Failure      422   {object}  problem.Details        "Idempotency-Key reused for a different request"
+// @Failure      500   {object}  problem.Details        "internal server error"
 // @Router       /users [post]
 // @Security     BearerAuth
 func (h *UserHandler) CreateUser(c *gin.Context) {
-	var req domain.User
+	if !authorize(c, h.policy, actionUsersCreate, policy.Resource{Type: resourceUser}) {
+		return
+	}
+
+	var req CreateUserRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid request"})
+		problem.Binding(c, err)
 		return
 	}
-	if err := h.service.CreateUser(&req); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	user, err := domain.NewUser(req.Name, req.Email, req.Password)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if req.Role != "" {
+		if user.Role, err = domain.ParseRole(req.Role); err != nil {
+			problem.Error(c, domain.NewValidationError("role", "oneof", "must be one of admin, support, member"))
+			return
+		}
+	}
+	if err := h.service.CreateUser(auditContext(c), user); err != nil {
+		problem.Error(c, err)
 		return
 	}
 	c.Status(http.StatusCreated)
@@ -84,41 +254,55 @@ func (h *UserHandler) CreateUser(c *gin.Context) {
 
 // GetUser godoc
 // @Summary      ユーザーの取得
-// @Description  指定されたIDのユーザー情報を取得します。
+// @Description  指定されたIDのユーザー情報を取得します。（ポリシーで users:read が許可されている必要があります）
+// @Description  ETag ヘッダーの値を、更新・削除時の If-Match に指定してください。
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        id   path      int  true  "ユーザーID"
-// @Success      200  {object}  domain.User
-// @Failure      400  {object}  handler.ErrorResponse  "invalid ID"
-// @Failure      404  {object}  handler.ErrorResponse  "user not found"
+// @Success      200  {object}  handler.UserResponse
+// @Header       200  {string}  ETag  "ユーザーのバージョン"
+// @Failure      400  {object}  problem.Details  "invalid ID"
+// @Failure      403  {object}  problem.Details  "forbidden"
+// @Failure      404  {object}  problem.Details  "user not found"
 // @Router       /users/{id} [get]
 // @Security     BearerAuth
 func (h *UserHandler) GetUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid ID"})
+		problem.Error(c, errInvalidUserID)
+		return
+	}
+	if !authorize(c, h.policy, actionUsersRead, userResource(uint(id))) {
 		return
 	}
 	user, err := h.service.GetUserByID(uint(id))
 	if err != nil {
-		c.JSON(http.StatusNotFound, ErrorResponse{Error: "user not found"})
+		problem.Error(c, err)
 		return
 	}
-	c.JSON(http.StatusOK, user)
+	setUserETag(c, user)
+	c.JSON(http.StatusOK, newUserResponse(user))
 }
 
 // @Summary      ユーザー情報の更新
-// @Description  指定されたIDのユーザー情報を更新します。
+// @Description  指定されたIDのユーザー情報を更新します。変更するフィールドを含めてポリシー（users:update）で認可します。
+// @Description  メールアドレスを変更した場合は新しいアドレスに確認メールを送り、確認が済んだ時点で反映されます。
+// @Description  If-Match に取得時の ETag が必要で、その後に他の更新があった場合は 412 を返します。
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        id   path      int  true  "ユーザーID"
+// @Param        If-Match header string true "取得時の ETag"
 // @Param        user body      domain.User true "更新するユーザー情報"
-// @Success      200  {object}  domain.User
-// @Failure      400  {object}  ErrorResponse  "invalid request or ID"
-// @Failure      403  {object}  ErrorResponse "unauthorized"
-// @Failure      404  {object}  ErrorResponse  "user not found"
+// @Success      200  {object}  handler.UserResponse
+// @Header       200  {string}  ETag  "更新後のバージョン"
+// @Failure      400  {object}  problem.Details  "invalid request or ID"
+// @Failure      403  {object}  problem.Details "unauthorized"
+// @Failure      404  {object}  problem.Details  "user not found"
+// @Failure      409  {object}  problem.Details  "email already exists"
+// @Failure      412  {object}  problem.Details  "version mismatch"
+// @Failure      428  {object}  problem.Details  "If-Match required"
 // @Router       /users/{id} [put]
 // @Security     BearerAuth
 func (h *UserHandler) UpdateUser(c *gin.Context) {
@@ -126,14 +310,17 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	idParam := c.Param("id")
 	id, err := strconv.Atoi(idParam)
 	if err != nil || id <= 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid user ID"})
+		problem.Error(c, errInvalidUserID)
 		return
 	}
 
-	// 認証情報から userID を取得（本人チェック用）
-	authUserID, exists := c.Get("userID")
-	if !exists || authUserID.(uint) != uint(id) {
-		c.JSON(http.StatusForbidden, ErrorResponse{Error: "You can update only your own profile"})
+	// 存在やバージョンを知らせる前に、対象のユーザーを更新できるかを認可する
+	if !authorize(c, h.policy, actionUsersUpdate, userResource(uint(id))) {
+		return
+	}
+
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
 		return
 	}
 
@@ -141,84 +328,240 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	var req struct {
 		Name     string `json:"name" binding:"required"`
 		Email    string `json:"email" binding:"required,email"`
-		Password string `json:"password,omitempty" binding:"omitempty,min=6"`
+		Password string `json:"password,omitempty" binding:"omitempty,min=6,max=72"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
-		log.Printf("[ERROR] Failed to bind JSON: %v", err)
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request"})
+		problem.Binding(c, err)
 		return
 	}
 
-	// 更新用のUser構造体を作成
-	user := &domain.User{
-		ID:       uint(id),
-		Name:     req.Name,
-		Email:    req.Email,
-		Password: req.Password,
+	// 変更されるフィールドを求めてポリシーで認可する（本人チェックもポリシーで行う）
+	existing, err := h.service.GetUserByID(uint(id))
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	version, err := matchVersion(ifMatch, existing)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	var fields []string
+	if req.Name != existing.Name {
+		fields = append(fields, "name")
+	}
+	if domain.NormalizeEmail(req.Email) != existing.Email {
+		fields = append(fields, "email")
+	}
+	if req.Password != "" {
+		fields = append(fields, "password")
+	}
+	if !authorize(c, h.policy, actionUsersUpdate, userResource(uint(id)), fields...) {
+		return
 	}
 
-	log.Printf("[DEBUG] req: %+v", req)
-	log.Printf("[DEBUG] user: %+v", user)
+	// 更新するフィールド。name と email は必須なので常に指定する
+	patch := domain.UserPatch{Name: &req.Name, Email: &req.Email}
 
 	// パスワードは任意更新のため、あればセット（暗号化はサービス層で）
 	if req.Password != "" {
-		user.Password = req.Password
+		patch.Password = &req.Password
 	}
 
-	if err := h.service.UpdateUser(user); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update user"})
+	user, err := h.service.PatchUser(auditContext(c), uint(id), version, patch)
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
 
-	c.Status(http.StatusOK)
+	setUserETag(c, user)
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
+// @Summary      ユーザー情報の部分更新
+// @Description  指定されたIDのユーザー情報のうち、パッチで変わるフィールドだけを更新します。変更するフィールドを含めてポリシー（users:update）で認可します。
+// @Description  Content-Type が application/merge-patch+json なら JSON Merge Patch（RFC 7386）、application/json-patch+json なら JSON Patch（RFC 6902）として、{"name": ..., "email": ...} のドキュメントに適用します。
+// @Description  password は書き込み専用でドキュメントには含まれませんが、merge patch の password や JSON Patch の add /password で変更できます。
+// @Description  name・email・password は削除できず、null の指定や remove は 400 になります。JSON Patch の test が一致しない場合は 409 を返します。
+// @Description  メールアドレスを変更した場合は新しいアドレスに確認メールを送り、確認が済んだ時点で反映されます。
+// @Description  If-Match に取得時の ETag が必要で、その後に他の更新があった場合は 412 を返します。
+// @Tags         users
+// @Accept       application/merge-patch+json,application/json-patch+json
+// @Produce      json
+// @Param        id   path      int  true  "ユーザーID"
+// @Param        If-Match header string true "取得時の ETag"
+// @Param        patch body     object true "merge patch のオブジェクト、または JSON Patch の操作の配列"
+// @Success      200  {object}  handler.UserResponse
+// @Header       200  {string}  ETag  "更新後のバージョン"
+// @Failure      400  {object}  problem.Details  "invalid patch or ID"
+// @Failure      403  {object}  problem.Details  "unauthorized"
+// @Failure      404  {object}  problem.Details  "user not found"
+// @Failure      409  {object}  problem.Details  "email already exists or test operation failed"
+// @Failure      412  {object}  problem.Details  "version mismatch"
+// @Failure      415  {object}  problem.Details  "unsupported patch format"
+// @Header       415  {string}  Accept-Patch  "受け付けるパッチの形式"
+// @Failure      428  {object}  problem.Details  "If-Match required"
+// @Router       /users/{id} [patch]
+// @Security     BearerAuth
+func (h *UserHandler) PatchUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		problem.Error(c, errInvalidUserID)
+		return
+	}
+	// 存在やバージョンを知らせる前に、対象のユーザーを更新できるかを認可する
+	if !authorize(c, h.policy, actionUsersUpdate, userResource(uint(id))) {
+		return
+	}
+
+	apply, ok := userPatchers[c.ContentType()]
+	if !ok {
+		c.Header("Accept-Patch", acceptUserPatch)
+		problem.Respond(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType,
+			"Content-Type must be one of "+acceptUserPatch)
+		return
+	}
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+	body, err := c.GetRawData()
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	existing, err := h.service.GetUserByID(uint(id))
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	version, err := matchVersion(ifMatch, existing)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	// パッチを適用した結果から変わるフィールドを求めてポリシーで認可する（本人チェックもポリシーで行う）
+	changes, fields, err := applyUserPatch(existing, apply, body)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if !authorize(c, h.policy, actionUsersUpdate, userResource(uint(id)), fields...) {
+		return
+	}
+
+	user, err := h.service.PatchUser(auditContext(c), uint(id), version, changes)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+
+	setUserETag(c, user)
+	c.JSON(http.StatusOK, newUserResponse(user))
 }
 
 // @Summary      ユーザーの削除
-// @Description  指定されたIDのユーザーを削除します。
+// @Description  指定されたIDのユーザーを論理削除します。猶予期間内であれば復元でき、過ぎると物理削除されます。（ポリシーで users:delete が許可されている必要があります）
+// @Description  削除したユーザーのアクセストークンとリフレッシュトークンはすべて失効します（復元してもログインし直す必要があります）。
 // @Tags         users
 // @Accept       json
 // @Produce      json
 // @Param        id   path      int  true  "ユーザーID"
+// @Param        If-Match header string true "取得時の ETag"
 // @Success      204  {string}  string  "No Content"
-// @Failure      400  {object}  ErrorResponse  "invalid ID"
-// @Failure      403  {object}  ErrorResponse  "unauthorized"
-// @Failure      404  {object}  ErrorResponse  "user not found"
+// @Failure      400  {object}  problem.Details  "invalid ID"
+// @Failure      403  {object}  problem.Details  "unauthorized"
+// @Failure      404  {object}  problem.Details  "user not found"
+// @Failure      412  {object}  problem.Details  "version mismatch"
+// @Failure      428  {object}  problem.Details  "If-Match required"
 // @Router       /users/{id} [delete]
 // @Security     BearerAuth
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "invalid ID"})
+		problem.Error(c, errInvalidUserID)
+		return
+	}
+	if !authorize(c, h.policy, actionUsersDelete, userResource(uint(id))) {
+		return
+	}
+	ifMatch, ok := requireIfMatch(c)
+	if !ok {
+		return
+	}
+	user, err := h.service.GetUserByID(uint(id))
+	if err != nil {
+		problem.Error(c, err)
 		return
 	}
-	if err := h.service.DeleteUser(uint(id)); err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	version, err := matchVersion(ifMatch, user)
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	if err := h.service.DeleteUser(auditContext(c), uint(id), version); err != nil {
+		problem.Error(c, err)
 		return
 	}
 	c.Status(http.StatusNoContent)
 }
 
+// RestoreUser godoc
+// @Summary      削除したユーザーの復元
+// @Description  論理削除されたユーザーを復元します。削除から猶予期間を過ぎたユーザーは復元できません。（ポリシーで users:restore が許可されている必要があります）
+// @Tags         users
+// @Produce      json
+// @Param        id   path      int  true  "ユーザーID"
+// @Success      200  {object}  handler.UserResponse
+// @Failure      400  {object}  problem.Details  "invalid ID"
+// @Failure      403  {object}  problem.Details  "forbidden"
+// @Failure      404  {object}  problem.Details  "deleted user not found"
+// @Router       /users/{id}/restore [post]
+// @Security     BearerAuth
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		problem.Error(c, errInvalidUserID)
+		return
+	}
+	if !authorize(c, h.policy, actionUsersRestore, userResource(uint(id))) {
+		return
+	}
+
+	user, err := h.service.RestoreUser(auditContext(c), uint(id))
+	if err != nil {
+		problem.Error(c, err)
+		return
+	}
+	setUserETag(c, user)
+	c.JSON(http.StatusOK, newUserResponse(user))
+}
+
 // @Summary ログインユーザー情報を取得
 // @Description JWTトークンに基づいて、現在のログインユーザーの情報を取得します。
 // @Tags Users
 // @Security BearerAuth
 // @Produce json
-// @Success 200 {object} domain.User
-// @Failure 401 {object} handler.ErrorResponse
+// @Success 200 {object} handler.UserResponse
+// @Header 200 {string} ETag "ユーザーのバージョン"
+// @Failure 401 {object} problem.Details
 // @Router /me [get]
 func (h *UserHandler) Me(c *gin.Context) {
 	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "user not found in context"})
+		problem.Respond(c, http.StatusUnauthorized, problem.CodeUnauthorized, "user not found in context")
 		return
 	}
 
 	user, err := h.service.GetUserByID(userID.(uint))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "failed to get user"})
+		problem.Error(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, user)
+	setUserETag(c, user)
+	c.JSON(http.StatusOK, newUserResponse(user))
 }
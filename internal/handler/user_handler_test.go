@@ -2,22 +2,31 @@ package handler_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
 
+	"github.com/okamuuu/go-user-app/internal/cursor"
 	"github.com/okamuuu/go-user-app/internal/domain"
 	"github.com/okamuuu/go-user-app/internal/handler"
 	"github.com/okamuuu/go-user-app/internal/middleware"
+	"github.com/okamuuu/go-user-app/internal/notify"
+	"github.com/okamuuu/go-user-app/internal/policy"
+	"github.com/okamuuu/go-user-app/internal/problem"
 	"github.com/okamuuu/go-user-app/internal/repository"
 	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/okamuuu/go-user-app/internal/signing"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
@@ -32,33 +41,88 @@ func setupRouter() (*gin.Engine, *gorm.DB, *handler.UserHandler, *service.AuthSe
 	}
 
 	// マイグレーション
-	if err := db.AutoMigrate(&domain.User{}); err != nil {
+	if err := db.AutoMigrate(&domain.User{}, &repository.RefreshToken{}, &repository.RevokedToken{}, &repository.UserTokenRevocation{}, &repository.EmailVerificationToken{}, &repository.MFACredential{}, &repository.AuditLog{}); err != nil {
 		panic(err)
 	}
 
 	// リポジトリ、サービス、ハンドラー作成
 	userRepo := repository.NewUserRepository(db)
-	userService := service.NewUserService(userRepo)
+	auditService := service.NewAuditService(repository.NewAuditRepository(db))
+	emailVerificationService := service.NewEmailVerificationService(
+		userRepo,
+		repository.NewEmailVerificationRepository(db),
+		auditService,
+		notify.NewLogNotifier(),
+		"http://localhost:3000/email/verify",
+		time.Hour,
+	)
+	mfaService := service.NewMFAService(userRepo, repository.NewMFARepository(db), auditService, "Go User App")
 
 	jwtSecret := []byte("test-secret")
 	expireHours := 1000
 
-	authService := service.NewAuthService(userRepo, []byte(jwtSecret), time.Duration(expireHours)*time.Hour)
-	userHandler := handler.NewUserHandler(userService)
+	refreshTokenRepo := repository.NewRefreshTokenRepository(db)
+	revocationRepo := repository.NewRevocationRepository(db)
+
+	authService := service.NewAuthService(userRepo, emailVerificationService, mfaService, auditService, refreshTokenRepo, revocationRepo, signing.NewHMACKeySet(jwtSecret), time.Duration(expireHours)*time.Hour, time.Duration(expireHours)*time.Hour)
+	userService := service.NewUserService(userRepo, emailVerificationService, authService, auditService, cursor.NewCodec([]byte("test-cursor-secret")), 30*24*time.Hour)
+	userHandler := handler.NewUserHandler(userService, policy.Default())
 
 	// ルーター作成
 	r := gin.Default()
 
-	authMiddleware := middleware.AuthMiddleware([]byte(jwtSecret))
+	authMiddleware := middleware.AuthMiddleware(signing.NewHMACKeySet(jwtSecret), authService)
 
 	// 認証ミドルウェアを適用したルートグループ
 	authorized := r.Group("/")
 	authorized.Use(authMiddleware)
+	authorized.GET("/api/users", userHandler.GetUsers)
+	authorized.GET("/api/users/search", userHandler.SearchUsers)
+	authorized.GET("/api/users/:id", userHandler.GetUser)
+	authorized.POST("/api/users", userHandler.CreateUser)
 	authorized.PUT("/api/users/:id", userHandler.UpdateUser)
+	authorized.PATCH("/api/users/:id", userHandler.PatchUser)
+	authorized.DELETE("/api/users/:id", userHandler.DeleteUser)
+	authorized.POST("/api/users/:id/restore", userHandler.RestoreUser)
+	authorized.GET("/api/me", userHandler.Me)
 
 	return r, db, userHandler, authService
 }
 
+func TestLogoutAll_SameSecond(t *testing.T) {
+	r, db, _, authService := setupRouter()
+
+	user := &domain.User{Name: "Logout", Email: "logout-all@example.com", Password: "password"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	me := func(token string) int {
+		req, _ := http.NewRequest(http.MethodGet, "/api/me", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	before, err := authService.GenerateJWT(user)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	if err := authService.LogoutAll(context.Background(), user.ID); err != nil {
+		t.Fatalf("failed to logout all: %v", err)
+	}
+	// 失効と同じミリ秒に発行したトークンは失効対象に含まれるので、1ミリ秒以上あけてログインし直す
+	time.Sleep(2 * time.Millisecond)
+	after, err := authService.GenerateJWT(user)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	// 一括失効の前のトークンは使えず、同じ秒のうちにログインし直したトークンは使える
+	assert.Equal(t, http.StatusUnauthorized, me(before))
+	assert.Equal(t, http.StatusOK, me(after))
+}
+
 func TestUpdateUser(t *testing.T) {
 	r, db, _, authService := setupRouter()
 
@@ -88,6 +152,7 @@ func TestUpdateUser(t *testing.T) {
 	req, _ := http.NewRequest(http.MethodPut, "/api/users/"+strconv.Itoa(int(user.ID)), bytes.NewBuffer(jsonBody))
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("If-Match", "*")
 
 	// レスポンス取得用Recorder
 	w := httptest.NewRecorder()
@@ -98,6 +163,13 @@ func TestUpdateUser(t *testing.T) {
 	// ステータスコードチェック
 	assert.Equal(t, http.StatusOK, w.Code)
 
+	// 更新後のユーザーと ETag を返し、パスワードは含まない
+	var res handler.UserResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	assert.Equal(t, "New Name", res.Name)
+	assert.Equal(t, fmt.Sprintf(`"%d"`, res.Version), w.Header().Get("ETag"))
+	assert.NotContains(t, w.Body.String(), `"Password"`)
+
 	// DBの値も確認
 	var updatedUser domain.User
 	if err := db.First(&updatedUser, user.ID).Error; err != nil {
@@ -105,7 +177,482 @@ func TestUpdateUser(t *testing.T) {
 	}
 
 	assert.Equal(t, "New Name", updatedUser.Name)
-	assert.Equal(t, "new@example.com", updatedUser.Email)
+	// メールアドレスは確認が済むまで変更されない
+	assert.Equal(t, "old@example.com", updatedUser.Email)
+
+	var pending repository.EmailVerificationToken
+	if err := db.Where("user_id = ?", user.ID).First(&pending).Error; err != nil {
+		t.Fatalf("verification token for the new email not found: %v", err)
+	}
+	assert.Equal(t, "new@example.com", pending.Email)
 	// パスワードはハッシュ化されているはずなので値は異なる
 	assert.NotEqual(t, "newpassword", updatedUser.Password)
+
+	// 監査ログには操作者と変更内容が残り、パスワードは伏せられる
+	var audit repository.AuditLog
+	if err := db.Where("action = ? AND target_id = ?", domain.AuditUserUpdated, user.ID).First(&audit).Error; err != nil {
+		t.Fatalf("audit event not found: %v", err)
+	}
+	assert.Equal(t, user.ID, *audit.ActorID)
+	assert.Contains(t, audit.After, `"name":"New Name"`)
+	assert.Contains(t, audit.After, `"password":"[REDACTED]"`)
+	assert.NotContains(t, audit.After, updatedUser.Password)
+}
+
+func TestGetUsers_FilterSortAndCursor(t *testing.T) {
+	r, db, _, authService := setupRouter()
+
+	admin := &domain.User{Name: "Admin", Email: "admin@admin.list.test", Password: "password", Role: domain.RoleAdmin}
+	if err := db.Create(admin).Error; err != nil {
+		t.Fatalf("failed to create admin: %v", err)
+	}
+	for _, name := range []string{"carol", "alice", "dave", "bob"} {
+		if err := db.Create(&domain.User{Name: name, Email: name + "@list.test", Password: "password", Role: domain.RoleMember}).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	token, err := authService.GenerateJWT(admin)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	names := func(users []domain.User) []string {
+		var result []string
+		for _, u := range users {
+			result = append(result, u.Name)
+		}
+		return result
+	}
+
+	// カーソル方式では、絞り込み・並び順を保ったまま次のページをたどれる
+	w := get("/api/users?email_domain=list.test&sort=name&order=desc&limit=3")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var first struct {
+		Items []domain.User `json:"items"`
+		Next  string        `json:"next"`
+		Prev  string        `json:"prev"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &first))
+	assert.Equal(t, []string{"dave", "carol", "bob"}, names(first.Items))
+	assert.NotContains(t, w.Body.String(), `"Password"`, "password hash is not returned")
+	assert.Empty(t, first.Prev)
+	assert.Contains(t, first.Next, "email_domain=list.test")
+
+	w = get(first.Next)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var second struct {
+		Items []domain.User `json:"items"`
+		Next  string        `json:"next"`
+	}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &second))
+	assert.Equal(t, []string{"alice"}, names(second.Items))
+	assert.Empty(t, second.Next)
+
+	assert.Contains(t, w.Header().Get("Link"), `rel="prev"`)
+
+	// page を指定するとオフセット方式で、総数と Link ヘッダーを返す
+	w = get("/api/users?page=1&limit=1&email_domain=list.test&sort=name")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var offset handler.UserListResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &offset))
+	assert.Len(t, offset.Items, 1)
+	assert.NotContains(t, w.Body.String(), `"Password"`)
+	if assert.NotNil(t, offset.Total) {
+		assert.Equal(t, int64(4), *offset.Total)
+	}
+	assert.Equal(t, 1, offset.Page)
+	assert.True(t, offset.HasMore)
+	link := w.Header().Get("Link")
+	assert.Contains(t, link, `page=2&sort=name>; rel="next"`)
+	assert.Contains(t, link, `page=4&sort=name>; rel="last"`)
+	assert.NotContains(t, link, `rel="prev"`)
+
+	// count=false なら総数を集計しない
+	w = get("/api/users?page=4&limit=1&email_domain=list.test&count=false")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), `"total"`)
+	assert.NotContains(t, w.Header().Get("Link"), `rel="last"`)
+	assert.Contains(t, w.Body.String(), `"has_more":false`)
+
+	w = get("/api/users?page=1&limit=10&q=AL&email_domain=list.test")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var found handler.UserListResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &found))
+	assert.Len(t, found.Items, 1)
+
+	for _, query := range []string{
+		"sort=password",
+		"order=sideways",
+		"role=owner",
+		"verified=maybe",
+		"created_from=yesterday",
+		"email_domain=a@b",
+		"cursor=forged",
+		"count=maybe",
+	} {
+		assert.Equal(t, http.StatusBadRequest, get("/api/users?"+query).Code, query)
+	}
+}
+
+func TestSearchUsers(t *testing.T) {
+	r, db, _, authService := setupRouter()
+
+	support := &domain.User{Name: "Support", Email: "support@search.test", Password: "password", Role: domain.RoleSupport}
+	if err := db.Create(support).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := db.Create(&domain.User{Name: "Zelda Quixote", Email: "zelda@search.test", Password: "password", Role: domain.RoleMember}).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := authService.GenerateJWT(support)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	get := func(url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/users/search?q=quix")
+	assert.Equal(t, http.StatusOK, w.Code)
+	var res handler.UserSearchResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &res))
+	if assert.Len(t, res.Items, 1) {
+		assert.Equal(t, "Zelda <mark>Quixote</mark>", res.Items[0].NameHighlight)
+		assert.Equal(t, "zelda@search.test", res.Items[0].User.Email)
+	}
+	assert.NotContains(t, w.Body.String(), `"Password"`, "password hash is not returned")
+
+	assert.Equal(t, http.StatusBadRequest, get("/api/users/search").Code)
+	assert.Equal(t, http.StatusBadRequest, get("/api/users/search?q=%40%40").Code, "no words")
+}
+
+func TestUpdateUser_ProblemDetails(t *testing.T) {
+	r, db, _, authService := setupRouter()
+
+	user := &domain.User{Name: "Problem", Email: "problem@example.com", Password: "password"}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	if err := db.Create(&domain.User{Name: "Taken", Email: "taken@example.com", Password: "password"}).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := authService.GenerateJWT(user)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	put := func(id string, body map[string]string) (*httptest.ResponseRecorder, problem.Details) {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(http.MethodPut, "/api/users/"+id, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var p problem.Details
+		assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		return w, p
+	}
+	id := strconv.Itoa(int(user.ID))
+
+	w, p := put(id, map[string]string{"name": "Problem", "email": "taken@example.com"})
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, http.StatusConflict, p.Status)
+	assert.Equal(t, "email_taken", p.Code)
+	assert.Equal(t, "/api/users/"+id, p.Instance)
+
+	// 他のユーザーは存在するかどうかを確かめる前に認可で拒否する
+	w, p = put("99999", map[string]string{"name": "Nobody", "email": "nobody@example.com"})
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, problem.CodeForbidden, p.Code)
+
+	w, p = put(id, map[string]string{"email": "not-an-email", "password": "short"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, problem.CodeValidation, p.Code)
+	assert.ElementsMatch(t, []problem.FieldError{
+		{Field: "name", Code: "required", Message: "is required"},
+		{Field: "email", Code: "email", Message: "must be a valid email address"},
+		{Field: "password", Code: "min", Message: "must be at least 6 characters"},
+	}, p.Errors)
+
+	w, p = put(id, map[string]string{"name": "Problem", "email": "problem@example.com", "password": strings.Repeat("a", 73)})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	if assert.Len(t, p.Errors, 1) {
+		assert.Equal(t, "password", p.Errors[0].Field)
+		assert.Equal(t, "max", p.Errors[0].Code)
+	}
+
+	w, p = put("abc", map[string]string{"name": "x", "email": "x@example.com"})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	if assert.Len(t, p.Errors, 1) {
+		assert.Equal(t, "id", p.Errors[0].Field)
+	}
+}
+
+func TestUpdateUser_IfMatch(t *testing.T) {
+	r, db, _, authService := setupRouter()
+
+	user := &domain.User{Name: "Etag", Email: "etag@example.com", Password: "password", Version: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := authService.GenerateJWT(user)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+	url := "/api/users/" + strconv.Itoa(int(user.ID))
+
+	serve := func(method, ifMatch string, body map[string]string) *httptest.ResponseRecorder {
+		jsonBody, _ := json.Marshal(body)
+		req, _ := http.NewRequest(method, url, bytes.NewBuffer(jsonBody))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		if ifMatch != "" {
+			req.Header.Set("If-Match", ifMatch)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	update := map[string]string{"name": "Renamed", "email": "etag@example.com"}
+
+	w := serve(http.MethodGet, "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Equal(t, `"1"`, etag)
+
+	// If-Match が無ければ 428
+	w = serve(http.MethodPut, "", update)
+	assert.Equal(t, http.StatusPreconditionRequired, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, http.StatusPreconditionRequired, serve(http.MethodDelete, "", nil).Code)
+
+	w = serve(http.MethodPut, etag, update)
+	assert.Equal(t, http.StatusOK, w.Code)
+	newETag := w.Header().Get("ETag")
+	assert.Equal(t, `"2"`, newETag)
+	assert.Equal(t, newETag, serve(http.MethodGet, "", nil).Header().Get("ETag"))
+
+	// 古い ETag からの更新・削除は上書きせずに 412
+	w = serve(http.MethodPut, etag, map[string]string{"name": "Lost Update", "email": "etag@example.com"})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+	var p problem.Details
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, "version_mismatch", p.Code)
+	assert.Equal(t, http.StatusPreconditionFailed, serve(http.MethodDelete, etag, nil).Code)
+	assert.Equal(t, http.StatusPreconditionFailed, serve(http.MethodDelete, "W/"+newETag, nil).Code, "weak ETags never match")
+
+	var stored domain.User
+	if err := db.First(&stored, user.ID).Error; err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	assert.Equal(t, "Renamed", stored.Name)
+
+	// 複数の ETag のいずれかが一致すれば削除できる
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, etag+", "+newETag, nil).Code)
+}
+
+func TestPatchUser(t *testing.T) {
+	r, db, _, authService := setupRouter()
+
+	user := &domain.User{Name: "Patch", Email: "patch@example.com", Password: "password", Version: 1}
+	if err := db.Create(user).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := authService.GenerateJWT(user)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	serve := func(contentType, body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPatch, "/api/users/"+strconv.Itoa(int(user.ID)), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+	stored := func() domain.User {
+		var u domain.User
+		if err := db.First(&u, user.ID).Error; err != nil {
+			t.Fatalf("failed to find user: %v", err)
+		}
+		return u
+	}
+	problemOf := func(w *httptest.ResponseRecorder) problem.Details {
+		var p problem.Details
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+		return p
+	}
+
+	// merge patch では指定したフィールドだけが変わり、他の列はそのまま
+	w := serve("application/merge-patch+json", `{"name":"Patched"}`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	assert.Equal(t, "Patched", stored().Name)
+	assert.Equal(t, "patch@example.com", stored().Email)
+	assert.Equal(t, "password", stored().Password)
+
+	// JSON Patch は操作をまとめて適用する。password は書き込み専用で add で設定する
+	w = serve("application/json-patch+json", `[
+		{"op":"test","path":"/name","value":"Patched"},
+		{"op":"replace","path":"/name","value":"Again"},
+		{"op":"add","path":"/password","value":"newpassword"}
+	]`)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "Again", stored().Name)
+	assert.NotEqual(t, "password", stored().Password)
+	assert.NotContains(t, w.Body.String(), "newpassword")
+	assert.NotContains(t, w.Body.String(), `"Password"`, "password hash is not returned")
+
+	// test が一致しなければ何も反映せずに 409
+	w = serve("application/json-patch+json", `[{"op":"replace","path":"/name","value":"Lost"},{"op":"test","path":"/name","value":"Patched"}]`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "patch_test_failed", problemOf(w).Code)
+	assert.Equal(t, "Again", stored().Name)
+
+	// null や remove でフィールドを消すことはできない
+	cases := []struct {
+		contentType, body string
+		field, code       string
+	}{
+		{"application/merge-patch+json", `{"email":null}`, "email", "not_null"},
+		{"application/merge-patch+json", `{"password":null}`, "password", "not_null"},
+		{"application/json-patch+json", `[{"op":"remove","path":"/name"}]`, "name", "required"},
+		{"application/json-patch+json", `[{"op":"replace","path":"/name","value":null}]`, "name", "not_null"},
+		{"application/merge-patch+json", `{"role":"admin"}`, "role", "unknown"},
+		{"application/merge-patch+json", `{"email":"not-an-email"}`, "email", "email"},
+		{"application/merge-patch+json", `{"password":"short"}`, "password", "min"},
+		{"application/merge-patch+json", `{"password":"` + strings.Repeat("a", 73) + `"}`, "password", "max"},
+		// 文字数では 72 以内でも、bcrypt で扱えない 72 バイトを超えるパスワード
+		{"application/merge-patch+json", `{"password":"` + strings.Repeat("あ", 25) + `"}`, "password", "max"},
+		{"application/merge-patch+json", `{"name":1}`, "name", "type"},
+	}
+	for _, tc := range cases {
+		w = serve(tc.contentType, tc.body)
+		assert.Equal(t, http.StatusBadRequest, w.Code, tc.body)
+		p := problemOf(w)
+		if assert.Len(t, p.Errors, 1, tc.body) {
+			assert.Equal(t, tc.field, p.Errors[0].Field, tc.body)
+			assert.Equal(t, tc.code, p.Errors[0].Code, tc.body)
+		}
+	}
+
+	w = serve("application/json-patch+json", `[{"op":"replace","path":"/missing","value":1}]`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_patch", problemOf(w).Code)
+
+	// 対応していない形式は 415 で、受け付ける形式を Accept-Patch で返す
+	w = serve("application/json", `{"name":"Plain"}`)
+	assert.Equal(t, http.StatusUnsupportedMediaType, w.Code)
+	assert.Equal(t, "unsupported_media_type", problemOf(w).Code)
+	assert.Contains(t, w.Header().Get("Accept-Patch"), "application/merge-patch+json")
+	assert.Equal(t, "Again", stored().Name)
+
+	// 他のユーザーは、存在やバージョンを確かめる前に認可で拒否する（If-Match がなくても 403）
+	req, _ := http.NewRequest(http.MethodPatch, "/api/users/99999", bytes.NewBufferString(`{"name":"Nobody"}`))
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("Authorization", "Bearer "+token)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, problem.CodeForbidden, problemOf(w).Code)
+}
+
+func TestUserResponses_OmitPassword(t *testing.T) {
+	r, db, _, authService := setupRouter()
+
+	admin := &domain.User{Name: "Admin", Email: "admin@omit.test", Password: "$2a$10$admin-hash", Role: domain.RoleAdmin, Version: 1}
+	user := &domain.User{Name: "Member", Email: "member@omit.test", Password: "$2a$10$member-hash", Version: 1}
+	for _, u := range []*domain.User{admin, user} {
+		if err := db.Create(u).Error; err != nil {
+			t.Fatalf("failed to create user: %v", err)
+		}
+	}
+	token, err := authService.GenerateJWT(admin)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	serve := func(method, url string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, url, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		req.Header.Set("If-Match", "*")
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	userURL := "/api/users/" + strconv.Itoa(int(user.ID))
+	assert.Equal(t, http.StatusNoContent, serve(http.MethodDelete, userURL).Code)
+	for _, w := range []*httptest.ResponseRecorder{
+		serve(http.MethodPost, userURL+"/restore"),
+		serve(http.MethodGet, userURL),
+		serve(http.MethodGet, "/api/me"),
+	} {
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"Email"`)
+		assert.NotContains(t, w.Body.String(), `"Password"`)
+		assert.NotContains(t, w.Body.String(), "-hash")
+	}
+}
+
+func TestCreateUser(t *testing.T) {
+	r, db, _, authService := setupRouter()
+
+	admin := &domain.User{Name: "Admin", Email: "admin@create.test", Password: "password", Role: domain.RoleAdmin}
+	if err := db.Create(admin).Error; err != nil {
+		t.Fatalf("failed to create user: %v", err)
+	}
+	token, err := authService.GenerateJWT(admin)
+	if err != nil {
+		t.Fatalf("failed to generate token: %v", err)
+	}
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(http.MethodPost, "/api/users", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve(`{"name":"Created","email":"Created@Create.test","password":"password123","role":"support"}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var stored domain.User
+	if err := db.Where("email = ?", "created@create.test").First(&stored).Error; err != nil {
+		t.Fatalf("failed to find user: %v", err)
+	}
+	assert.Equal(t, domain.RoleSupport, stored.Role)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("password123")), "password is hashed")
+
+	// 不正な項目は作成せずに 400
+	for _, body := range []string{
+		`{"name":"","email":"empty-name@create.test","password":"password123"}`,
+		`{"name":" ","email":"blank-name@create.test","password":"password123"}`,
+		`{"name":"NoEmail","email":"","password":"password123"}`,
+		`{"name":"BadRole","email":"bad-role@create.test","password":"password123","role":"owner"}`,
+		`{"name":"Short","email":"short@create.test","password":"short"}`,
+	} {
+		assert.Equal(t, http.StatusBadRequest, serve(body).Code, body)
+	}
+	var count int64
+	db.Model(&domain.User{}).Where("email LIKE ?", "%@create.test").Count(&count)
+	assert.Equal(t, int64(2), count)
 }
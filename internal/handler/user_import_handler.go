@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/policy"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/service"
+)
+
+// importFormats は Content-Type ごとの一括登録の入力形式
+var importFormats = map[string]string{
+	"text/csv":             service.ImportFormatCSV,
+	"application/x-ndjson": service.ImportFormatNDJSON,
+	"application/ndjson":   service.ImportFormatNDJSON,
+}
+
+type UserImportHandler struct {
+	service *service.UserImportService
+	policy  *policy.Engine
+}
+
+func NewUserImportHandler(service *service.UserImportService, policy *policy.Engine) *UserImportHandler {
+	return &UserImportHandler{service: service, policy: policy}
+}
+
+// Import godoc
+// @Summary      ユーザーの一括登録
+// @Description  CSV（1行目はヘッダー）または NDJSON でユーザーを一括登録し、行ごとの結果を返します。（ポリシーで users:import が許可されている必要があります）
+// @Description  項目は name, email, password, role（省略時は member）で、各行はサインアップと同じ規則で検証します。
+// @Description  password を省略した行は、パスワードを設定するための招待リンクをメールで送ります。
+// @Description  100行ごとに1つのトランザクションで登録し、不正な行やメールアドレスが使われている行は失敗として報告して続きを登録します。
+// @Description  途中で続けられなくなった場合（NDJSON の 64 KiB を超える行、読み込みや登録のエラー）は、エラーのステータスで登録を終えた行までの結果を返します。
+// @Description  結果の error に止めた理由が入り、rows の行までは登録済みで、それより後の行は登録していません。
+// @Tags         users
+// @Accept       text/csv,application/x-ndjson
+// @Produce      json
+// @Param        format  query     string  false  "入力形式（省略時は Content-Type から判定）"  Enums(csv, ndjson)
+// @Param        users   body      string  true   "登録するユーザー"
+// @Success      200     {object}  service.UserImportReport
+// @Failure      400     {object}  problem.Details  "invalid header or format (a report with error if a later line is invalid)"
+// @Failure      403     {object}  problem.Details  "forbidden"
+// @Failure      415     {object}  problem.Details  "unsupported content type"
+// @Failure      500     {object}  problem.Details  "internal server error"
+// @Router       /users/import [post]
+// @Security     BearerAuth
+func (h *UserImportHandler) Import(c *gin.Context) {
+	if !authorize(c, h.policy, actionUsersImport, policy.Resource{Type: resourceUser}) {
+		return
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		var ok bool
+		if format, ok = importFormats[c.ContentType()]; !ok {
+			problem.Respond(c, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType,
+				"Content-Type must be text/csv or application/x-ndjson, or specify the format parameter")
+			return
+		}
+	}
+
+	// ボディはまとめて読み込まず、読みながら登録する
+	report, err := h.service.Import(auditContext(c), format, c.Request.Body)
+	if err != nil && report == nil {
+		problem.Error(c, err)
+		return
+	}
+	if err != nil {
+		// 途中で止めた場合は、どこまで登録したかが分かるようにそれまでの結果を返す
+		p := problem.FromError(err)
+		if p.Status == http.StatusInternalServerError {
+			log.Printf("[ERROR] %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		}
+		report.Error = p.Detail
+		c.JSON(p.Status, report)
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/patch"
+	"github.com/okamuuu/go-user-app/internal/problem"
+)
+
+// PATCH /users/:id で受け付けるパッチの形式（415 のときに Accept-Patch で返す）
+const acceptUserPatch = patch.MergePatchContentType + ", " + patch.JSONPatchContentType
+
+// userPatchFields はパッチで変更できるフィールド。
+// パッチを適用するドキュメントは {"name", "email"} で、password は書き込み専用のため含めない
+var userPatchFields = []string{"name", "email", "password"}
+
+// userPatchers は Content-Type ごとのパッチの適用方法
+var userPatchers = map[string]func(doc interface{}, body []byte) (interface{}, error){
+	patch.MergePatchContentType: mergeUserPatch,
+	patch.JSONPatchContentType:  patch.JSONPatch,
+}
+
+// userPatchDocument はパッチを適用した後のドキュメント。PUT と同じ規則で検証する
+type userPatchDocument struct {
+	Name     string  `json:"name" binding:"required"`
+	Email    string  `json:"email" binding:"required,email"`
+	Password *string `json:"password" binding:"omitempty,min=6,max=72"`
+}
+
+// mergeUserPatch は JSON Merge Patch を適用する。
+// null はメンバーの削除を表すが、ユーザーのフィールドはどれも削除できないので検証エラーにする
+func mergeUserPatch(doc interface{}, body []byte) (interface{}, error) {
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(body, &members); err == nil {
+		ve := &domain.ValidationError{}
+		for _, field := range userPatchFields {
+			if raw, ok := members[field]; ok && bytes.Equal(bytes.TrimSpace(raw), []byte("null")) {
+				ve.Fields = append(ve.Fields, domain.FieldError{Field: field, Code: "not_null", Message: "cannot be removed"})
+			}
+		}
+		if len(ve.Fields) > 0 {
+			return nil, ve
+		}
+	}
+	return patch.MergePatch(doc, body)
+}
+
+// applyUserPatch はユーザーのドキュメントにパッチを適用して検証し、今の値から変わるフィールドとその名前を返す
+func applyUserPatch(user *domain.User, apply func(doc interface{}, body []byte) (interface{}, error), body []byte) (domain.UserPatch, []string, error) {
+	var changes domain.UserPatch
+
+	result, err := apply(map[string]interface{}{"name": user.Name, "email": user.Email}, body)
+	if err != nil {
+		return changes, nil, userPatchError(err)
+	}
+	members, ok := result.(map[string]interface{})
+	if !ok {
+		return changes, nil, domain.NewError(domain.ErrValidation, "invalid_patch", "patched user must be a JSON object")
+	}
+
+	// 変更できないフィールドの追加や、null の代入は検証エラー
+	keys := make([]string, 0, len(members))
+	for k := range members {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	ve := &domain.ValidationError{}
+	for _, k := range keys {
+		switch {
+		case !isUserPatchField(k):
+			ve.Fields = append(ve.Fields, domain.FieldError{Field: k, Code: "unknown", Message: "is not a patchable field"})
+		case members[k] == nil:
+			ve.Fields = append(ve.Fields, domain.FieldError{Field: k, Code: "not_null", Message: "cannot be null"})
+		}
+	}
+	if len(ve.Fields) > 0 {
+		return changes, nil, ve
+	}
+
+	var doc userPatchDocument
+	data, err := json.Marshal(members)
+	if err != nil {
+		return changes, nil, err
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return changes, nil, problem.BindingError(err)
+	}
+	if err := binding.Validator.ValidateStruct(&doc); err != nil {
+		return changes, nil, problem.BindingError(err)
+	}
+
+	var fields []string
+	if doc.Name != user.Name {
+		changes.Name = &doc.Name
+		fields = append(fields, "name")
+	}
+	if domain.NormalizeEmail(doc.Email) != user.Email {
+		changes.Email = &doc.Email
+		fields = append(fields, "email")
+	}
+	if doc.Password != nil {
+		changes.Password = doc.Password
+		fields = append(fields, "password")
+	}
+	return changes, fields, nil
+}
+
+func isUserPatchField(name string) bool {
+	for _, f := range userPatchFields {
+		if f == name {
+			return true
+		}
+	}
+	return false
+}
+
+// userPatchError はパッチの適用に失敗したエラーを変換する。
+// JSON Patch の test が一致しないのは読み込んだ後の変更との競合なので 409、それ以外の不正なパッチは 400 にする
+func userPatchError(err error) error {
+	switch {
+	case errors.Is(err, patch.ErrTestFailed):
+		return domain.NewError(domain.ErrConflict, "patch_test_failed", err.Error())
+	case errors.Is(err, patch.ErrInvalid):
+		return domain.NewError(domain.ErrValidation, "invalid_patch", err.Error())
+	default:
+		return err
+	}
+}
@@ -0,0 +1,27 @@
+// Package job はアプリケーション内で定期的に実行するバックグラウンド処理を扱う
+package job
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+// Every は起動直後と、その後 interval ごとに fn を実行する。ctx がキャンセルされると終了する。
+// fn がエラーを返しても処理は継続し、ログに記録するだけにする。
+func Every(ctx context.Context, name string, interval time.Duration, fn func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := fn(); err != nil {
+			log.Printf("[ERROR] job %s failed: %v", name, err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -1,50 +1,100 @@
 package middleware
 
 import (
+	"math"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/signing"
 )
 
-func AuthMiddleware(secret []byte) gin.HandlerFunc {
+// RevocationChecker はアクセストークンの失効状態を問い合わせるためのインターフェース
+type RevocationChecker interface {
+	IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error)
+}
+
+func AuthMiddleware(keys *signing.KeySet, revocations RevocationChecker) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+			problem.Respond(c, http.StatusUnauthorized, "authorization_required", "authorization header required")
 			return
 		}
 
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			// アルゴリズムの検証
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, jwt.ErrSignatureInvalid
-			}
-			return secret, nil
-		})
+		// kid から検証鍵を選び、アルゴリズムも鍵の種類と一致するか検証する
+		token, err := keys.Parse(tokenString, jwt.MapClaims{})
 
 		if err != nil || !token.Valid {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
+			problem.Respond(c, http.StatusUnauthorized, "invalid_token", "invalid token")
 			return
 		}
 
 		claims, ok := token.Claims.(jwt.MapClaims)
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid token claims"})
+			problem.Respond(c, http.StatusUnauthorized, "invalid_token", "invalid token claims")
+			return
+		}
+
+		// MFA チャレンジ等、アクセストークン以外の用途のトークンは受け付けない
+		if typ, ok := claims["typ"].(string); ok && typ != "access" {
+			problem.Respond(c, http.StatusUnauthorized, "invalid_token", "invalid token type")
 			return
 		}
 
 		userID, ok := claims["user_id"].(float64) // JWTでは数値はfloat64になる
 		if !ok {
-			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "user_id not found in token"})
+			problem.Respond(c, http.StatusUnauthorized, "invalid_token", "user_id not found in token")
+			return
+		}
+
+		jti, ok := claims["jti"].(string)
+		if !ok || jti == "" {
+			problem.Respond(c, http.StatusUnauthorized, "invalid_token", "jti not found in token")
+			return
+		}
+
+		// iat はミリ秒まで持つ（秒の小数）。GetIssuedAt は秒に切り捨てるので値をそのまま読む
+		iat, ok := claims["iat"].(float64)
+		if !ok {
+			problem.Respond(c, http.StatusUnauthorized, "invalid_token", "iat not found in token")
 			return
 		}
+		issuedAt := time.UnixMilli(int64(math.Round(iat * 1000)))
+
+		// ログアウト等で失効済みのトークンは拒否する
+		revoked, err := revocations.IsRevoked(jti, uint(userID), issuedAt)
+		if err != nil {
+			problem.Respond(c, http.StatusInternalServerError, problem.CodeInternal, "failed to verify token")
+			return
+		}
+		if revoked {
+			problem.Respond(c, http.StatusUnauthorized, "token_revoked", "token has been revoked")
+			return
+		}
+
+		// ロールが無い（古い）トークンは一般ユーザーとして扱う
+		role := domain.RoleMember
+		if r, ok := claims["role"].(string); ok && r != "" {
+			if parsed, err := domain.ParseRole(r); err == nil {
+				role = parsed
+			}
+		}
 
 		// userID を context に保存しておく
 		c.Set("userID", uint(userID))
+		c.Set("role", role)
+		// ログアウト時に失効させるため、jti と有効期限も保存しておく
+		c.Set("jti", jti)
+		if expiresAt, err := claims.GetExpirationTime(); err == nil && expiresAt != nil {
+			c.Set("tokenExpiresAt", expiresAt.Time)
+		}
 
 		c.Next()
 	}
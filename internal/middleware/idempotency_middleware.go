@@ -0,0 +1,150 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/problem"
+)
+
+const (
+	// IdempotencyKeyHeader はクライアントがリクエストごとに付ける一意なキーのヘッダー
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// IdempotentReplayedHeader は保存したレスポンスを返したときに付けるヘッダー
+	IdempotentReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+	// 最初のリクエストの処理が終わらないまま（プロセスの停止など）キーが使えなくなるのを防ぐため、処理中の予約はこの時間で切れる
+	idempotencyLockTimeout = time.Minute
+)
+
+// IdempotencyStore は Idempotency-Key ごとの処理状況とレスポンスを保存する
+type IdempotencyStore interface {
+	// Begin はキーを処理中として予約する。有効期限内の記録が既にあればその記録を返す
+	Begin(record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error)
+	// Complete はレスポンスを record.ExpiresAt まで保存する
+	Complete(record *domain.IdempotencyRecord) error
+	// Release は処理中のキーの予約を取り消し、同じキーで再試行できるようにする
+	Release(keyHash string) error
+}
+
+// Idempotency は Idempotency-Key ヘッダーの付いたリクエストを一度だけ処理する。
+// 最初のリクエストのレスポンスを ttl の間保存し、同じキーで再送されたリクエストは処理せずに保存したレスポンスを返す。
+// 同じキーを内容（メソッド・URL・ボディ）の異なるリクエストに使うと 422、最初のリクエストの処理中に再送されると 409 を返す。
+// キーはメソッド・パス・ユーザーごとに区別する。認証が必要なルートでは AuthMiddleware の後に適用すること
+func Idempotency(store IdempotencyStore, ttl time.Duration) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			problem.Error(c, domain.NewValidationError(IdempotencyKeyHeader, "max",
+				fmt.Sprintf("must be at most %d characters", maxIdempotencyKeyLength)))
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			problem.Respond(c, http.StatusBadRequest, problem.CodeInvalidRequest, "failed to read request body")
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		scope := c.Request.Method + " " + c.Request.URL.Path
+		if userID, ok := c.Get("userID"); ok {
+			scope += fmt.Sprintf(" user:%v", userID)
+		}
+		record := &domain.IdempotencyRecord{
+			KeyHash:     hashParts(scope, key),
+			Fingerprint: hashParts(c.Request.Method, c.Request.URL.RequestURI(), string(body)),
+			ExpiresAt:   time.Now().Add(idempotencyLockTimeout),
+		}
+
+		existing, err := store.Begin(record)
+		if err != nil {
+			problem.Error(c, err)
+			return
+		}
+		if existing != nil {
+			switch {
+			case existing.Fingerprint != record.Fingerprint:
+				problem.Respond(c, http.StatusUnprocessableEntity, "idempotency_key_reused",
+					"Idempotency-Key has already been used for a different request")
+			case !existing.IsCompleted():
+				c.Header("Retry-After", "1")
+				problem.Respond(c, http.StatusConflict, "idempotency_key_in_use",
+					"a request with this Idempotency-Key is still being processed")
+			default:
+				replay(c, existing)
+			}
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		// サーバー側のエラーは再試行で成功しうるので、レスポンスを保存せずにキーを解放する
+		if recorder.Status() >= http.StatusInternalServerError {
+			if err := store.Release(record.KeyHash); err != nil {
+				log.Printf("[ERROR] failed to release idempotency key: %v", err)
+			}
+			return
+		}
+
+		record.StatusCode = recorder.Status()
+		record.Header = recorder.Header().Clone()
+		record.Body = recorder.body.Bytes()
+		record.ExpiresAt = time.Now().Add(ttl)
+		if err := store.Complete(record); err != nil {
+			log.Printf("[ERROR] failed to store idempotent response: %v", err)
+		}
+	}
+}
+
+// replay は保存したレスポンスをそのまま返す
+func replay(c *gin.Context, record *domain.IdempotencyRecord) {
+	for name, values := range record.Header {
+		c.Writer.Header()[name] = values
+	}
+	c.Header(IdempotentReplayedHeader, "true")
+	c.Writer.WriteHeader(record.StatusCode)
+	c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// hashParts は区切りを入れて連結した値の SHA-256 を返す
+func hashParts(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// responseRecorder はクライアントに書き込むレスポンスのボディを保存用に控えておく
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
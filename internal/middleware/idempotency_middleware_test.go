@@ -0,0 +1,113 @@
+package middleware_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/middleware"
+	"github.com/okamuuu/go-user-app/internal/problem"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestIdempotency(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		gin.SetMode(gin.TestMode)
+		repo := repository.NewIdempotencyRepository(db)
+
+		r := gin.New()
+		calls := 0
+		var status int
+		var inner *httptest.ResponseRecorder
+		send := func(key, body string, userID uint) *httptest.ResponseRecorder {
+			req, _ := http.NewRequest(http.MethodPost, "/signup", bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			if key != "" {
+				req.Header.Set(middleware.IdempotencyKeyHeader, key)
+			}
+			if userID != 0 {
+				req.Header.Set("X-User", "set")
+			}
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, req)
+			return w
+		}
+		// AuthMiddleware の代わりにユーザーを context に設定する
+		withUser := func(c *gin.Context) {
+			if c.GetHeader("X-User") != "" {
+				c.Set("userID", uint(7))
+			}
+			c.Next()
+		}
+		r.POST("/signup", withUser, middleware.Idempotency(repo, time.Hour), func(c *gin.Context) {
+			calls++
+			if c.GetHeader("X-Nested") != "" {
+				// 処理中に同じキーで再送されたことにする
+				inner = send(c.GetHeader(middleware.IdempotencyKeyHeader), "{}", 0)
+			}
+			if status != 0 {
+				c.JSON(status, gin.H{"error": "unavailable"})
+				return
+			}
+			c.Header("Location", "/users/1")
+			c.JSON(http.StatusCreated, gin.H{"call": calls})
+		})
+
+		// キーが無ければ毎回処理する
+		send("", "{}", 0)
+		send("", "{}", 0)
+		assert.Equal(t, 2, calls)
+
+		first := send("key-1", `{"name":"a"}`, 0)
+		assert.Equal(t, http.StatusCreated, first.Code)
+		assert.Empty(t, first.Header().Get(middleware.IdempotentReplayedHeader))
+
+		// 同じキー・同じ内容の再送は処理せずに保存したレスポンスを返す
+		retry := send("key-1", `{"name":"a"}`, 0)
+		assert.Equal(t, 3, calls)
+		assert.Equal(t, http.StatusCreated, retry.Code)
+		assert.Equal(t, first.Body.String(), retry.Body.String())
+		assert.Equal(t, "/users/1", retry.Header().Get("Location"))
+		assert.Equal(t, "true", retry.Header().Get(middleware.IdempotentReplayedHeader))
+
+		// 同じキーを別の内容に使うと 422
+		reused := send("key-1", `{"name":"b"}`, 0)
+		assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+		var p problem.Details
+		require.NoError(t, json.Unmarshal(reused.Body.Bytes(), &p))
+		assert.Equal(t, "idempotency_key_reused", p.Code)
+
+		// キーはユーザーごとに区別する
+		assert.Equal(t, http.StatusCreated, send("key-1", `{"name":"a"}`, 7).Code)
+		assert.Equal(t, 4, calls)
+
+		// サーバーのエラーは保存せず、同じキーで再試行できる
+		status = http.StatusServiceUnavailable
+		assert.Equal(t, http.StatusServiceUnavailable, send("key-2", "{}", 0).Code)
+		status = 0
+		assert.Equal(t, http.StatusCreated, send("key-2", "{}", 0).Code)
+		assert.Equal(t, 6, calls)
+
+		// 処理中に同じキーで再送されると 409
+		req, _ := http.NewRequest(http.MethodPost, "/signup", bytes.NewBufferString("{}"))
+		req.Header.Set(middleware.IdempotencyKeyHeader, "key-3")
+		req.Header.Set("X-Nested", "1")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+		require.NotNil(t, inner)
+		assert.Equal(t, http.StatusConflict, inner.Code)
+		assert.Equal(t, 7, calls)
+
+		// 保存期間を過ぎたレスポンスは削除される
+		deleted, err := repo.DeleteExpired(time.Now().Add(2 * time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, int64(4), deleted)
+	})
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/problem"
+)
+
+// CurrentRole は AuthMiddleware が context に保存したロールを返す
+func CurrentRole(c *gin.Context) domain.Role {
+	if role, ok := c.Get("role"); ok {
+		if r, ok := role.(domain.Role); ok {
+			return r
+		}
+	}
+	return domain.RoleMember
+}
+
+// RequireRole は指定したいずれかのロールを持つユーザーだけを通す
+// AuthMiddleware の後に適用すること
+func RequireRole(roles ...domain.Role) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		current := CurrentRole(c)
+		for _, role := range roles {
+			if current == role {
+				c.Next()
+				return
+			}
+		}
+		problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "insufficient role")
+	}
+}
+
+// RequirePermission は指定した権限を持つロールのユーザーだけを通す
+// AuthMiddleware の後に適用すること
+func RequirePermission(perm domain.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !CurrentRole(c).Can(perm) {
+			problem.Respond(c, http.StatusForbidden, problem.CodeForbidden, "permission denied")
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,47 @@
+package migrate
+
+import (
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// メールアドレスは大文字小文字を区別せずに一意とする。
+// アプリは正規化（前後の空白を除いた小文字）してから保存するが、既存の行を正規化した上で
+// 正規化した値に一意索引を張り、アプリ以外から書き込まれた場合も重複できないようにする
+
+func normalizeUserEmails(tx *gorm.DB) error {
+	// 正規化すると重複するアドレスは自動では統合できないので、一覧を返して止める
+	var duplicates []string
+	if err := tx.Raw(`SELECT LOWER(TRIM(email)) FROM users GROUP BY LOWER(TRIM(email)) HAVING COUNT(*) > 1`).
+		Scan(&duplicates).Error; err != nil {
+		return err
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("users have emails that differ only in case or surrounding spaces, merge them first: %s",
+			strings.Join(duplicates, ", "))
+	}
+
+	if err := tx.Exec(`UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email))`).Error; err != nil {
+		return err
+	}
+
+	switch tx.Dialector.Name() {
+	case "mysql":
+		// MySQL の式索引は式を括弧で囲む（8.0.13 以降）
+		return tx.Exec(`CREATE UNIQUE INDEX idx_users_email_normalized ON users ((LOWER(TRIM(email))))`).Error
+	default:
+		return tx.Exec(`CREATE UNIQUE INDEX idx_users_email_normalized ON users (LOWER(TRIM(email)))`).Error
+	}
+}
+
+func dropNormalizedUserEmailIndex(tx *gorm.DB) error {
+	// 正規化したアドレスは元に戻せないので、索引だけを削除する
+	switch tx.Dialector.Name() {
+	case "mysql":
+		return tx.Exec(`DROP INDEX idx_users_email_normalized ON users`).Error
+	default:
+		return tx.Exec(`DROP INDEX IF EXISTS idx_users_email_normalized`).Error
+	}
+}
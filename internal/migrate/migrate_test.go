@@ -0,0 +1,182 @@
+package migrate_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/migrate"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// runEmpty はマイグレーションを適用していない空の DB で、利用できるすべてのバックエンドについて fn を実行する
+func runEmpty(t *testing.T, fn func(t *testing.T, db *gorm.DB)) {
+	for _, backend := range testdb.Backends() {
+		t.Run(backend.Name, func(t *testing.T) {
+			fn(t, testdb.Open(t, backend))
+		})
+	}
+}
+
+func TestMigrator_UpDownStatus(t *testing.T) {
+	runEmpty(t, func(t *testing.T, db *gorm.DB) {
+		migrator := migrate.New(db, migrate.Migrations())
+
+		pending, err := migrator.Pending()
+		require.NoError(t, err)
+		assert.Len(t, pending, len(migrate.Migrations()))
+
+		applied, err := migrator.Up()
+		require.NoError(t, err)
+		assert.Len(t, applied, len(migrate.Migrations()))
+
+		pending, err = migrator.Pending()
+		require.NoError(t, err)
+		assert.Empty(t, pending)
+
+		statuses, err := migrator.Status()
+		require.NoError(t, err)
+		for _, status := range statuses {
+			assert.NotNil(t, status.AppliedAt, "migration %d", status.Version)
+		}
+
+		// 最後のマイグレーションだけが戻る
+		last, err := migrator.Down()
+		require.NoError(t, err)
+		assert.Equal(t, statuses[len(statuses)-1].Version, last.Version)
+		pending, err = migrator.Pending()
+		require.NoError(t, err)
+		require.Len(t, pending, 1)
+		assert.Equal(t, last.Version, pending[0].Version)
+
+		// すべて戻してから再度適用できる
+		for {
+			if _, err := migrator.Down(); err != nil {
+				assert.ErrorIs(t, err, migrate.ErrNoAppliedMigration)
+				break
+			}
+		}
+		assert.False(t, db.Migrator().HasTable("users"))
+
+		_, err = migrator.Up()
+		require.NoError(t, err)
+		assert.True(t, db.Migrator().HasTable("users"))
+	})
+}
+
+func TestMigrator_DuplicateVersionPanics(t *testing.T) {
+	assert.Panics(t, func() {
+		migrate.New(nil, []migrate.Migration{{Version: 1}, {Version: 1}})
+	})
+}
+
+// マイグレーション後のスキーマが repository のモデルと一致していること
+func TestMigrations_MatchModels(t *testing.T) {
+	runEmpty(t, func(t *testing.T, db *gorm.DB) {
+		_, err := migrate.New(db, migrate.Migrations()).Up()
+		require.NoError(t, err)
+
+		models := []interface{}{
+			&repository.User{},
+			&repository.RefreshToken{},
+			&repository.RevokedToken{},
+			&repository.UserTokenRevocation{},
+			&repository.PasswordResetToken{},
+			&repository.EmailVerificationToken{},
+			&repository.MFACredential{},
+			&repository.MFARecoveryCode{},
+			&repository.AuditLog{},
+		}
+		for _, model := range models {
+			stmt := &gorm.Statement{DB: db}
+			require.NoError(t, stmt.Parse(model))
+			table := stmt.Schema.Table
+
+			require.True(t, db.Migrator().HasTable(model), "table %s", table)
+			for _, column := range stmt.Schema.DBNames {
+				assert.True(t, db.Migrator().HasColumn(model, column), "column %s.%s", table, column)
+			}
+			for _, index := range stmt.Schema.ParseIndexes() {
+				assert.True(t, db.Migrator().HasIndex(model, index.Name), "index %s", index.Name)
+			}
+		}
+	})
+}
+
+func TestMigrations_NormalizeUserEmails(t *testing.T) {
+	insertUser := func(db *gorm.DB, email string) error {
+		now := time.Now()
+		return db.Exec(`INSERT INTO users (name, email, password, role, created_at, updated_at) VALUES (?, ?, ?, ?, ?, ?)`,
+			"user", email, "hash", "member", now, now).Error
+	}
+	// 正規化するマイグレーションの直前までを適用する
+	previous := migrate.Migrations()[:11]
+	require.Equal(t, "normalize_users_email", migrate.Migrations()[len(previous)].Name)
+
+	runEmpty(t, func(t *testing.T, db *gorm.DB) {
+		_, err := migrate.New(db, previous).Up()
+		require.NoError(t, err)
+		require.NoError(t, insertUser(db, " Alice@Example.COM"))
+		require.NoError(t, insertUser(db, "bob@example.com"))
+
+		_, err = migrate.New(db, migrate.Migrations()).Up()
+		require.NoError(t, err)
+
+		var emails []string
+		require.NoError(t, db.Raw(`SELECT email FROM users ORDER BY id`).Scan(&emails).Error)
+		assert.Equal(t, []string{"alice@example.com", "bob@example.com"}, emails)
+
+		// アプリを通さずに書き込んでも、大文字小文字違いのアドレスは重複できない
+		assert.Error(t, insertUser(db, "BOB@example.com"))
+		assert.NoError(t, insertUser(db, "carol@example.com"))
+	})
+
+	runEmpty(t, func(t *testing.T, db *gorm.DB) {
+		if db.Dialector.Name() == "mysql" {
+			t.Skip("MySQL's default collation already rejects emails that differ only in case")
+		}
+		_, err := migrate.New(db, previous).Up()
+		require.NoError(t, err)
+		require.NoError(t, insertUser(db, "alice@example.com"))
+		require.NoError(t, insertUser(db, "ALICE@example.com"))
+
+		_, err = migrate.New(db, migrate.Migrations()).Up()
+		assert.ErrorContains(t, err, "alice@example.com")
+
+		// 失敗したマイグレーションは何も変更しない
+		var emails []string
+		require.NoError(t, db.Raw(`SELECT email FROM users ORDER BY id`).Scan(&emails).Error)
+		assert.Equal(t, []string{"alice@example.com", "ALICE@example.com"}, emails)
+	})
+}
+
+// FTS5 なしのビルドでマイグレーションを適用した DB でも、後から検索の索引を作れること
+func TestEnsureUserSearchIndex(t *testing.T) {
+	runEmpty(t, func(t *testing.T, db *gorm.DB) {
+		if db.Dialector.Name() != "sqlite" {
+			t.Skip("only SQLite creates the search index outside of migrations")
+		}
+		_, err := migrate.New(db, migrate.Migrations()).Up()
+		require.NoError(t, err)
+
+		// 索引が作られなかった状態にする
+		for _, sql := range []string{
+			`DROP TRIGGER IF EXISTS users_fts_au`,
+			`DROP TRIGGER IF EXISTS users_fts_ad`,
+			`DROP TRIGGER IF EXISTS users_fts_ai`,
+			`DROP TABLE IF EXISTS users_fts`,
+		} {
+			require.NoError(t, db.Exec(sql).Error)
+		}
+
+		var fts5 bool
+		require.NoError(t, db.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&fts5).Error)
+
+		require.NoError(t, migrate.EnsureUserSearchIndex(db))
+		require.NoError(t, migrate.EnsureUserSearchIndex(db))
+		assert.Equal(t, fts5, db.Migrator().HasTable("users_fts"))
+	})
+}
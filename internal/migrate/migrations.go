@@ -0,0 +1,310 @@
+package migrate
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migrations はアプリケーションのスキーマ変更の一覧。
+// 適用済みのマイグレーションは書き換えず、変更は必ず新しいバージョンとして末尾に追加する。
+// 各マイグレーションはその時点のテーブル定義を構造体として持ち、repository のモデルには依存しない。
+func Migrations() []Migration {
+	return []Migration{
+		{
+			Version: 1,
+			Name:    "create_users",
+			Up: func(tx *gorm.DB) error {
+				type User struct {
+					ID        uint `gorm:"primaryKey;autoIncrement"`
+					Name      string
+					Email     string `gorm:"uniqueIndex"`
+					Password  string
+					CreatedAt time.Time
+					UpdatedAt time.Time
+				}
+				return tx.Migrator().CreateTable(&User{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("users")
+			},
+		},
+		{
+			Version: 2,
+			Name:    "create_refresh_tokens",
+			Up: func(tx *gorm.DB) error {
+				type RefreshToken struct {
+					ID           uint   `gorm:"primaryKey;autoIncrement"`
+					UserID       uint   `gorm:"index"`
+					FamilyID     string `gorm:"index"`
+					TokenHash    string `gorm:"uniqueIndex"`
+					ExpiresAt    time.Time
+					RevokedAt    *time.Time
+					ReplacedByID *uint
+					CreatedAt    time.Time
+				}
+				return tx.Migrator().CreateTable(&RefreshToken{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("refresh_tokens")
+			},
+		},
+		{
+			Version: 3,
+			Name:    "create_token_revocations",
+			Up: func(tx *gorm.DB) error {
+				type RevokedToken struct {
+					ID        uint   `gorm:"primaryKey;autoIncrement"`
+					JTI       string `gorm:"uniqueIndex"`
+					UserID    uint   `gorm:"index"`
+					ExpiresAt time.Time
+					CreatedAt time.Time
+				}
+				type UserTokenRevocation struct {
+					UserID        uint `gorm:"primaryKey"`
+					RevokedBefore time.Time
+					UpdatedAt     time.Time
+				}
+				return tx.Migrator().CreateTable(&RevokedToken{}, &UserTokenRevocation{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("user_token_revocations", "revoked_tokens")
+			},
+		},
+		{
+			Version: 4,
+			Name:    "create_password_reset_tokens",
+			Up: func(tx *gorm.DB) error {
+				type PasswordResetToken struct {
+					ID        uint   `gorm:"primaryKey;autoIncrement"`
+					UserID    uint   `gorm:"index"`
+					TokenHash string `gorm:"uniqueIndex"`
+					ExpiresAt time.Time
+					UsedAt    *time.Time
+					CreatedAt time.Time
+				}
+				return tx.Migrator().CreateTable(&PasswordResetToken{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("password_reset_tokens")
+			},
+		},
+		{
+			Version: 5,
+			Name:    "add_email_verification",
+			Up: func(tx *gorm.DB) error {
+				type User struct {
+					EmailVerifiedAt *time.Time
+				}
+				type EmailVerificationToken struct {
+					ID        uint `gorm:"primaryKey;autoIncrement"`
+					UserID    uint `gorm:"index"`
+					Email     string
+					TokenHash string `gorm:"uniqueIndex"`
+					ExpiresAt time.Time
+					UsedAt    *time.Time
+					CreatedAt time.Time
+				}
+				if err := tx.Migrator().AddColumn(&User{}, "EmailVerifiedAt"); err != nil {
+					return err
+				}
+				return tx.Migrator().CreateTable(&EmailVerificationToken{})
+			},
+			Down: func(tx *gorm.DB) error {
+				type User struct {
+					EmailVerifiedAt *time.Time
+				}
+				if err := tx.Migrator().DropTable("email_verification_tokens"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&User{}, "EmailVerifiedAt")
+			},
+		},
+		{
+			Version: 6,
+			Name:    "create_mfa",
+			Up: func(tx *gorm.DB) error {
+				type MFACredential struct {
+					UserID       uint `gorm:"primaryKey"`
+					Secret       string
+					EnabledAt    *time.Time
+					LastUsedStep int64
+					CreatedAt    time.Time
+					UpdatedAt    time.Time
+				}
+				type MFARecoveryCode struct {
+					ID       uint `gorm:"primaryKey;autoIncrement"`
+					UserID   uint `gorm:"index"`
+					CodeHash string
+					UsedAt   *time.Time
+				}
+				return tx.Migrator().CreateTable(&MFACredential{}, &MFARecoveryCode{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("mfa_recovery_codes", "mfa_credentials")
+			},
+		},
+		{
+			Version: 7,
+			Name:    "add_users_role",
+			Up: func(tx *gorm.DB) error {
+				type User struct {
+					Role string `gorm:"not null;default:member"`
+				}
+				return tx.Migrator().AddColumn(&User{}, "Role")
+			},
+			Down: func(tx *gorm.DB) error {
+				type User struct {
+					Role string
+				}
+				return tx.Migrator().DropColumn(&User{}, "Role")
+			},
+		},
+		{
+			Version: 8,
+			Name:    "add_users_deleted_at",
+			Up: func(tx *gorm.DB) error {
+				type User struct {
+					DeletedAt gorm.DeletedAt `gorm:"index"`
+				}
+				if err := tx.Migrator().AddColumn(&User{}, "DeletedAt"); err != nil {
+					return err
+				}
+				return tx.Migrator().CreateIndex(&User{}, "DeletedAt")
+			},
+			Down: func(tx *gorm.DB) error {
+				type User struct {
+					DeletedAt gorm.DeletedAt `gorm:"index"`
+				}
+				if err := tx.Migrator().DropIndex(&User{}, "DeletedAt"); err != nil {
+					return err
+				}
+				return tx.Migrator().DropColumn(&User{}, "DeletedAt")
+			},
+		},
+		{
+			Version: 9,
+			Name:    "create_audit_logs",
+			Up: func(tx *gorm.DB) error {
+				type AuditLog struct {
+					ID         uint   `gorm:"primaryKey;autoIncrement"`
+					ActorID    *uint  `gorm:"index"`
+					Action     string `gorm:"index;not null"`
+					TargetType string
+					TargetID   *uint `gorm:"index"`
+					IP         string
+					UserAgent  string    `gorm:"type:text"`
+					Before     string    `gorm:"type:text"`
+					After      string    `gorm:"type:text"`
+					CreatedAt  time.Time `gorm:"index"`
+				}
+				return tx.Migrator().CreateTable(&AuditLog{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("audit_logs")
+			},
+		},
+		{
+			Version: 10,
+			Name:    "add_users_created_at_id_index",
+			Up: func(tx *gorm.DB) error {
+				type User struct {
+					ID        uint      `gorm:"primaryKey;index:idx_users_created_at_id,priority:2"`
+					CreatedAt time.Time `gorm:"index:idx_users_created_at_id,priority:1"`
+				}
+				return tx.Migrator().CreateIndex(&User{}, "idx_users_created_at_id")
+			},
+			Down: func(tx *gorm.DB) error {
+				type User struct {
+					ID        uint      `gorm:"primaryKey;index:idx_users_created_at_id,priority:2"`
+					CreatedAt time.Time `gorm:"index:idx_users_created_at_id,priority:1"`
+				}
+				return tx.Migrator().DropIndex(&User{}, "idx_users_created_at_id")
+			},
+		},
+		{
+			Version: 11,
+			Name:    "create_users_search_index",
+			Up:      createUserSearchIndex,
+			Down:    dropUserSearchIndex,
+		},
+		{
+			Version: 12,
+			Name:    "normalize_users_email",
+			Up:      normalizeUserEmails,
+			Down:    dropNormalizedUserEmailIndex,
+		},
+		{
+			Version: 13,
+			Name:    "add_users_version",
+			Up: func(tx *gorm.DB) error {
+				type User struct {
+					Version uint `gorm:"not null;default:1"`
+				}
+				return tx.Migrator().AddColumn(&User{}, "Version")
+			},
+			Down: func(tx *gorm.DB) error {
+				// SQLite の Migrator().DropColumn はテーブルを作り直し、索引や検索のトリガーが消えるため SQL で削除する
+				return tx.Exec(`ALTER TABLE users DROP COLUMN version`).Error
+			},
+		},
+		{
+			Version: 14,
+			Name:    "create_idempotency_keys",
+			Up: func(tx *gorm.DB) error {
+				type IdempotencyKey struct {
+					KeyHash     string `gorm:"primaryKey;size:64"`
+					Fingerprint string `gorm:"size:64"`
+					StatusCode  int
+					Header      string `gorm:"type:text"`
+					Body        []byte
+					ExpiresAt   time.Time `gorm:"index"`
+					CreatedAt   time.Time
+				}
+				return tx.Migrator().CreateTable(&IdempotencyKey{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("idempotency_keys")
+			},
+		},
+		{
+			Version: 15,
+			Name:    "create_erasure_records",
+			Up: func(tx *gorm.DB) error {
+				type ErasureRecord struct {
+					ID          uint `gorm:"primaryKey;autoIncrement"`
+					UserID      uint `gorm:"index"`
+					RequestedBy *uint
+					Reason      string    `gorm:"type:text"`
+					Deleted     string    `gorm:"type:text"`
+					Anonymized  string    `gorm:"type:text"`
+					ErasedAt    time.Time `gorm:"index"`
+				}
+				return tx.Migrator().CreateTable(&ErasureRecord{})
+			},
+			Down: func(tx *gorm.DB) error {
+				return tx.Migrator().DropTable("erasure_records")
+			},
+		},
+		{
+			Version: 16,
+			Name:    "add_mfa_credentials_lockout",
+			Up: func(tx *gorm.DB) error {
+				type MFACredential struct {
+					FailedAttempts int `gorm:"not null;default:0"`
+					LockedUntil    *time.Time
+				}
+				if err := tx.Migrator().AddColumn(&MFACredential{}, "FailedAttempts"); err != nil {
+					return err
+				}
+				return tx.Migrator().AddColumn(&MFACredential{}, "LockedUntil")
+			},
+			Down: func(tx *gorm.DB) error {
+				if err := tx.Exec(`ALTER TABLE mfa_credentials DROP COLUMN locked_until`).Error; err != nil {
+					return err
+				}
+				return tx.Exec(`ALTER TABLE mfa_credentials DROP COLUMN failed_attempts`).Error
+			},
+		},
+	}
+}
@@ -0,0 +1,102 @@
+package migrate
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// ユーザー検索の索引は DB ごとに仕組みが異なるため SQL で作る。
+// SQLite は FTS5 の仮想テーブルをトリガーで users と同期させ、PostgreSQL は tsvector の生成列に GIN 索引を張る。
+// それ以外（MySQL や FTS5 なしでビルドした SQLite）では索引を作らず、リポジトリが LIKE で検索する
+
+func createUserSearchIndex(tx *gorm.DB) error {
+	switch tx.Dialector.Name() {
+	case "sqlite":
+		return ensureSQLiteUserSearchIndex(tx)
+	case "postgres":
+		// メールアドレスは区切り文字を空白にして、ローカル部・ドメインの各単語で引けるようにする
+		return execAll(tx,
+			`ALTER TABLE users ADD COLUMN search_vector tsvector GENERATED ALWAYS AS (
+				setweight(to_tsvector('simple', coalesce(name, '')), 'A') ||
+				setweight(to_tsvector('simple', translate(coalesce(email, ''), '@.+-_', '     ')), 'B')
+			) STORED`,
+			`CREATE INDEX idx_users_search_vector ON users USING GIN (search_vector)`,
+		)
+	default:
+		return nil
+	}
+}
+
+// EnsureUserSearchIndex は索引を作るマイグレーションが適用済みで、SQLite の FTS5 の索引が無ければ作る（何度呼んでもよい）。
+// FTS5 なしでビルドした状態でマイグレーションを適用した DB も、sqlite_fts5 付きのビルドで起動すれば索引が作られる
+func EnsureUserSearchIndex(db *gorm.DB) error {
+	if db.Dialector.Name() != "sqlite" || !db.Migrator().HasTable(&SchemaMigration{}) {
+		return nil
+	}
+	var applied int64
+	if err := db.Model(&SchemaMigration{}).Where("name = ?", "create_users_search_index").Count(&applied).Error; err != nil {
+		return err
+	}
+	if applied == 0 {
+		return nil
+	}
+	return db.Transaction(ensureSQLiteUserSearchIndex)
+}
+
+func ensureSQLiteUserSearchIndex(tx *gorm.DB) error {
+	var enabled bool
+	if err := tx.Raw("SELECT sqlite_compileoption_used('ENABLE_FTS5')").Scan(&enabled).Error; err != nil {
+		return err
+	}
+	if !enabled {
+		log.Printf("[WARN] SQLite was built without FTS5 (build with -tags sqlite_fts5): user search falls back to LIKE")
+		return nil
+	}
+	if tx.Migrator().HasTable("users_fts") {
+		return nil
+	}
+	return execAll(tx,
+		`CREATE VIRTUAL TABLE users_fts USING fts5(name, email, content='users', content_rowid='id')`,
+		`CREATE TRIGGER IF NOT EXISTS users_fts_ai AFTER INSERT ON users BEGIN
+			INSERT INTO users_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS users_fts_ad AFTER DELETE ON users BEGIN
+			INSERT INTO users_fts(users_fts, rowid, name, email) VALUES ('delete', old.id, old.name, old.email);
+		END`,
+		`CREATE TRIGGER IF NOT EXISTS users_fts_au AFTER UPDATE OF name, email ON users BEGIN
+			INSERT INTO users_fts(users_fts, rowid, name, email) VALUES ('delete', old.id, old.name, old.email);
+			INSERT INTO users_fts(rowid, name, email) VALUES (new.id, new.name, new.email);
+		END`,
+		// 既存のユーザーを索引に載せる
+		`INSERT INTO users_fts(users_fts) VALUES ('rebuild')`,
+	)
+}
+
+func dropUserSearchIndex(tx *gorm.DB) error {
+	switch tx.Dialector.Name() {
+	case "sqlite":
+		return execAll(tx,
+			`DROP TRIGGER IF EXISTS users_fts_au`,
+			`DROP TRIGGER IF EXISTS users_fts_ad`,
+			`DROP TRIGGER IF EXISTS users_fts_ai`,
+			`DROP TABLE IF EXISTS users_fts`,
+		)
+	case "postgres":
+		return execAll(tx,
+			`DROP INDEX IF EXISTS idx_users_search_vector`,
+			`ALTER TABLE users DROP COLUMN IF EXISTS search_vector`,
+		)
+	default:
+		return nil
+	}
+}
+
+func execAll(tx *gorm.DB, statements ...string) error {
+	for _, sql := range statements {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package notify
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Message はユーザーに送る通知
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Notifier は通知の送信手段を抽象化したインターフェース
+// 本番ではメール送信サービス等の実装に差し替える
+type Notifier interface {
+	Send(msg Message) error
+}
+
+// LogNotifier は通知内容をログに出力するだけの実装（ローカル開発用）
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Send(msg Message) error {
+	log.Printf("[NOTIFY] to=%s subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	return nil
+}
+
+// FileNotifier は通知内容をファイルに追記する実装（ローカル開発用）
+type FileNotifier struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFileNotifier(path string) *FileNotifier {
+	return &FileNotifier{path: path}
+}
+
+func (n *FileNotifier) Send(msg Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = fmt.Fprintf(f, "Date: %s\nTo: %s\nSubject: %s\n\n%s\n\n", time.Now().Format(time.RFC1123Z), msg.To, msg.Subject, msg.Body)
+	return err
+}
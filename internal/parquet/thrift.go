@@ -0,0 +1,105 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+)
+
+// Thrift Compact Protocol の型
+const (
+	thriftI32    = 5
+	thriftI64    = 6
+	thriftBinary = 8
+	thriftList   = 9
+	thriftStruct = 12
+)
+
+// thriftEncoder は Parquet のメタデータ（ページヘッダー・フッター）を Thrift Compact Protocol で書き出す。
+// 必要な型（i32, i64, binary, list, struct）だけを実装している
+type thriftEncoder struct {
+	buf bytes.Buffer
+	// フィールド ID は直前のフィールドとの差分で書くので、入れ子の構造体ごとに直前の ID を覚えておく
+	lastID  int16
+	parents []int16
+}
+
+func (e *thriftEncoder) fieldHeader(id int16, typ byte) {
+	if delta := id - e.lastID; delta > 0 && delta <= 15 {
+		e.buf.WriteByte(byte(delta)<<4 | typ)
+	} else {
+		e.buf.WriteByte(typ)
+		e.varint(int64(id))
+	}
+	e.lastID = id
+}
+
+func (e *thriftEncoder) uvarint(v uint64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf.Write(b[:binary.PutUvarint(b[:], v)])
+}
+
+// varint は zigzag 符号化した整数を書く
+func (e *thriftEncoder) varint(v int64) {
+	var b [binary.MaxVarintLen64]byte
+	e.buf.Write(b[:binary.PutVarint(b[:], v)])
+}
+
+func (e *thriftEncoder) i32(id int16, v int32) {
+	e.fieldHeader(id, thriftI32)
+	e.varint(int64(v))
+}
+
+func (e *thriftEncoder) i64(id int16, v int64) {
+	e.fieldHeader(id, thriftI64)
+	e.varint(v)
+}
+
+func (e *thriftEncoder) string(id int16, s string) {
+	e.fieldHeader(id, thriftBinary)
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// beginStruct は構造体のフィールドを始める。フィールドを書き終えたら endStruct を呼ぶ
+func (e *thriftEncoder) beginStruct(id int16) {
+	e.fieldHeader(id, thriftStruct)
+	e.beginElement()
+}
+
+// beginElement はリストの要素の構造体を始める（リストの要素にはフィールドヘッダーを付けない）
+func (e *thriftEncoder) beginElement() {
+	e.parents = append(e.parents, e.lastID)
+	e.lastID = 0
+}
+
+func (e *thriftEncoder) endStruct() {
+	e.buf.WriteByte(0) // STOP
+	e.lastID = e.parents[len(e.parents)-1]
+	e.parents = e.parents[:len(e.parents)-1]
+}
+
+// list はリストのフィールドを始める。続けて size 個の要素を書く
+func (e *thriftEncoder) list(id int16, elemType byte, size int) {
+	e.fieldHeader(id, thriftList)
+	if size < 15 {
+		e.buf.WriteByte(byte(size)<<4 | elemType)
+		return
+	}
+	e.buf.WriteByte(0xf0 | elemType)
+	e.uvarint(uint64(size))
+}
+
+func (e *thriftEncoder) i32Element(v int32) {
+	e.varint(int64(v))
+}
+
+func (e *thriftEncoder) stringElement(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// bytes は書き出したメタデータを返す。トップレベルの構造体の STOP も付ける
+func (e *thriftEncoder) bytes() []byte {
+	e.buf.WriteByte(0)
+	return e.buf.Bytes()
+}
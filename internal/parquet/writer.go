@@ -0,0 +1,315 @@
+// Package parquet は表形式のデータを Apache Parquet のファイルとして書き出す。
+// 分析用のエクスポートに必要な範囲だけを実装した最小限のライターで、
+// すべての列を null を許す（OPTIONAL）フラットな列とし、圧縮せず PLAIN エンコーディングで書く。
+// 行は RowGroupSize 行ごとに行グループとして書き出すので、メモリに保持するのは1つの行グループ分だけになる
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// Kind は列の値の種類
+type Kind int
+
+const (
+	// String は UTF-8 の文字列（BYTE_ARRAY / UTF8）。値は string
+	String Kind = iota
+	// Int64 は符号付き64ビット整数（INT64）。値は int64
+	Int64
+	// Timestamp は UTC のミリ秒単位の日時（INT64 / TIMESTAMP_MILLIS）。値は time.Time
+	Timestamp
+)
+
+// Column は列の定義
+type Column struct {
+	Name string
+	Kind Kind
+}
+
+// RowGroupSize は1つの行グループにまとめる行数
+const RowGroupSize = 10000
+
+const magic = "PAR1"
+
+// Parquet の型・エンコーディングなどの定数（parquet.thrift の値）
+const (
+	typeInt64     = 2
+	typeByteArray = 6
+
+	repetitionOptional = 1
+
+	convertedUTF8            = 0
+	convertedTimestampMillis = 9
+
+	encodingPlain = 0
+	encodingRLE   = 3
+
+	codecUncompressed = 0
+
+	pageTypeData = 0
+)
+
+// ErrClosed は Close した後に書き込もうとした場合のエラー
+var ErrClosed = errors.New("parquet: writer is closed")
+
+// Writer は行を Parquet のファイルとして書き出す。Close でフッターを書くまではファイルとして読めない
+type Writer struct {
+	w         io.Writer
+	offset    int64 // ファイルの先頭から書き出したバイト数
+	columns   []Column
+	buffers   []*columnBuffer
+	rows      int // バッファにある行数
+	numRows   int64
+	rowGroups []rowGroup
+	closed    bool
+	err       error
+}
+
+// columnBuffer は行グループの1列分の値。null かどうか（definition level）と null でない値を分けて持つ
+type columnBuffer struct {
+	defined []bool
+	values  bytes.Buffer
+}
+
+// columnChunk は書き出した列のチャンクのメタデータ
+type columnChunk struct {
+	offset int64
+	size   int64
+	values int64
+}
+
+type rowGroup struct {
+	columns []columnChunk
+	size    int64
+	rows    int64
+}
+
+// NewWriter は columns の列を持つ Parquet ファイルを w に書き出すライターを返す
+func NewWriter(w io.Writer, columns []Column) *Writer {
+	buffers := make([]*columnBuffer, len(columns))
+	for i := range buffers {
+		buffers[i] = &columnBuffer{}
+	}
+	return &Writer{w: w, columns: columns, buffers: buffers}
+}
+
+// Write は1行を書き込む。values は列の順で、null の列は nil にする
+func (w *Writer) Write(values []interface{}) error {
+	if w.closed {
+		return ErrClosed
+	}
+	if w.err != nil {
+		return w.err
+	}
+	if len(values) != len(w.columns) {
+		return fmt.Errorf("parquet: got %d values for %d columns", len(values), len(w.columns))
+	}
+	// 型が合わない値があれば、どの列にも書き込まない
+	for i, v := range values {
+		if v != nil && !w.columns[i].Kind.accepts(v) {
+			return fmt.Errorf("parquet: invalid value for column %s: %T", w.columns[i].Name, v)
+		}
+	}
+
+	for i, v := range values {
+		buf := w.buffers[i]
+		buf.defined = append(buf.defined, v != nil)
+		switch v := v.(type) {
+		case nil:
+		case string:
+			binary.Write(&buf.values, binary.LittleEndian, uint32(len(v)))
+			buf.values.WriteString(v)
+		case int64:
+			binary.Write(&buf.values, binary.LittleEndian, v)
+		case time.Time:
+			binary.Write(&buf.values, binary.LittleEndian, v.UnixMilli())
+		}
+	}
+	w.rows++
+	if w.rows >= RowGroupSize {
+		return w.flush()
+	}
+	return nil
+}
+
+func (k Kind) accepts(v interface{}) bool {
+	switch v.(type) {
+	case string:
+		return k == String
+	case int64:
+		return k == Int64
+	case time.Time:
+		return k == Timestamp
+	}
+	return false
+}
+
+// physicalType は列を保存する Parquet の型を返す
+func (k Kind) physicalType() int32 {
+	if k == String {
+		return typeByteArray
+	}
+	return typeInt64
+}
+
+// Close はバッファにある行とフッターを書き出す。下位の io.Writer は閉じない
+func (w *Writer) Close() error {
+	if w.closed {
+		return nil
+	}
+	if w.err == nil && w.rows > 0 {
+		w.flush()
+	}
+	w.closed = true
+	if w.err != nil {
+		return w.err
+	}
+
+	if w.offset == 0 {
+		w.write([]byte(magic))
+	}
+	footer := w.footer()
+	w.write(footer)
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(footer)))
+	w.write(size[:])
+	w.write([]byte(magic))
+	return w.err
+}
+
+// flush はバッファにある行を1つの行グループとして書き出す
+func (w *Writer) flush() error {
+	if w.offset == 0 {
+		w.write([]byte(magic))
+	}
+
+	group := rowGroup{rows: int64(w.rows)}
+	for _, buf := range w.buffers {
+		chunk := columnChunk{offset: w.offset, values: int64(len(buf.defined))}
+
+		// データページ: definition level（RLE、長さを前に付ける）と null でない値（PLAIN）
+		levels := encodeLevels(buf.defined)
+		var page bytes.Buffer
+		binary.Write(&page, binary.LittleEndian, uint32(len(levels)))
+		page.Write(levels)
+		page.Write(buf.values.Bytes())
+
+		var header thriftEncoder
+		header.i32(1, pageTypeData)
+		header.i32(2, int32(page.Len()))
+		header.i32(3, int32(page.Len()))
+		header.beginStruct(5)
+		header.i32(1, int32(len(buf.defined)))
+		header.i32(2, encodingPlain)
+		header.i32(3, encodingRLE)
+		header.i32(4, encodingRLE)
+		header.endStruct()
+
+		w.write(header.bytes())
+		w.write(page.Bytes())
+		chunk.size = w.offset - chunk.offset
+		group.size += chunk.size
+		group.columns = append(group.columns, chunk)
+
+		buf.defined = buf.defined[:0]
+		buf.values.Reset()
+	}
+
+	w.rowGroups = append(w.rowGroups, group)
+	w.numRows += group.rows
+	w.rows = 0
+	return w.err
+}
+
+// encodeLevels は definition level（null なら 0、値があれば 1）を RLE で符号化する。
+// 同じ値が続く区間ごとに「長さ << 1」と値（ビット幅 1 なので 1 バイト）を書く
+func encodeLevels(defined []bool) []byte {
+	var buf []byte
+	for i := 0; i < len(defined); {
+		j := i
+		for j < len(defined) && defined[j] == defined[i] {
+			j++
+		}
+		buf = binary.AppendUvarint(buf, uint64(j-i)<<1)
+		if defined[i] {
+			buf = append(buf, 1)
+		} else {
+			buf = append(buf, 0)
+		}
+		i = j
+	}
+	return buf
+}
+
+// footer はファイルのメタデータ（FileMetaData）を返す
+func (w *Writer) footer() []byte {
+	var e thriftEncoder
+	e.i32(1, 1) // version
+
+	// スキーマは列を子に持つルート要素と、各列の要素からなる
+	e.list(2, thriftStruct, len(w.columns)+1)
+	e.beginElement()
+	e.string(4, "schema")
+	e.i32(5, int32(len(w.columns)))
+	e.endStruct()
+	for _, c := range w.columns {
+		e.beginElement()
+		e.i32(1, c.Kind.physicalType())
+		e.i32(3, repetitionOptional)
+		e.string(4, c.Name)
+		switch c.Kind {
+		case String:
+			e.i32(6, convertedUTF8)
+		case Timestamp:
+			e.i32(6, convertedTimestampMillis)
+		}
+		e.endStruct()
+	}
+
+	e.i64(3, w.numRows)
+
+	e.list(4, thriftStruct, len(w.rowGroups))
+	for _, g := range w.rowGroups {
+		e.beginElement()
+		e.list(1, thriftStruct, len(g.columns))
+		for i, chunk := range g.columns {
+			column := w.columns[i]
+			e.beginElement()
+			e.i64(2, chunk.offset) // file_offset
+			e.beginStruct(3)       // meta_data
+			e.i32(1, column.Kind.physicalType())
+			e.list(2, thriftI32, 2)
+			e.i32Element(encodingPlain)
+			e.i32Element(encodingRLE)
+			e.list(3, thriftBinary, 1)
+			e.stringElement(column.Name)
+			e.i32(4, codecUncompressed)
+			e.i64(5, chunk.values)
+			e.i64(6, chunk.size)
+			e.i64(7, chunk.size)
+			e.i64(9, chunk.offset) // data_page_offset
+			e.endStruct()
+			e.endStruct()
+		}
+		e.i64(2, g.size)
+		e.i64(3, g.rows)
+		e.endStruct()
+	}
+
+	e.string(6, "go-user-app")
+	return e.bytes()
+}
+
+func (w *Writer) write(b []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(b)
+	w.offset += int64(n)
+	w.err = err
+}
@@ -0,0 +1,186 @@
+package parquet
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// thriftDecoder はテストで書き出したメタデータを読むための Thrift Compact Protocol のデコーダー。
+// 構造体はフィールド ID ごとの値の map、リストは []interface{} として読む
+type thriftDecoder struct {
+	data []byte
+	pos  int
+}
+
+func (d *thriftDecoder) byte() byte {
+	b := d.data[d.pos]
+	d.pos++
+	return b
+}
+
+func (d *thriftDecoder) uvarint() uint64 {
+	v, n := binary.Uvarint(d.data[d.pos:])
+	d.pos += n
+	return v
+}
+
+func (d *thriftDecoder) varint() int64 {
+	v, n := binary.Varint(d.data[d.pos:])
+	d.pos += n
+	return v
+}
+
+func (d *thriftDecoder) value(typ byte) interface{} {
+	switch typ {
+	case thriftI32, thriftI64:
+		return d.varint()
+	case thriftBinary:
+		n := int(d.uvarint())
+		s := string(d.data[d.pos : d.pos+n])
+		d.pos += n
+		return s
+	case thriftList:
+		header := d.byte()
+		size := int(header >> 4)
+		if size == 15 {
+			size = int(d.uvarint())
+		}
+		list := make([]interface{}, size)
+		for i := range list {
+			list[i] = d.value(header & 0x0f)
+		}
+		return list
+	case thriftStruct:
+		fields := map[int16]interface{}{}
+		var id int16
+		for {
+			header := d.byte()
+			if header == 0 {
+				return fields
+			}
+			if delta := int16(header >> 4); delta != 0 {
+				id += delta
+			} else {
+				id = int16(d.varint())
+			}
+			fields[id] = d.value(header & 0x0f)
+		}
+	}
+	panic(fmt.Sprintf("unsupported thrift type %d", typ))
+}
+
+// readFile は Parquet ファイルを読み、列の名前ごとの値（null は nil）を返す
+func readFile(t *testing.T, data []byte) (map[int16]interface{}, map[string][]interface{}) {
+	t.Helper()
+	require.Equal(t, magic, string(data[:4]))
+	require.Equal(t, magic, string(data[len(data)-4:]))
+	size := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	footerStart := len(data) - 8 - size
+	footer := (&thriftDecoder{data: data[footerStart : len(data)-8]}).value(thriftStruct).(map[int16]interface{})
+
+	schema := footer[2].([]interface{})
+	columns := map[string][]interface{}{}
+	for _, g := range footer[4].([]interface{}) {
+		chunks := g.(map[int16]interface{})[1].([]interface{})
+		for i, c := range chunks {
+			element := schema[i+1].(map[int16]interface{})
+			meta := c.(map[int16]interface{})[3].(map[int16]interface{})
+			d := &thriftDecoder{data: data, pos: int(meta[9].(int64))}
+			header := d.value(thriftStruct).(map[int16]interface{})
+			page := data[d.pos : d.pos+int(header[3].(int64))]
+			numValues := int(header[5].(map[int16]interface{})[1].(int64))
+
+			// definition level を展開する
+			levelsSize := int(binary.LittleEndian.Uint32(page))
+			levels := &thriftDecoder{data: page[4 : 4+levelsSize]}
+			var defined []bool
+			for levels.pos < len(levels.data) {
+				run := int(levels.uvarint() >> 1)
+				v := levels.byte() == 1
+				for j := 0; j < run; j++ {
+					defined = append(defined, v)
+				}
+			}
+			require.Len(t, defined, numValues)
+
+			values := page[4+levelsSize:]
+			name := element[4].(string)
+			for _, ok := range defined {
+				if !ok {
+					columns[name] = append(columns[name], nil)
+					continue
+				}
+				if element[1].(int64) == typeByteArray {
+					n := int(binary.LittleEndian.Uint32(values))
+					columns[name] = append(columns[name], string(values[4:4+n]))
+					values = values[4+n:]
+					continue
+				}
+				columns[name] = append(columns[name], int64(binary.LittleEndian.Uint64(values)))
+				values = values[8:]
+			}
+			assert.Empty(t, values, "all values are read")
+		}
+	}
+	return footer, columns
+}
+
+func TestWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, []Column{{"id", Int64}, {"name", String}, {"created_at", Timestamp}})
+
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 6000000, time.UTC)
+	rows := RowGroupSize + 3
+	for i := 0; i < rows; i++ {
+		var name interface{}
+		if i%3 != 0 {
+			name = fmt.Sprintf("ユーザー%d", i)
+		}
+		require.NoError(t, w.Write([]interface{}{int64(i), name, createdAt}))
+	}
+	require.NoError(t, w.Close())
+
+	footer, columns := readFile(t, buf.Bytes())
+	assert.Equal(t, int64(rows), footer[3])
+	assert.Len(t, footer[4], 2, "rows are split into row groups")
+
+	schema := footer[2].([]interface{})
+	require.Len(t, schema, 4)
+	assert.Equal(t, int64(3), schema[0].(map[int16]interface{})[5])
+	created := schema[3].(map[int16]interface{})
+	assert.Equal(t, "created_at", created[4])
+	assert.Equal(t, int64(convertedTimestampMillis), created[6])
+
+	require.Len(t, columns["id"], rows)
+	assert.Equal(t, int64(rows-1), columns["id"][rows-1])
+	assert.Nil(t, columns["name"][0])
+	assert.Equal(t, "ユーザー1", columns["name"][1])
+	assert.Nil(t, columns["name"][rows-1])
+	assert.Equal(t, fmt.Sprintf("ユーザー%d", rows-2), columns["name"][rows-2])
+	assert.Equal(t, createdAt.UnixMilli(), columns["created_at"][rows-1])
+
+	assert.ErrorIs(t, w.Write([]interface{}{int64(1), nil, nil}), ErrClosed)
+}
+
+func TestWriter_Empty(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewWriter(&buf, []Column{{"id", Int64}})
+	require.NoError(t, w.Close())
+
+	footer, columns := readFile(t, buf.Bytes())
+	assert.Equal(t, int64(0), footer[3])
+	assert.Empty(t, columns)
+}
+
+func TestWriter_InvalidValue(t *testing.T) {
+	w := NewWriter(&bytes.Buffer{}, []Column{{"id", Int64}, {"name", String}})
+	assert.Error(t, w.Write([]interface{}{int64(1)}))
+	assert.Error(t, w.Write([]interface{}{"1", "name"}))
+	assert.Zero(t, w.rows)
+}
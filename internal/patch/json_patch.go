@@ -0,0 +1,303 @@
+package patch
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	errPathNotFound  = errors.New("path does not exist")
+	errInvalidIndex  = errors.New("invalid array index")
+	errValueMismatch = errors.New("value does not match")
+)
+
+// JSONPatch は doc に JSON Patch（RFC 6902）の操作を順に適用した結果を返す。
+// 操作は全体で1つの更新として扱い、途中の操作が失敗した場合はどの操作も反映せずにエラーを返す。
+// test 操作の値が一致しなければ ErrTestFailed、それ以外の失敗は ErrInvalid になる
+func JSONPatch(doc interface{}, data []byte) (interface{}, error) {
+	ops, err := parseOperations(data)
+	if err != nil {
+		return nil, err
+	}
+
+	result := deepCopy(doc)
+	for i, op := range ops {
+		if result, err = op.apply(result); err != nil {
+			kind := ErrInvalid
+			if errors.Is(err, errValueMismatch) {
+				kind = ErrTestFailed
+			}
+			return nil, fmt.Errorf("%w: operation %d (%s %s): %v", kind, i, op.op, op.path, err)
+		}
+	}
+	return result, nil
+}
+
+type operation struct {
+	op    string
+	path  string
+	from  string
+	value interface{}
+}
+
+func parseOperations(data []byte) ([]operation, error) {
+	v, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	list, ok := v.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: JSON Patch must be an array of operations", ErrInvalid)
+	}
+
+	ops := make([]operation, 0, len(list))
+	for i, item := range list {
+		members, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%w: operation %d must be an object", ErrInvalid, i)
+		}
+		var op operation
+		if op.op, ok = members["op"].(string); !ok {
+			return nil, fmt.Errorf("%w: operation %d: op must be a string", ErrInvalid, i)
+		}
+		if op.path, ok = members["path"].(string); !ok {
+			return nil, fmt.Errorf("%w: operation %d: path must be a string", ErrInvalid, i)
+		}
+		switch op.op {
+		case "add", "replace", "test":
+			if op.value, ok = members["value"]; !ok {
+				return nil, fmt.Errorf("%w: operation %d: %s requires value", ErrInvalid, i, op.op)
+			}
+		case "move", "copy":
+			if op.from, ok = members["from"].(string); !ok {
+				return nil, fmt.Errorf("%w: operation %d: %s requires from", ErrInvalid, i, op.op)
+			}
+		case "remove":
+		default:
+			return nil, fmt.Errorf("%w: operation %d: unknown op %q", ErrInvalid, i, op.op)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+func (op operation) apply(doc interface{}) (interface{}, error) {
+	path, err := parsePointer(op.path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch op.op {
+	case "add":
+		return add(doc, path, op.value)
+	case "remove":
+		doc, _, err := remove(doc, path)
+		return doc, err
+	case "replace":
+		return replace(doc, path, op.value)
+	case "move":
+		from, err := parsePointer(op.from)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(op.path, op.from+"/") {
+			return nil, errors.New("cannot move a value into one of its children")
+		}
+		if op.path == op.from {
+			_, err := get(doc, from)
+			return doc, err
+		}
+		doc, value, err := remove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, value)
+	case "copy":
+		from, err := parsePointer(op.from)
+		if err != nil {
+			return nil, err
+		}
+		value, err := get(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return add(doc, path, deepCopy(value))
+	default: // test
+		value, err := get(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !equal(value, op.value) {
+			return nil, errValueMismatch
+		}
+		return doc, nil
+	}
+}
+
+// parsePointer は JSON Pointer（RFC 6901）をトークンに分ける。空文字列はドキュメント全体を指す
+func parsePointer(s string) ([]string, error) {
+	if s == "" {
+		return nil, nil
+	}
+	if s[0] != '/' {
+		return nil, fmt.Errorf("pointer %q must start with /", s)
+	}
+	tokens := strings.Split(s[1:], "/")
+	unescape := strings.NewReplacer("~1", "/", "~0", "~")
+	for i, t := range tokens {
+		tokens[i] = unescape.Replace(t)
+	}
+	return tokens, nil
+}
+
+// arrayIndex は配列のインデックスのトークンを読む。
+// end が true なら末尾の次（長さ、または "-"）も指せる
+func arrayIndex(token string, length int, end bool) (int, error) {
+	if token == "-" && end {
+		return length, nil
+	}
+	if token == "" || (len(token) > 1 && token[0] == '0') || strings.Trim(token, "0123456789") != "" {
+		return 0, errInvalidIndex
+	}
+	i, err := strconv.Atoi(token)
+	if err != nil || i > length || (i == length && !end) {
+		return 0, errInvalidIndex
+	}
+	return i, nil
+}
+
+func get(doc interface{}, tokens []string) (interface{}, error) {
+	for _, t := range tokens {
+		switch node := doc.(type) {
+		case map[string]interface{}:
+			v, ok := node[t]
+			if !ok {
+				return nil, errPathNotFound
+			}
+			doc = v
+		case []interface{}:
+			i, err := arrayIndex(t, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			doc = node[i]
+		default:
+			return nil, errPathNotFound
+		}
+	}
+	return doc, nil
+}
+
+// modify は tokens の最後の1つを除いたパスが指すコンテナ（オブジェクトか配列）を fn で置き換える。
+// 配列は要素の追加・削除で作り直されるため、置き換えた値を親のコンテナに入れ直す
+func modify(doc interface{}, tokens []string, fn func(container interface{}, key string) (interface{}, error)) (interface{}, error) {
+	if len(tokens) == 1 {
+		return fn(doc, tokens[0])
+	}
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[tokens[0]]
+		if !ok {
+			return nil, errPathNotFound
+		}
+		v, err := modify(child, tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[tokens[0]] = v
+		return node, nil
+	case []interface{}:
+		i, err := arrayIndex(tokens[0], len(node), false)
+		if err != nil {
+			return nil, err
+		}
+		v, err := modify(node[i], tokens[1:], fn)
+		if err != nil {
+			return nil, err
+		}
+		node[i] = v
+		return node, nil
+	default:
+		return nil, errPathNotFound
+	}
+}
+
+func add(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return modify(doc, tokens, func(container interface{}, key string) (interface{}, error) {
+		switch node := container.(type) {
+		case map[string]interface{}:
+			node[key] = value
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(node), true)
+			if err != nil {
+				return nil, err
+			}
+			node = append(node, nil)
+			copy(node[i+1:], node[i:])
+			node[i] = value
+			return node, nil
+		default:
+			return nil, errPathNotFound
+		}
+	})
+}
+
+func remove(doc interface{}, tokens []string) (interface{}, interface{}, error) {
+	if len(tokens) == 0 {
+		return nil, nil, errors.New("cannot remove the whole document")
+	}
+	var removed interface{}
+	doc, err := modify(doc, tokens, func(container interface{}, key string) (interface{}, error) {
+		switch node := container.(type) {
+		case map[string]interface{}:
+			v, ok := node[key]
+			if !ok {
+				return nil, errPathNotFound
+			}
+			removed = v
+			delete(node, key)
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			removed = node[i]
+			return append(node[:i], node[i+1:]...), nil
+		default:
+			return nil, errPathNotFound
+		}
+	})
+	return doc, removed, err
+}
+
+func replace(doc interface{}, tokens []string, value interface{}) (interface{}, error) {
+	if len(tokens) == 0 {
+		return value, nil
+	}
+	return modify(doc, tokens, func(container interface{}, key string) (interface{}, error) {
+		switch node := container.(type) {
+		case map[string]interface{}:
+			if _, ok := node[key]; !ok {
+				return nil, errPathNotFound
+			}
+			node[key] = value
+			return node, nil
+		case []interface{}:
+			i, err := arrayIndex(key, len(node), false)
+			if err != nil {
+				return nil, err
+			}
+			node[i] = value
+			return node, nil
+		default:
+			return nil, errPathNotFound
+		}
+	})
+}
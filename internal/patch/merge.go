@@ -0,0 +1,31 @@
+package patch
+
+// MergePatch は doc に JSON Merge Patch（RFC 7386）を適用した結果を返す。
+// パッチのオブジェクトのメンバーは再帰的にマージされ、値が null のメンバーは削除される。
+// オブジェクト以外の値（配列を含む）はそのまま置き換わる
+func MergePatch(doc interface{}, data []byte) (interface{}, error) {
+	p, err := decode(data)
+	if err != nil {
+		return nil, err
+	}
+	return merge(deepCopy(doc), p), nil
+}
+
+func merge(target, p interface{}) interface{} {
+	members, ok := p.(map[string]interface{})
+	if !ok {
+		return p
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = map[string]interface{}{}
+	}
+	for k, v := range members {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = merge(t[k], v)
+	}
+	return t
+}
@@ -0,0 +1,111 @@
+// Package patch は JSON ドキュメントに部分更新のパッチを適用する。
+// RFC 7386 の JSON Merge Patch と RFC 6902 の JSON Patch に対応する。
+// ドキュメントは encoding/json でデコードした値（map[string]interface{}, []interface{} など）として扱い、
+// 適用しても元のドキュメントは書き換えない
+package patch
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// パッチのメディアタイプ
+const (
+	MergePatchContentType = "application/merge-patch+json"
+	JSONPatchContentType  = "application/json-patch+json"
+)
+
+var (
+	// ErrInvalid はパッチの形式が不正、またはドキュメントに適用できない（存在しないパスなど）場合のエラー
+	ErrInvalid = errors.New("invalid patch")
+	// ErrTestFailed は JSON Patch の test 操作で値が一致しなかった場合のエラー
+	ErrTestFailed = errors.New("patch test failed")
+)
+
+// decode はパッチを1つの JSON の値として読み込む。数値は精度を落とさないよう json.Number のまま扱う
+func decode(data []byte) (interface{}, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var v interface{}
+	if err := dec.Decode(&v); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalid, err)
+	}
+	if dec.More() {
+		return nil, fmt.Errorf("%w: unexpected data after the patch document", ErrInvalid)
+	}
+	return v, nil
+}
+
+// deepCopy はオブジェクトと配列を複製する（パッチの適用中に元のドキュメントを書き換えないため）
+func deepCopy(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = deepCopy(e)
+		}
+		return m
+	case []interface{}:
+		a := make([]interface{}, len(v))
+		for i, e := range v {
+			a[i] = deepCopy(e)
+		}
+		return a
+	default:
+		return v
+	}
+}
+
+// equal は JSON の値として等しいかを返す。数値は表記ではなく値で比べる（1 と 1.0 は等しい）
+func equal(a, b interface{}) bool {
+	switch a := a.(type) {
+	case map[string]interface{}:
+		b, ok := b.(map[string]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for k, v := range a {
+			w, ok := b[k]
+			if !ok || !equal(v, w) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		b, ok := b.([]interface{})
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !equal(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	case nil:
+		return b == nil
+	default:
+		x, xok := number(a)
+		y, yok := number(b)
+		if xok || yok {
+			return xok && yok && x == y
+		}
+		return a == b
+	}
+}
+
+func number(v interface{}) (float64, bool) {
+	switch v := v.(type) {
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	default:
+		return 0, false
+	}
+}
@@ -0,0 +1,107 @@
+package patch
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func parse(t *testing.T, s string) interface{} {
+	t.Helper()
+	v, err := decode([]byte(s))
+	require.NoError(t, err)
+	return v
+}
+
+func TestMergePatch(t *testing.T) {
+	// RFC 7386 の付録の例から
+	cases := []struct {
+		doc, patch, want string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tc := range cases {
+		doc := parse(t, tc.doc)
+		got, err := MergePatch(doc, []byte(tc.patch))
+		require.NoError(t, err, tc.patch)
+		assert.True(t, equal(parse(t, tc.want), got), "%s + %s = %v", tc.doc, tc.patch, got)
+		assert.True(t, equal(parse(t, tc.doc), doc), "original document is not modified")
+	}
+
+	_, err := MergePatch(map[string]interface{}{}, []byte(`{"a":`))
+	assert.ErrorIs(t, err, ErrInvalid)
+}
+
+func TestJSONPatch(t *testing.T) {
+	// RFC 6902 の付録の例から
+	cases := []struct {
+		doc, patch, want string
+	}{
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/baz","value":"qux"}]`, `{"baz":"qux","foo":"bar"}`},
+		{`{"foo":["bar","baz"]}`, `[{"op":"add","path":"/foo/1","value":"qux"}]`, `{"foo":["bar","qux","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"remove","path":"/baz"}]`, `{"foo":"bar"}`},
+		{`{"foo":["bar","qux","baz"]}`, `[{"op":"remove","path":"/foo/1"}]`, `{"foo":["bar","baz"]}`},
+		{`{"baz":"qux","foo":"bar"}`, `[{"op":"replace","path":"/baz","value":"boo"}]`, `{"baz":"boo","foo":"bar"}`},
+		{`{"foo":{"bar":"baz","waldo":"fred"},"qux":{"corge":"grault"}}`, `[{"op":"move","from":"/foo/waldo","path":"/qux/thud"}]`,
+			`{"foo":{"bar":"baz"},"qux":{"corge":"grault","thud":"fred"}}`},
+		{`{"foo":["all","grass","cows","eat"]}`, `[{"op":"move","from":"/foo/1","path":"/foo/3"}]`, `{"foo":["all","cows","eat","grass"]}`},
+		{`{"baz":"qux","foo":["a",2,"c"]}`, `[{"op":"test","path":"/baz","value":"qux"},{"op":"test","path":"/foo/1","value":2.0}]`,
+			`{"baz":"qux","foo":["a",2,"c"]}`},
+		{`{"foo":"bar"}`, `[{"op":"add","path":"/child","value":{"grandchild":{}}}]`, `{"foo":"bar","child":{"grandchild":{}}}`},
+		{`{"foo":["bar"]}`, `[{"op":"add","path":"/foo/-","value":["abc","def"]}]`, `{"foo":["bar",["abc","def"]]}`},
+		{`{"/":9,"~1":10}`, `[{"op":"test","path":"/~01","value":10},{"op":"copy","from":"/~1","path":"/a"}]`, `{"/":9,"~1":10,"a":9}`},
+		{`{"foo":null}`, `[{"op":"test","path":"/foo","value":null},{"op":"replace","path":"/foo","value":1}]`, `{"foo":1}`},
+	}
+	for _, tc := range cases {
+		doc := parse(t, tc.doc)
+		got, err := JSONPatch(doc, []byte(tc.patch))
+		require.NoError(t, err, tc.patch)
+		assert.True(t, equal(parse(t, tc.want), got), "%s + %s = %v", tc.doc, tc.patch, got)
+		assert.True(t, equal(parse(t, tc.doc), doc), "original document is not modified")
+	}
+}
+
+func TestJSONPatch_Errors(t *testing.T) {
+	doc := map[string]interface{}{"foo": "bar", "list": []interface{}{"a"}}
+	invalid := []string{
+		`{"op":"add","path":"/a","value":1}`,
+		`[{"op":"add","path":"/a"}]`,
+		`[{"op":"unknown","path":"/a"}]`,
+		`[{"path":"/a","value":1}]`,
+		`[{"op":"remove","path":"/missing"}]`,
+		`[{"op":"replace","path":"/missing","value":1}]`,
+		`[{"op":"add","path":"/missing/child","value":1}]`,
+		`[{"op":"add","path":"/list/2","value":"b"}]`,
+		`[{"op":"remove","path":"/list/01"}]`,
+		`[{"op":"move","from":"/list","path":"/list/0"}]`,
+		`[{"op":"copy","path":"/a"}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+	}
+	for _, p := range invalid {
+		_, err := JSONPatch(doc, []byte(p))
+		assert.ErrorIs(t, err, ErrInvalid, p)
+	}
+
+	// 途中で失敗した場合は、それまでの操作も反映しない
+	_, err := JSONPatch(doc, []byte(`[{"op":"replace","path":"/foo","value":"baz"},{"op":"test","path":"/foo","value":"bar"}]`))
+	assert.ErrorIs(t, err, ErrTestFailed)
+	assert.Equal(t, "bar", doc["foo"])
+}
+
+func TestEqual(t *testing.T) {
+	assert.True(t, equal(json.Number("1"), json.Number("1.0")))
+	assert.False(t, equal(json.Number("1"), "1"))
+	assert.False(t, equal(nil, false))
+	assert.False(t, equal(map[string]interface{}{"a": nil}, map[string]interface{}{}))
+}
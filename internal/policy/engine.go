@@ -0,0 +1,136 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Subject は操作を行う主体
+type Subject struct {
+	ID   uint   `json:"id"`
+	Role string `json:"role"`
+}
+
+// Resource は操作の対象
+// OwnerID はリソースの所有者（ユーザー自身なら ID と同じ）
+type Resource struct {
+	Type    string `json:"type"`
+	ID      uint   `json:"id,omitempty"`
+	OwnerID uint   `json:"owner_id,omitempty"`
+}
+
+// Request は認可判定のリクエスト
+type Request struct {
+	Subject  Subject  `json:"subject"`
+	Action   string   `json:"action"`
+	Resource Resource `json:"resource"`
+	Fields   []string `json:"fields,omitempty"` // 更新系の操作で変更しようとしているフィールド
+}
+
+// RuleResult は各ルールの評価結果（判定理由の説明用）
+type RuleResult struct {
+	RuleID  string `json:"rule_id"`
+	Effect  Effect `json:"effect"`
+	Matched bool   `json:"matched"`
+	Reason  string `json:"reason"`
+}
+
+// Decision は認可判定の結果
+type Decision struct {
+	Allowed bool         `json:"allowed"`
+	Rule    string       `json:"rule,omitempty"` // 判定を決めたルール
+	Reason  string       `json:"reason"`
+	Trace   []RuleResult `json:"trace"`
+}
+
+// Engine はポリシーに基づいて認可判定を行う
+type Engine struct {
+	policy *Policy
+}
+
+// Evaluate はリクエストを評価する
+// deny のルールが1つでも一致すれば拒否、allow のルールが一致すれば許可、どちらも無ければ拒否する
+func (e *Engine) Evaluate(req Request) Decision {
+	decision := Decision{Trace: []RuleResult{}}
+	var allowedBy *Rule
+
+	for i := range e.policy.Rules {
+		rule := &e.policy.Rules[i]
+		matched, reason := rule.match(req)
+		decision.Trace = append(decision.Trace, RuleResult{
+			RuleID:  rule.ID,
+			Effect:  rule.Effect,
+			Matched: matched,
+			Reason:  reason,
+		})
+		if !matched {
+			continue
+		}
+
+		if rule.Effect == Deny {
+			decision.Allowed = false
+			decision.Rule = rule.ID
+			decision.Reason = fmt.Sprintf("denied by rule %s", rule.ID)
+			return decision
+		}
+		if allowedBy == nil {
+			allowedBy = rule
+		}
+	}
+
+	if allowedBy != nil {
+		decision.Allowed = true
+		decision.Rule = allowedBy.ID
+		decision.Reason = fmt.Sprintf("allowed by rule %s", allowedBy.ID)
+		return decision
+	}
+
+	decision.Reason = fmt.Sprintf("no rule allows %s on %s for role %s", req.Action, req.Resource.Type, req.Subject.Role)
+	return decision
+}
+
+// match はルールがリクエストに一致するかどうかと、その理由を返す
+func (r *Rule) match(req Request) (bool, string) {
+	if !matchAny(r.Roles, req.Subject.Role) {
+		return false, fmt.Sprintf("role %q not in %v", req.Subject.Role, r.Roles)
+	}
+	if !matchAny(r.Actions, req.Action) {
+		return false, fmt.Sprintf("action %q not in %v", req.Action, r.Actions)
+	}
+	if r.Resource != "" && r.Resource != req.Resource.Type {
+		return false, fmt.Sprintf("resource %q is not %q", req.Resource.Type, r.Resource)
+	}
+	if r.Conditions.Self && req.Subject.ID != req.Resource.OwnerID {
+		return false, "resource is not owned by the subject"
+	}
+	if len(r.Fields) > 0 {
+		for _, field := range req.Fields {
+			if !contains(r.Fields, field) {
+				return false, fmt.Sprintf("field %q not in %v", field, r.Fields)
+			}
+		}
+	}
+	return true, "matched"
+}
+
+// matchAny は "*" と末尾の "*" による前方一致をサポートする
+func matchAny(patterns []string, value string) bool {
+	for _, p := range patterns {
+		if p == "*" || p == value {
+			return true
+		}
+		if strings.HasSuffix(p, "*") && strings.HasPrefix(value, strings.TrimSuffix(p, "*")) {
+			return true
+		}
+	}
+	return false
+}
+
+func contains(values []string, v string) bool {
+	for _, value := range values {
+		if value == v {
+			return true
+		}
+	}
+	return false
+}
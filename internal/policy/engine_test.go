@@ -0,0 +1,83 @@
+package policy_test
+
+import (
+	"testing"
+
+	"github.com/okamuuu/go-user-app/internal/policy"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func user(id uint) policy.Resource {
+	return policy.Resource{Type: "user", ID: id, OwnerID: id}
+}
+
+func TestDefaultPolicy(t *testing.T) {
+	engine := policy.Default()
+
+	admin := policy.Subject{ID: 1, Role: "admin"}
+	support := policy.Subject{ID: 2, Role: "support"}
+	member := policy.Subject{ID: 3, Role: "member"}
+
+	cases := []struct {
+		name    string
+		req     policy.Request
+		allowed bool
+		rule    string
+	}{
+		{"admin can delete anyone", policy.Request{Subject: admin, Action: "users:delete", Resource: user(3)}, true, "admin-manage-users"},
+		{"support can list users", policy.Request{Subject: support, Action: "users:list", Resource: policy.Resource{Type: "user"}}, true, "support-read-users"},
+		{"support can update name", policy.Request{Subject: support, Action: "users:update", Resource: user(3), Fields: []string{"name"}}, true, "support-update-name"},
+		{"support cannot update email", policy.Request{Subject: support, Action: "users:update", Resource: user(3), Fields: []string{"name", "email"}}, false, ""},
+		{"support cannot delete", policy.Request{Subject: support, Action: "users:delete", Resource: user(3)}, false, ""},
+		{"member can read self", policy.Request{Subject: member, Action: "users:read", Resource: user(3)}, true, "self-read"},
+		{"member cannot read others", policy.Request{Subject: member, Action: "users:read", Resource: user(1)}, false, ""},
+		{"member can update self", policy.Request{Subject: member, Action: "users:update", Resource: user(3), Fields: []string{"email", "password"}}, true, "self-update"},
+		{"member can delete self", policy.Request{Subject: member, Action: "users:delete", Resource: user(3)}, true, "self-delete"},
+		{"member cannot delete others", policy.Request{Subject: member, Action: "users:delete", Resource: user(2)}, false, ""},
+		{"member cannot create", policy.Request{Subject: member, Action: "users:create", Resource: policy.Resource{Type: "user"}}, false, ""},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			decision := engine.Evaluate(tc.req)
+			assert.Equal(t, tc.allowed, decision.Allowed, decision.Reason)
+			assert.Equal(t, tc.rule, decision.Rule)
+			assert.NotEmpty(t, decision.Trace)
+		})
+	}
+}
+
+func TestDenyOverridesAllow(t *testing.T) {
+	engine, err := policy.Parse([]byte(`{
+		"version": 1,
+		"rules": [
+			{"id": "allow-all", "effect": "allow", "roles": ["*"], "actions": ["*"]},
+			{"id": "no-delete", "effect": "deny", "roles": ["support"], "actions": ["users:delete"]}
+		]
+	}`))
+	require.NoError(t, err)
+
+	decision := engine.Evaluate(policy.Request{
+		Subject:  policy.Subject{ID: 1, Role: "support"},
+		Action:   "users:delete",
+		Resource: user(2),
+	})
+	assert.False(t, decision.Allowed)
+	assert.Equal(t, "no-delete", decision.Rule)
+
+	decision = engine.Evaluate(policy.Request{
+		Subject:  policy.Subject{ID: 1, Role: "member"},
+		Action:   "users:delete",
+		Resource: user(2),
+	})
+	assert.True(t, decision.Allowed)
+}
+
+func TestParse_Invalid(t *testing.T) {
+	_, err := policy.Parse([]byte(`{"rules": [{"id": "x", "effect": "maybe", "roles": ["*"], "actions": ["*"]}]}`))
+	assert.Error(t, err)
+
+	_, err = policy.Parse([]byte(`{"rules": [{"id": "x", "effect": "allow", "roles": ["*"], "actions": ["*"]}, {"id": "x", "effect": "allow", "roles": ["*"], "actions": ["*"]}]}`))
+	assert.Error(t, err)
+}
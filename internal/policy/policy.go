@@ -0,0 +1,99 @@
+// Package policy は (subject, action, resource) に対する認可判定を、宣言的なポリシーファイルから行う
+package policy
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Effect はルールが一致したときの効果
+type Effect string
+
+const (
+	Allow Effect = "allow"
+	Deny  Effect = "deny"
+)
+
+// Policy はポリシーファイル全体
+type Policy struct {
+	Version int    `json:"version"`
+	Rules   []Rule `json:"rules"`
+}
+
+// Rule はポリシーの1ルール
+//
+//	Roles:      対象となるロール（"*" で全ロール）
+//	Actions:    対象となる操作（"users:*" のように末尾の * で前方一致）
+//	Resource:   対象となるリソースの種類（空なら全種類）
+//	Conditions: 追加の条件
+//	Fields:     更新系の操作で変更してよいフィールド（空なら制限なし）
+type Rule struct {
+	ID          string     `json:"id"`
+	Description string     `json:"description,omitempty"`
+	Effect      Effect     `json:"effect"`
+	Roles       []string   `json:"roles"`
+	Actions     []string   `json:"actions"`
+	Resource    string     `json:"resource,omitempty"`
+	Conditions  Conditions `json:"conditions,omitempty"`
+	Fields      []string   `json:"fields,omitempty"`
+}
+
+// Conditions はルールの追加条件
+type Conditions struct {
+	// Self が true の場合、操作対象が自分自身のときだけ一致する
+	Self bool `json:"self,omitempty"`
+}
+
+//go:embed default_policy.json
+var defaultPolicy []byte
+
+// Load はポリシーファイルを読み込む
+func Load(path string) (*Engine, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Default は組み込みのデフォルトポリシーを返す
+func Default() *Engine {
+	engine, err := Parse(defaultPolicy)
+	if err != nil {
+		panic(fmt.Sprintf("invalid default policy: %v", err))
+	}
+	return engine
+}
+
+// Parse は JSON 形式のポリシーをパースする
+func Parse(data []byte) (*Engine, error) {
+	var p Policy
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return New(&p)
+}
+
+// New はポリシーを検証して Engine を作成する
+func New(p *Policy) (*Engine, error) {
+	seen := map[string]bool{}
+	for i, rule := range p.Rules {
+		if rule.ID == "" {
+			return nil, fmt.Errorf("rule #%d: id is required", i+1)
+		}
+		if seen[rule.ID] {
+			return nil, fmt.Errorf("rule %s: duplicate id", rule.ID)
+		}
+		seen[rule.ID] = true
+
+		if rule.Effect != Allow && rule.Effect != Deny {
+			return nil, fmt.Errorf("rule %s: effect must be %q or %q", rule.ID, Allow, Deny)
+		}
+		if len(rule.Roles) == 0 || len(rule.Actions) == 0 {
+			return nil, fmt.Errorf("rule %s: roles and actions are required", rule.ID)
+		}
+	}
+	return &Engine{policy: p}, nil
+}
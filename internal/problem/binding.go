@@ -0,0 +1,75 @@
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+)
+
+func init() {
+	// 検証エラーの項目名を、構造体のフィールド名ではなく JSON のキーにする
+	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(func(f reflect.StructField) string {
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if name == "-" {
+				return ""
+			}
+			if name == "" {
+				return f.Name
+			}
+			return name
+		})
+	}
+}
+
+// Binding はリクエストボディの読み取り・検証に失敗したエラーを、項目ごとの検証エラーとして返す
+func Binding(c *gin.Context, err error) {
+	Error(c, BindingError(err))
+}
+
+// BindingError は ShouldBindJSON などのエラーを domain の検証エラーに変換する
+func BindingError(err error) error {
+	var verrs validator.ValidationErrors
+	if errors.As(err, &verrs) {
+		ve := &domain.ValidationError{}
+		for _, fe := range verrs {
+			ve.Fields = append(ve.Fields, domain.FieldError{
+				Field:   fe.Field(),
+				Code:    fe.Tag(),
+				Message: fieldMessage(fe),
+			})
+		}
+		return ve
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) {
+		return domain.NewValidationError(typeErr.Field, "type", "must be of type "+typeErr.Type.String())
+	}
+	return domain.NewError(domain.ErrValidation, CodeInvalidRequest, "request body is not valid JSON")
+}
+
+func fieldMessage(fe validator.FieldError) string {
+	switch fe.Tag() {
+	case "required":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "min":
+		return fmt.Sprintf("must be at least %s characters", fe.Param())
+	case "max":
+		return fmt.Sprintf("must be at most %s characters", fe.Param())
+	case "oneof":
+		return "must be one of " + fe.Param()
+	default:
+		return "is invalid"
+	}
+}
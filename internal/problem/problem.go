@@ -0,0 +1,123 @@
+// Package problem はエラーレスポンスを RFC 7807 の problem details（application/problem+json）で返す。
+// domain のエラーの種類から HTTP のステータスを決め、機械可読なコードと項目ごとの検証エラーを載せる
+package problem
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+)
+
+const ContentType = "application/problem+json"
+
+// 種類ごとの既定のコード（domain.Error を使わないエラーで使う）
+const (
+	CodeInvalidRequest = "invalid_request"
+	CodeValidation     = "validation_failed"
+	CodeUnauthorized   = "unauthorized"
+	CodeForbidden      = "forbidden"
+	CodeNotFound       = "not_found"
+	CodeConflict       = "conflict"
+	CodeInternal       = "internal_error"
+
+	CodePreconditionFailed   = "precondition_failed"
+	CodeTooManyRequests      = "too_many_requests"
+	CodePreconditionRequired = "precondition_required"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+)
+
+// Details は RFC 7807 の problem details です。
+// code は問題の種類を表す変わらない値で、クライアントはこれでエラーを判別します。
+type Details struct {
+	Type     string       `json:"type" example:"about:blank"`
+	Title    string       `json:"title" example:"Not Found"`
+	Status   int          `json:"status" example:"404"`
+	Detail   string       `json:"detail,omitempty" example:"user not found"`
+	Instance string       `json:"instance,omitempty" example:"/api/users/42"`
+	Code     string       `json:"code" example:"user_not_found"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError は入力項目ごとの検証エラーです。
+type FieldError struct {
+	Field   string `json:"field" example:"email"`
+	Code    string `json:"code" example:"email"`
+	Message string `json:"message" example:"must be a valid email address"`
+}
+
+// New は problem details を作る
+func New(status int, code, detail string) *Details {
+	return &Details{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// FromError はエラーの種類からステータスとコードを決める。
+// 種類の分からないエラーは 500 とし、内部のエラーメッセージは返さない
+func FromError(err error) *Details {
+	var p *Details
+	switch {
+	case errors.Is(err, domain.ErrValidation):
+		p = New(http.StatusBadRequest, CodeValidation, err.Error())
+	case errors.Is(err, domain.ErrUnauthorized):
+		p = New(http.StatusUnauthorized, CodeUnauthorized, err.Error())
+	case errors.Is(err, domain.ErrForbidden):
+		p = New(http.StatusForbidden, CodeForbidden, err.Error())
+	case errors.Is(err, domain.ErrNotFound):
+		p = New(http.StatusNotFound, CodeNotFound, err.Error())
+	case errors.Is(err, domain.ErrConflict):
+		p = New(http.StatusConflict, CodeConflict, err.Error())
+	case errors.Is(err, domain.ErrPreconditionFailed):
+		p = New(http.StatusPreconditionFailed, CodePreconditionFailed, err.Error())
+	case errors.Is(err, domain.ErrTooManyRequests):
+		p = New(http.StatusTooManyRequests, CodeTooManyRequests, err.Error())
+	default:
+		return New(http.StatusInternalServerError, CodeInternal, "internal server error")
+	}
+
+	var de *domain.Error
+	if errors.As(err, &de) {
+		p.Code = de.Code
+	}
+	var ve *domain.ValidationError
+	if errors.As(err, &ve) {
+		p.Detail = "request has invalid fields"
+		for _, f := range ve.Fields {
+			p.Errors = append(p.Errors, FieldError{Field: f.Field, Code: f.Code, Message: f.Message})
+		}
+	}
+	return p
+}
+
+// Write はレスポンスに problem details を書き込み、以降のハンドラーを止める
+func Write(c *gin.Context, p *Details) {
+	if p.Instance == "" {
+		p.Instance = c.Request.URL.Path
+	}
+	c.Header("Content-Type", ContentType)
+	c.Render(p.Status, render.JSON{Data: p})
+	c.Abort()
+}
+
+// Respond は status と code でエラーを返す
+func Respond(c *gin.Context, status int, code, detail string) {
+	Write(c, New(status, code, detail))
+}
+
+// Error はエラーの種類に応じたレスポンスを返す。500 になるエラーはログに残す
+func Error(c *gin.Context, err error) {
+	p := FromError(err)
+	if p.Status == http.StatusInternalServerError {
+		log.Printf("[ERROR] %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+	}
+	Write(c, p)
+}
@@ -0,0 +1,71 @@
+package problem
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/gin-gonic/gin/binding"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+)
+
+func TestFromError(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{domain.ErrUserNotFound, http.StatusNotFound, "user_not_found"},
+		{fmt.Errorf("update: %w", domain.ErrEmailTaken), http.StatusConflict, "email_taken"},
+		{domain.NewError(domain.ErrForbidden, "", "nope"), http.StatusForbidden, ""},
+		{domain.ErrUnauthorized, http.StatusUnauthorized, CodeUnauthorized},
+		{domain.ErrConflict, http.StatusConflict, CodeConflict},
+		{domain.ErrVersionMismatch, http.StatusPreconditionFailed, "version_mismatch"},
+		{domain.NewValidationError("q", "required", "is required"), http.StatusBadRequest, CodeValidation},
+		{errors.New("connection refused"), http.StatusInternalServerError, CodeInternal},
+	}
+	for _, tt := range tests {
+		p := FromError(tt.err)
+		assert.Equal(t, tt.status, p.Status, tt.err.Error())
+		assert.Equal(t, http.StatusText(tt.status), p.Title)
+		assert.Equal(t, "about:blank", p.Type)
+		if tt.code != "" {
+			assert.Equal(t, tt.code, p.Code, tt.err.Error())
+		}
+	}
+
+	// 内部のエラーメッセージは返さない
+	assert.Equal(t, "internal server error", FromError(errors.New("connection refused")).Detail)
+}
+
+func TestBindingError(t *testing.T) {
+	var req struct {
+		Name  string `json:"name" binding:"required"`
+		Email string `json:"email" binding:"required,email"`
+		Age   int    `json:"age"`
+	}
+
+	err := binding.Validator.ValidateStruct(&struct {
+		Name  string `json:"name" binding:"required"`
+		Email string `json:"email" binding:"required,email"`
+	}{Email: "x"})
+	p := FromError(BindingError(err))
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, []FieldError{
+		{Field: "name", Code: "required", Message: "is required"},
+		{Field: "email", Code: "email", Message: "must be a valid email address"},
+	}, p.Errors)
+
+	err = binding.JSON.BindBody([]byte(`{"name":"a","email":"a@example.com","age":"x"}`), &req)
+	p = FromError(BindingError(err))
+	assert.Equal(t, []FieldError{{Field: "age", Code: "type", Message: "must be of type int"}}, p.Errors)
+
+	err = binding.JSON.BindBody([]byte(`{`), &req)
+	p = FromError(BindingError(err))
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, CodeInvalidRequest, p.Code)
+	assert.Empty(t, p.Errors)
+}
@@ -0,0 +1,17 @@
+package repository
+
+import "time"
+
+// AuditLog は監査ログ。変更前後の値は JSON 文字列で保存する
+type AuditLog struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	ActorID    *uint  `gorm:"index"`
+	Action     string `gorm:"index;not null"`
+	TargetType string
+	TargetID   *uint `gorm:"index"`
+	IP         string
+	UserAgent  string    `gorm:"type:text"`
+	Before     string    `gorm:"type:text"`
+	After      string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"index"`
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"gorm.io/gorm"
+)
+
+type AuditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) *AuditRepository {
+	return &AuditRepository{db: db}
+}
+
+// Create appends an audit event
+func (r *AuditRepository) Create(event *domain.AuditEvent) error {
+	model, err := ToAuditLogModel(event)
+	if err != nil {
+		return err
+	}
+	if err := r.db.Create(model).Error; err != nil {
+		return err
+	}
+	event.ID = model.ID
+	event.CreatedAt = model.CreatedAt
+	return nil
+}
+
+// Find returns audit events matching the filter, newest first
+func (r *AuditRepository) Find(filter domain.AuditFilter, offset, limit int) ([]*domain.AuditEvent, error) {
+	query := r.db.Model(&AuditLog{})
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	if filter.UserID != nil {
+		query = query.Where("(actor_id = ? OR target_id = ?)", *filter.UserID, *filter.UserID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var models []AuditLog
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]*domain.AuditEvent, 0, len(models))
+	for i := range models {
+		events = append(events, ToDomainAuditEvent(&models[i]))
+	}
+	return events, nil
+}
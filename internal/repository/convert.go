@@ -1,27 +1,289 @@
 package repository
 
-import "github.com/okamuuu/go-user-app/internal/domain"
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"gorm.io/gorm"
+)
+
+func toGormDeletedAt(t *time.Time) gorm.DeletedAt {
+	if t == nil {
+		return gorm.DeletedAt{}
+	}
+	return gorm.DeletedAt{Time: *t, Valid: true}
+}
+
+func fromGormDeletedAt(d gorm.DeletedAt) *time.Time {
+	if !d.Valid {
+		return nil
+	}
+	t := d.Time
+	return &t
+}
 
 // ドメインモデル → DBモデル
 func ToUserModel(u *domain.User) *User {
 	return &User{
-		ID:        u.ID,
-		Name:      u.Name,
-		Email:     u.Email,
-		Password:  u.Password,
-		CreatedAt: u.CreatedAt,
-		UpdatedAt: u.UpdatedAt,
+		ID:              u.ID,
+		Name:            u.Name,
+		Email:           u.Email,
+		Password:        u.Password,
+		Role:            string(u.Role),
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+		EmailVerifiedAt: u.EmailVerifiedAt,
+		DeletedAt:       toGormDeletedAt(u.DeletedAt),
+		Version:         u.Version,
 	}
 }
 
 // DBモデル → ドメインモデル
 func ToDomainUser(um *User) *domain.User {
 	return &domain.User{
-		ID:        um.ID,
-		Name:      um.Name,
-		Email:     um.Email,
-		Password:  um.Password,
-		CreatedAt: um.CreatedAt,
-		UpdatedAt: um.UpdatedAt,
+		ID:              um.ID,
+		Name:            um.Name,
+		Email:           um.Email,
+		Password:        um.Password,
+		Role:            domain.Role(um.Role),
+		CreatedAt:       um.CreatedAt,
+		UpdatedAt:       um.UpdatedAt,
+		EmailVerifiedAt: um.EmailVerifiedAt,
+		DeletedAt:       fromGormDeletedAt(um.DeletedAt),
+		Version:         um.Version,
+	}
+}
+
+// ドメインモデル → DBモデル
+func ToRefreshTokenModel(t *domain.RefreshToken) *RefreshToken {
+	return &RefreshToken{
+		ID:           t.ID,
+		UserID:       t.UserID,
+		FamilyID:     t.FamilyID,
+		TokenHash:    t.TokenHash,
+		ExpiresAt:    t.ExpiresAt,
+		RevokedAt:    t.RevokedAt,
+		ReplacedByID: t.ReplacedByID,
+		CreatedAt:    t.CreatedAt,
+	}
+}
+
+// DBモデル → ドメインモデル
+func ToDomainRefreshToken(m *RefreshToken) *domain.RefreshToken {
+	return &domain.RefreshToken{
+		ID:           m.ID,
+		UserID:       m.UserID,
+		FamilyID:     m.FamilyID,
+		TokenHash:    m.TokenHash,
+		ExpiresAt:    m.ExpiresAt,
+		RevokedAt:    m.RevokedAt,
+		ReplacedByID: m.ReplacedByID,
+		CreatedAt:    m.CreatedAt,
+	}
+}
+
+// ドメインモデル → DBモデル
+func ToPasswordResetTokenModel(t *domain.PasswordResetToken) *PasswordResetToken {
+	return &PasswordResetToken{
+		ID:        t.ID,
+		UserID:    t.UserID,
+		TokenHash: t.TokenHash,
+		ExpiresAt: t.ExpiresAt,
+		UsedAt:    t.UsedAt,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+// DBモデル → ドメインモデル
+func ToDomainPasswordResetToken(m *PasswordResetToken) *domain.PasswordResetToken {
+	return &domain.PasswordResetToken{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		TokenHash: m.TokenHash,
+		ExpiresAt: m.ExpiresAt,
+		UsedAt:    m.UsedAt,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// ドメインモデル → DBモデル
+func ToEmailVerificationTokenModel(t *domain.EmailVerificationToken) *EmailVerificationToken {
+	return &EmailVerificationToken{
+		ID:        t.ID,
+		UserID:    t.UserID,
+		Email:     t.Email,
+		TokenHash: t.TokenHash,
+		ExpiresAt: t.ExpiresAt,
+		UsedAt:    t.UsedAt,
+		CreatedAt: t.CreatedAt,
+	}
+}
+
+// DBモデル → ドメインモデル
+func ToDomainEmailVerificationToken(m *EmailVerificationToken) *domain.EmailVerificationToken {
+	return &domain.EmailVerificationToken{
+		ID:        m.ID,
+		UserID:    m.UserID,
+		Email:     m.Email,
+		TokenHash: m.TokenHash,
+		ExpiresAt: m.ExpiresAt,
+		UsedAt:    m.UsedAt,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// DBモデル → ドメインモデル
+func ToDomainMFACredential(m *MFACredential) *domain.MFACredential {
+	return &domain.MFACredential{
+		UserID:         m.UserID,
+		Secret:         m.Secret,
+		EnabledAt:      m.EnabledAt,
+		LastUsedStep:   m.LastUsedStep,
+		FailedAttempts: m.FailedAttempts,
+		LockedUntil:    m.LockedUntil,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
+
+// ドメインモデル → DBモデル
+func ToAuditLogModel(e *domain.AuditEvent) (*AuditLog, error) {
+	before, err := marshalChanges(e.Before)
+	if err != nil {
+		return nil, err
+	}
+	after, err := marshalChanges(e.After)
+	if err != nil {
+		return nil, err
+	}
+	return &AuditLog{
+		ID:         e.ID,
+		ActorID:    e.ActorID,
+		Action:     e.Action,
+		TargetType: e.TargetType,
+		TargetID:   e.TargetID,
+		IP:         e.IP,
+		UserAgent:  e.UserAgent,
+		Before:     before,
+		After:      after,
+		CreatedAt:  e.CreatedAt,
+	}, nil
+}
+
+// DBモデル → ドメインモデル
+func ToDomainAuditEvent(m *AuditLog) *domain.AuditEvent {
+	return &domain.AuditEvent{
+		ID:         m.ID,
+		ActorID:    m.ActorID,
+		Action:     m.Action,
+		TargetType: m.TargetType,
+		TargetID:   m.TargetID,
+		IP:         m.IP,
+		UserAgent:  m.UserAgent,
+		Before:     unmarshalChanges(m.Before),
+		After:      unmarshalChanges(m.After),
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+func marshalChanges(changes map[string]interface{}) (string, error) {
+	if len(changes) == 0 {
+		return "", nil
+	}
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func unmarshalChanges(s string) map[string]interface{} {
+	if s == "" {
+		return nil
+	}
+	var changes map[string]interface{}
+	if err := json.Unmarshal([]byte(s), &changes); err != nil {
+		return nil
+	}
+	return changes
+}
+
+// ドメインモデル → DBモデル
+func ToIdempotencyKeyModel(r *domain.IdempotencyRecord) (*IdempotencyKey, error) {
+	var header string
+	if len(r.Header) > 0 {
+		b, err := json.Marshal(r.Header)
+		if err != nil {
+			return nil, err
+		}
+		header = string(b)
+	}
+	return &IdempotencyKey{
+		KeyHash:     r.KeyHash,
+		Fingerprint: r.Fingerprint,
+		StatusCode:  r.StatusCode,
+		Header:      header,
+		Body:        r.Body,
+		ExpiresAt:   r.ExpiresAt,
+		CreatedAt:   r.CreatedAt,
+	}, nil
+}
+
+// DBモデル → ドメインモデル
+func ToDomainIdempotencyRecord(m *IdempotencyKey) *domain.IdempotencyRecord {
+	var header map[string][]string
+	if m.Header != "" {
+		if err := json.Unmarshal([]byte(m.Header), &header); err != nil {
+			header = nil
+		}
+	}
+	return &domain.IdempotencyRecord{
+		KeyHash:     m.KeyHash,
+		Fingerprint: m.Fingerprint,
+		StatusCode:  m.StatusCode,
+		Header:      header,
+		Body:        m.Body,
+		ExpiresAt:   m.ExpiresAt,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// ドメインモデル → DBモデル
+func ToErasureRecordModel(r *domain.ErasureRecord) (*ErasureRecord, error) {
+	deleted, err := json.Marshal(r.Deleted)
+	if err != nil {
+		return nil, err
+	}
+	anonymized, err := json.Marshal(r.Anonymized)
+	if err != nil {
+		return nil, err
+	}
+	return &ErasureRecord{
+		ID:          r.ID,
+		UserID:      r.UserID,
+		RequestedBy: r.RequestedBy,
+		Reason:      r.Reason,
+		Deleted:     string(deleted),
+		Anonymized:  string(anonymized),
+		ErasedAt:    r.ErasedAt,
+	}, nil
+}
+
+// DBモデル → ドメインモデル
+func ToDomainErasureRecord(m *ErasureRecord) *domain.ErasureRecord {
+	record := &domain.ErasureRecord{
+		ID:          m.ID,
+		UserID:      m.UserID,
+		RequestedBy: m.RequestedBy,
+		Reason:      m.Reason,
+		ErasedAt:    m.ErasedAt,
+	}
+	if err := json.Unmarshal([]byte(m.Deleted), &record.Deleted); err != nil {
+		record.Deleted = nil
+	}
+	if err := json.Unmarshal([]byte(m.Anonymized), &record.Anonymized); err != nil {
+		record.Anonymized = nil
 	}
+	return record
 }
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrEmailVerificationTokenUsed は使用済み・期限切れのトークンを使おうとした場合のエラー
+	ErrEmailVerificationTokenUsed = errors.New("email verification token already used")
+	// ErrEmailTaken はアドレスが既に他のユーザーに使われている（一意制約に違反する）場合のエラー
+	ErrEmailTaken = domain.ErrEmailTaken
+)
+
+type EmailVerificationRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailVerificationRepository(db *gorm.DB) *EmailVerificationRepository {
+	return &EmailVerificationRepository{db: db}
+}
+
+// Create inserts a new verification token and invalidates the user's previous ones for the same purpose.
+// 今のアドレスの確認とアドレス変更の確認は別々に扱い、一方を送り直してももう一方のリンクは使えるままにする
+func (r *EmailVerificationRepository) Create(token *domain.EmailVerificationToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Unscoped().Select("email").Where("id = ?", token.UserID).First(&user).Error; err != nil {
+			return err
+		}
+
+		// 同じ用途では最新のリンクだけを有効にする
+		previous := tx.Model(&EmailVerificationToken{}).Where("user_id = ? AND used_at IS NULL", token.UserID)
+		if domain.NormalizeEmail(token.Email) == user.Email {
+			previous = previous.Where("email = ?", user.Email)
+		} else {
+			previous = previous.Where("email <> ?", user.Email)
+		}
+		if err := previous.Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		model := ToEmailVerificationTokenModel(token)
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		token.ID = model.ID
+		token.CreatedAt = model.CreatedAt
+		return nil
+	})
+}
+
+// FindByHash finds a verification token by its hash
+func (r *EmailVerificationRepository) FindByHash(hash string) (*domain.EmailVerificationToken, error) {
+	var model EmailVerificationToken
+	if err := r.db.Where("token_hash = ?", hash).First(&model).Error; err != nil {
+		return nil, err
+	}
+	return ToDomainEmailVerificationToken(&model), nil
+}
+
+// Consume marks the token as used and sets the user's email as verified in one transaction.
+// メールアドレス変更の確認であれば、このタイミングで新しいアドレスに切り替わる
+func (r *EmailVerificationRepository) Consume(token *domain.EmailVerificationToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&EmailVerificationToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrEmailVerificationTokenUsed
+		}
+
+		email := domain.NormalizeEmail(token.Email)
+		var count int64
+		if err := tx.Model(&User{}).
+			Where("email = ? AND id <> ?", email, token.UserID).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return ErrEmailTaken
+		}
+
+		err := tx.Model(&User{}).
+			Where("id = ?", token.UserID).
+			Updates(map[string]interface{}{
+				"email":             email,
+				"email_verified_at": now,
+				"updated_at":        now,
+				"version":           nextVersion,
+			}).Error
+		if isUniqueViolation(err) {
+			// 論理削除済みのユーザーが使っているアドレスも一意制約にかかる
+			return ErrEmailTaken
+		}
+		return err
+	})
+}
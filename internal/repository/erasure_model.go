@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+// ErasureRecord はユーザーのデータを消去した記録。テーブルごとの行数は JSON 文字列で保存する
+type ErasureRecord struct {
+	ID          uint `gorm:"primaryKey;autoIncrement"`
+	UserID      uint `gorm:"index"`
+	RequestedBy *uint
+	Reason      string    `gorm:"type:text"`
+	Deleted     string    `gorm:"type:text"`
+	Anonymized  string    `gorm:"type:text"`
+	ErasedAt    time.Time `gorm:"index"`
+}
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"gorm.io/gorm"
+)
+
+type ErasureRepository struct {
+	db *gorm.DB
+}
+
+func NewErasureRepository(db *gorm.DB) *ErasureRepository {
+	return &ErasureRepository{db: db}
+}
+
+// Erase removes the personal data of the user from every table in one transaction and stores the erasure record.
+// ユーザーの行（論理削除済みを含む）と紐づくトークン・MFA などの行は物理削除する。
+// 監査ログは行を残して「いつ・どの ID に・何をしたか」を保ち、本人の IP・User-Agent と、変更前後の値を匿名化する。
+// 消去した時点より前に発行したアクセストークンは使えないよう、失効の記録（ユーザー ID と日時のみ）を作り直す。
+// ユーザーが存在しなければ domain.ErrUserNotFound を返す
+func (r *ErasureRepository) Erase(userID uint, record *domain.ErasureRecord) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user User
+		if err := tx.Unscoped().Where("id = ?", userID).First(&user).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return domain.ErrUserNotFound
+			}
+			return err
+		}
+
+		now := time.Now()
+		record.UserID = userID
+		record.ErasedAt = now
+		record.Deleted = map[string]int64{}
+		record.Anonymized = map[string]int64{}
+
+		for _, model := range userRelatedModels() {
+			result := tx.Where("user_id = ?", userID).Delete(model)
+			if result.Error != nil {
+				return result.Error
+			}
+			table, err := tableName(tx, model)
+			if err != nil {
+				return err
+			}
+			record.Deleted[table] = result.RowsAffected
+		}
+
+		anonymized, err := anonymizeAuditLogs(tx, userID, user.Email)
+		if err != nil {
+			return err
+		}
+		record.Anonymized["audit_logs"] = anonymized
+
+		result := tx.Unscoped().Where("id = ?", userID).Delete(&User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		record.Deleted["users"] = result.RowsAffected
+
+		if err := tx.Create(&UserTokenRevocation{UserID: userID, RevokedBefore: now}).Error; err != nil {
+			return err
+		}
+
+		model, err := ToErasureRecordModel(record)
+		if err != nil {
+			return err
+		}
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		record.ID = model.ID
+		return nil
+	})
+}
+
+// anonymizeAuditLogs はユーザーが操作した、または操作対象になった監査ログを匿名化し、その行数を返す。
+// 本人が操作した行は IP と User-Agent を消し、本人が対象の行は変更前後の値を ErasedValue に置き換える（項目名は残す）。
+// 未登録の扱いになるログイン失敗（対象が無い）も、本人のメールアドレスが記録されていれば同様に匿名化する
+func anonymizeAuditLogs(tx *gorm.DB, userID uint, email string) (int64, error) {
+	var count int64
+	var logs []AuditLog
+	err := tx.Where("actor_id = ? OR target_id = ?", userID, userID).
+		FindInBatches(&logs, 500, func(_ *gorm.DB, _ int) error {
+			for i := range logs {
+				log := &logs[i]
+				updates := map[string]interface{}{}
+				if log.ActorID != nil && *log.ActorID == userID {
+					updates["ip"] = ""
+					updates["user_agent"] = ""
+				}
+				if log.TargetID != nil && *log.TargetID == userID {
+					updates["before"] = eraseChanges(log.Before)
+					updates["after"] = eraseChanges(log.After)
+				}
+				if err := tx.Model(&AuditLog{}).Where("id = ?", log.ID).Updates(updates).Error; err != nil {
+					return err
+				}
+				count++
+			}
+			return nil
+		}).Error
+	if err != nil {
+		return 0, err
+	}
+
+	// LIKE で候補を絞ってから、メールアドレスが一致する行だけを匿名化する
+	var failed []AuditLog
+	if err := tx.Where("action = ? AND target_id IS NULL AND LOWER(after) LIKE ?", domain.AuditLoginFailed, "%"+email+"%").
+		Find(&failed).Error; err != nil {
+		return 0, err
+	}
+	for _, log := range failed {
+		attempted, _ := unmarshalChanges(log.After)["email"].(string)
+		if domain.NormalizeEmail(attempted) != email {
+			continue
+		}
+		if err := tx.Model(&AuditLog{}).Where("id = ?", log.ID).Updates(map[string]interface{}{
+			"ip":         "",
+			"user_agent": "",
+			"after":      eraseChanges(log.After),
+		}).Error; err != nil {
+			return 0, err
+		}
+		count++
+	}
+	return count, nil
+}
+
+// eraseChanges は変更前後の値（JSON）の値をすべて domain.ErasedValue に置き換える
+func eraseChanges(s string) string {
+	changes := unmarshalChanges(s)
+	if len(changes) == 0 {
+		return s
+	}
+	for k := range changes {
+		changes[k] = domain.ErasedValue
+	}
+	b, err := json.Marshal(changes)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func tableName(db *gorm.DB, model interface{}) (string, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return "", err
+	}
+	return stmt.Schema.Table, nil
+}
+
+// Find returns erasure records, newest first. userID を指定するとそのユーザーの記録だけを返す
+func (r *ErasureRepository) Find(userID *uint, offset, limit int) ([]*domain.ErasureRecord, error) {
+	query := r.db.Model(&ErasureRecord{})
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+	var models []ErasureRecord
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+	records := make([]*domain.ErasureRecord, 0, len(models))
+	for i := range models {
+		records = append(records, ToDomainErasureRecord(&models[i]))
+	}
+	return records, nil
+}
@@ -0,0 +1,94 @@
+package repository_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func TestErasureRepository_Erase(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		userRepo := repository.NewUserRepository(db)
+		auditRepo := repository.NewAuditRepository(db)
+		repo := repository.NewErasureRepository(db)
+
+		user := &domain.User{Name: "Erased", Email: "erased_1@example.com", Password: "secure123"}
+		other := &domain.User{Name: "Other", Email: "other@example.com", Password: "secure123"}
+		for _, u := range []*domain.User{user, other} {
+			require.NoError(t, userRepo.Create(u))
+		}
+		require.NoError(t, db.Create(&repository.RefreshToken{
+			UserID: user.ID, TokenHash: "hash", FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour),
+		}).Error)
+
+		events := []*domain.AuditEvent{
+			// 本人の操作
+			{ActorID: &user.ID, Action: domain.AuditLogin, TargetID: &user.ID, IP: "192.0.2.1", UserAgent: "ua",
+				After: map[string]interface{}{"email": user.Email}},
+			// 本人が対象の、他のユーザーの操作
+			{ActorID: &other.ID, Action: domain.AuditUserUpdated, TargetID: &user.ID, IP: "192.0.2.2", UserAgent: "admin-ua",
+				Before: map[string]interface{}{"name": "Old"}, After: map[string]interface{}{"name": user.Name}},
+			// 未登録の扱いになったログイン失敗
+			{Action: domain.AuditLoginFailed, IP: "192.0.2.3", After: map[string]interface{}{"email": " Erased_1@Example.com"}},
+			// 似たアドレス（"_" は LIKE で任意の1文字に一致する）は対象外
+			{Action: domain.AuditLoginFailed, IP: "192.0.2.4", After: map[string]interface{}{"email": "erasedx1@example.com"}},
+			// 関係のない記録
+			{ActorID: &other.ID, Action: domain.AuditLogin, TargetID: &other.ID, IP: "192.0.2.5"},
+		}
+		for _, e := range events {
+			require.NoError(t, auditRepo.Create(e))
+		}
+
+		require.NoError(t, userRepo.Delete(user.ID, user.Version))
+		record := &domain.ErasureRecord{RequestedBy: &other.ID, Reason: "ticket-1"}
+		require.NoError(t, repo.Erase(user.ID, record))
+		assert.NotZero(t, record.ID)
+		assert.Equal(t, int64(1), record.Deleted["users"], "soft-deleted user is erased")
+		assert.Equal(t, int64(1), record.Deleted["refresh_tokens"])
+		assert.Equal(t, int64(3), record.Anonymized["audit_logs"])
+
+		_, err := userRepo.FindDeletedByID(user.ID)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+
+		// 監査ログの行は残り、個人データだけが消えている
+		logs, err := auditRepo.Find(domain.AuditFilter{}, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, logs, len(events))
+		byIP := map[string]*domain.AuditEvent{}
+		for _, e := range logs {
+			byIP[e.IP] = e
+		}
+		assert.Equal(t, map[string]interface{}{"name": domain.ErasedValue}, byIP["192.0.2.2"].After, "other actor's IP is kept")
+		assert.Equal(t, map[string]interface{}{"name": domain.ErasedValue}, byIP["192.0.2.2"].Before)
+		assert.Equal(t, "erasedx1@example.com", byIP["192.0.2.4"].After["email"])
+		assert.NotNil(t, byIP["192.0.2.5"])
+		assert.Nil(t, byIP["192.0.2.1"])
+		assert.Nil(t, byIP["192.0.2.3"])
+		for _, e := range logs {
+			if e.IP == "" {
+				assert.Equal(t, domain.ErasedValue, e.After["email"])
+				assert.Empty(t, e.UserAgent)
+			}
+		}
+
+		// 消去より前に発行したアクセストークンは使えない
+		revoked, err := repository.NewRevocationRepository(db).IsRevoked("jti", user.ID, time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.True(t, revoked)
+
+		records, err := repo.Find(&user.ID, 0, 10)
+		require.NoError(t, err)
+		require.Len(t, records, 1)
+		assert.Equal(t, "ticket-1", records[0].Reason)
+		assert.Equal(t, &other.ID, records[0].RequestedBy)
+		assert.Equal(t, record.Deleted, records[0].Deleted)
+
+		assert.ErrorIs(t, repo.Erase(user.ID, &domain.ErasureRecord{Reason: "again"}), domain.ErrUserNotFound)
+	})
+}
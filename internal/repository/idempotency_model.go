@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+// IdempotencyKey は Idempotency-Key ごとに保存したレスポンス
+type IdempotencyKey struct {
+	KeyHash     string `gorm:"primaryKey;size:64"`
+	Fingerprint string `gorm:"size:64"`
+	StatusCode  int
+	Header      string `gorm:"type:text"` // レスポンスヘッダーの JSON
+	Body        []byte
+	ExpiresAt   time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
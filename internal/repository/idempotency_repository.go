@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"gorm.io/gorm"
+)
+
+type IdempotencyRepository struct {
+	db *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) *IdempotencyRepository {
+	return &IdempotencyRepository{db: db}
+}
+
+// Begin reserves the key of record as in progress.
+// 有効期限内の記録が既にあれば予約せずにその記録を返し、無ければ nil を返す。
+// 同じキーで同時に予約しても、予約できるのは1つだけ
+func (r *IdempotencyRepository) Begin(record *domain.IdempotencyRecord) (*domain.IdempotencyRecord, error) {
+	// 期限切れの記録（終わらなかった処理や保存期間を過ぎたレスポンス）は新しいリクエストで置き換える
+	if err := r.db.Where("key_hash = ? AND expires_at <= ?", record.KeyHash, time.Now()).
+		Delete(&IdempotencyKey{}).Error; err != nil {
+		return nil, err
+	}
+
+	model, err := ToIdempotencyKeyModel(record)
+	if err != nil {
+		return nil, err
+	}
+	err = r.db.Create(model).Error
+	if err == nil {
+		record.CreatedAt = model.CreatedAt
+		return nil, nil
+	}
+	if !isUniqueViolation(err) {
+		return nil, err
+	}
+
+	var existing IdempotencyKey
+	if err := r.db.Where("key_hash = ?", record.KeyHash).First(&existing).Error; err != nil {
+		return nil, err
+	}
+	return ToDomainIdempotencyRecord(&existing), nil
+}
+
+// Complete stores the response of a reserved key until record.ExpiresAt
+func (r *IdempotencyRepository) Complete(record *domain.IdempotencyRecord) error {
+	model, err := ToIdempotencyKeyModel(record)
+	if err != nil {
+		return err
+	}
+	return r.db.Model(&IdempotencyKey{}).
+		Where("key_hash = ?", record.KeyHash).
+		Updates(map[string]interface{}{
+			"status_code": model.StatusCode,
+			"header":      model.Header,
+			"body":        model.Body,
+			"expires_at":  model.ExpiresAt,
+		}).Error
+}
+
+// Release deletes a key that is still in progress so that the request can be retried
+func (r *IdempotencyRepository) Release(keyHash string) error {
+	return r.db.Where("key_hash = ? AND status_code = 0", keyHash).Delete(&IdempotencyKey{}).Error
+}
+
+// DeleteExpired removes keys whose responses are no longer kept and returns how many were deleted
+func (r *IdempotencyRepository) DeleteExpired(now time.Time) (int64, error) {
+	result := r.db.Where("expires_at <= ?", now).Delete(&IdempotencyKey{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,338 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+)
+
+// MemoryUserStore は domain.UserStore のメモリ上の実装（テストや DB を使わない動作確認用）。
+// 複数のゴルーチンから使えるよう sync.RWMutex で排他制御する。
+// ロック情報がコピーされないよう、必ず NewMemoryUserStore で作ったポインタで扱うこと。
+type MemoryUserStore struct {
+	mu     sync.RWMutex
+	users  map[uint]*domain.User
+	nextID uint
+}
+
+var _ domain.UserStore = (*MemoryUserStore)(nil)
+
+func NewMemoryUserStore() *MemoryUserStore {
+	return &MemoryUserStore{users: map[uint]*domain.User{}, nextID: 1}
+}
+
+func (s *MemoryUserStore) FindAll(query domain.UserListQuery) ([]*domain.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := s.listUsers(query.Filter, query.Sort)
+	var users []*domain.User
+	for i := query.Offset; i < len(matched) && len(users) < query.Limit; i++ {
+		users = append(users, listedUser(matched[i]))
+	}
+	return users, nil
+}
+
+func (s *MemoryUserStore) FindPage(query domain.UserPageQuery) ([]*domain.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	backward := query.After == nil && query.Before != nil
+	var matched []*domain.User
+	for _, u := range s.listUsers(query.Filter, query.Sort) {
+		key := domain.KeyOf(u)
+		if query.After != nil && !query.After.Less(key, query.Sort) {
+			continue
+		}
+		if backward && !key.Less(*query.Before, query.Sort) {
+			continue
+		}
+		matched = append(matched, u)
+	}
+	if len(matched) > query.Limit {
+		if backward {
+			// 位置の直前の Limit 件を返す
+			matched = matched[len(matched)-query.Limit:]
+		} else {
+			matched = matched[:query.Limit]
+		}
+	}
+
+	users := make([]*domain.User, 0, len(matched))
+	for _, u := range matched {
+		users = append(users, listedUser(u))
+	}
+	return users, nil
+}
+
+func (s *MemoryUserStore) Count(filter domain.UserFilter) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var count int64
+	for _, u := range s.activeUsers() {
+		if filter.Match(u) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (s *MemoryUserStore) Search(terms []string, limit int) ([]*domain.UserSearchHit, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(terms) == 0 {
+		return []*domain.UserSearchHit{}, nil
+	}
+	return rankUsers(s.activeUsers(), terms, limit), nil
+}
+
+func (s *MemoryUserStore) Create(user *domain.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email := domain.NormalizeEmail(user.Email)
+	if s.emailTaken(email, 0) {
+		return domain.ErrEmailTaken
+	}
+
+	now := time.Now()
+	stored := &domain.User{
+		ID:        s.nextID,
+		Name:      user.Name,
+		Email:     email,
+		Password:  user.Password,
+		Role:      user.Role,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Version:   1,
+	}
+	if stored.Role == "" {
+		stored.Role = domain.RoleMember
+	}
+	s.users[stored.ID] = stored
+	s.nextID++
+
+	user.ID = stored.ID
+	user.Email = stored.Email
+	user.CreatedAt = stored.CreatedAt
+	user.UpdatedAt = stored.UpdatedAt
+	user.Role = stored.Role
+	user.Version = stored.Version
+	return nil
+}
+
+// CreateBatch はユーザーを順に登録し、ユーザーごとのエラーを返す
+func (s *MemoryUserStore) CreateBatch(users []*domain.User) ([]error, error) {
+	errs := make([]error, len(users))
+	for i, user := range users {
+		errs[i] = s.Create(user)
+	}
+	return errs, nil
+}
+
+func (s *MemoryUserStore) FindByEmail(email string) (*domain.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	email = domain.NormalizeEmail(email)
+	for _, u := range s.activeUsers() {
+		if u.Email == email {
+			return copyUser(u), nil
+		}
+	}
+	return nil, domain.ErrUserNotFound
+}
+
+func (s *MemoryUserStore) FindByID(id uint) (*domain.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok || u.DeletedAt != nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return copyUser(u), nil
+}
+
+func (s *MemoryUserStore) Update(user *domain.User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[user.ID]
+	if !ok || stored.DeletedAt != nil {
+		return domain.ErrUserNotFound
+	}
+	if stored.Version != user.Version {
+		return domain.ErrVersionMismatch
+	}
+	email := domain.NormalizeEmail(user.Email)
+	if s.emailTaken(email, user.ID) {
+		return domain.ErrEmailTaken
+	}
+
+	stored.Name = user.Name
+	stored.Email = email
+	stored.Password = user.Password
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	user.Email = stored.Email
+	user.UpdatedAt = stored.UpdatedAt
+	user.Version = stored.Version
+	return nil
+}
+
+func (s *MemoryUserStore) Patch(id uint, version uint, patch domain.UserPatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.DeletedAt != nil {
+		return domain.ErrUserNotFound
+	}
+	if stored.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	if patch.Email != nil {
+		email := domain.NormalizeEmail(*patch.Email)
+		if s.emailTaken(email, id) {
+			return domain.ErrEmailTaken
+		}
+		stored.Email = email
+	}
+	if patch.Name != nil {
+		stored.Name = *patch.Name
+	}
+	if patch.Password != nil {
+		stored.Password = *patch.Password
+	}
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	return nil
+}
+
+func (s *MemoryUserStore) UpdateRole(id uint, role domain.Role) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.DeletedAt != nil {
+		return domain.ErrUserNotFound
+	}
+	stored.Role = role
+	stored.UpdatedAt = time.Now()
+	stored.Version++
+	return nil
+}
+
+func (s *MemoryUserStore) Delete(id uint, version uint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.DeletedAt != nil {
+		return domain.ErrUserNotFound
+	}
+	if stored.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	now := time.Now()
+	stored.DeletedAt = &now
+	stored.Version++
+	return nil
+}
+
+func (s *MemoryUserStore) FindDeletedByID(id uint) (*domain.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, ok := s.users[id]
+	if !ok || u.DeletedAt == nil {
+		return nil, domain.ErrUserNotFound
+	}
+	return copyUser(u), nil
+}
+
+func (s *MemoryUserStore) Restore(id uint, deletedAfter time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.users[id]
+	if !ok || stored.DeletedAt == nil || !stored.DeletedAt.After(deletedAfter) {
+		return domain.ErrUserNotFound
+	}
+	stored.DeletedAt = nil
+	stored.Version++
+	return nil
+}
+
+func (s *MemoryUserStore) Purge(deletedBefore time.Time) ([]uint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var purged []uint
+	for id, u := range s.users {
+		if u.DeletedAt != nil && u.DeletedAt.Before(deletedBefore) {
+			delete(s.users, id)
+			purged = append(purged, id)
+		}
+	}
+	return purged, nil
+}
+
+// activeUsers は論理削除されていないユーザーを返す（呼び出し側でロックを取ること）
+func (s *MemoryUserStore) activeUsers() []*domain.User {
+	users := make([]*domain.User, 0, len(s.users))
+	for _, u := range s.users {
+		if u.DeletedAt == nil {
+			users = append(users, u)
+		}
+	}
+	return users
+}
+
+// listUsers は条件に合う論理削除されていないユーザーを並び順に返す（呼び出し側でロックを取ること）
+func (s *MemoryUserStore) listUsers(filter domain.UserFilter, order domain.UserSort) []*domain.User {
+	var users []*domain.User
+	for _, u := range s.activeUsers() {
+		if filter.Match(u) {
+			users = append(users, u)
+		}
+	}
+	sort.Slice(users, func(i, j int) bool { return domain.KeyOf(users[i]).Less(domain.KeyOf(users[j]), order) })
+	return users
+}
+
+// emailTaken は exceptID 以外のユーザー（論理削除済みを含む）がアドレスを使っているかを返す
+func (s *MemoryUserStore) emailTaken(email string, exceptID uint) bool {
+	for _, u := range s.users {
+		if u.Email == email && u.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// listedUser は一覧用にパスワードを除いたコピーを返す
+func listedUser(u *domain.User) *domain.User {
+	c := copyUser(u)
+	c.Password = ""
+	return c
+}
+
+// copyUser は保存しているユーザーが呼び出し側から書き換えられないようにコピーを返す
+func copyUser(u *domain.User) *domain.User {
+	c := *u
+	if u.EmailVerifiedAt != nil {
+		t := *u.EmailVerifiedAt
+		c.EmailVerifiedAt = &t
+	}
+	if u.DeletedAt != nil {
+		t := *u.DeletedAt
+		c.DeletedAt = &t
+	}
+	return &c
+}
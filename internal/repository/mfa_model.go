@@ -0,0 +1,23 @@
+package repository
+
+import "time"
+
+type MFACredential struct {
+	UserID       uint `gorm:"primaryKey"`
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep int64
+	// 続けてコードを間違えた回数と、コードを受け付けない期限
+	FailedAttempts int `gorm:"not null;default:0"`
+	LockedUntil    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// MFARecoveryCode は TOTP が使えない場合の一度きりのリカバリーコード（ハッシュのみ保存）
+type MFARecoveryCode struct {
+	ID       uint `gorm:"primaryKey;autoIncrement"`
+	UserID   uint `gorm:"index"`
+	CodeHash string
+	UsedAt   *time.Time
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type MFARepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) *MFARepository {
+	return &MFARepository{db: db}
+}
+
+// FindByUserID finds the MFA credential of the user
+func (r *MFARepository) FindByUserID(userID uint) (*domain.MFACredential, error) {
+	var model MFACredential
+	if err := r.db.Where("user_id = ?", userID).First(&model).Error; err != nil {
+		return nil, err
+	}
+	return ToDomainMFACredential(&model), nil
+}
+
+// SavePending stores a new, not yet enabled secret for the user (replacing any pending one)
+func (r *MFARepository) SavePending(userID uint, secret string) error {
+	model := MFACredential{
+		UserID: userID,
+		Secret: secret,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.Assignments(map[string]interface{}{"secret": secret, "enabled_at": nil, "last_used_step": 0, "failed_attempts": 0, "locked_until": nil, "updated_at": time.Now()}),
+	}).Create(&model).Error
+}
+
+// Enable enables MFA and replaces the user's recovery codes in one transaction
+func (r *MFARepository) Enable(userID uint, step int64, recoveryCodeHashes []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&MFACredential{}).
+			Where("user_id = ?", userID).
+			Updates(map[string]interface{}{"enabled_at": time.Now(), "last_used_step": step}).Error; err != nil {
+			return err
+		}
+		return replaceRecoveryCodes(tx, userID, recoveryCodeHashes)
+	})
+}
+
+// UseStep records the time step of an accepted code.
+// 既に同じかそれ以降のステップのコードが使われていれば false を返す（リプレイ対策）
+func (r *MFARepository) UseStep(userID uint, step int64) (bool, error) {
+	result := r.db.Model(&MFACredential{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RecordFailure counts a wrong code and reports whether the credential has been locked.
+// 続けて maxAttempts 回間違えると lockout の間コードを受け付けないようにし、回数を数え直す
+func (r *MFARepository) RecordFailure(userID uint, maxAttempts int, lockout time.Duration) (bool, error) {
+	locked := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&MFACredential{}).
+			Where("user_id = ?", userID).
+			Update("failed_attempts", gorm.Expr("failed_attempts + 1")).Error; err != nil {
+			return err
+		}
+		result := tx.Model(&MFACredential{}).
+			Where("user_id = ? AND failed_attempts >= ?", userID, maxAttempts).
+			Updates(map[string]interface{}{"failed_attempts": 0, "locked_until": time.Now().Add(lockout)})
+		locked = result.RowsAffected > 0
+		return result.Error
+	})
+	return locked, err
+}
+
+// ResetFailures clears the count of wrong codes after a correct one
+func (r *MFARepository) ResetFailures(userID uint) error {
+	return r.db.Model(&MFACredential{}).
+		Where("user_id = ? AND failed_attempts > 0", userID).
+		Update("failed_attempts", 0).Error
+}
+
+// UseRecoveryCode marks an unused recovery code as used
+func (r *MFARepository) UseRecoveryCode(userID uint, codeHash string) (bool, error) {
+	result := r.db.Model(&MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// Disable removes the MFA credential and recovery codes of the user
+func (r *MFARepository) Disable(userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&MFACredential{}).Error
+	})
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, userID uint, hashes []string) error {
+	if err := tx.Where("user_id = ?", userID).Delete(&MFARecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]MFARecoveryCode, 0, len(hashes))
+	for _, h := range hashes {
+		codes = append(codes, MFARecoveryCode{UserID: userID, CodeHash: h})
+	}
+	if len(codes) == 0 {
+		return nil
+	}
+	return tx.Create(&codes).Error
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrPasswordResetTokenUsed は使用済み・期限切れのトークンを使おうとした場合のエラー
+var ErrPasswordResetTokenUsed = errors.New("password reset token already used")
+
+type PasswordResetRepository struct {
+	db *gorm.DB
+}
+
+func NewPasswordResetRepository(db *gorm.DB) *PasswordResetRepository {
+	return &PasswordResetRepository{db: db}
+}
+
+// Create inserts a new reset token and invalidates the user's previous ones
+func (r *PasswordResetRepository) Create(token *domain.PasswordResetToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// 最新のリンクだけを有効にする
+		if err := tx.Model(&PasswordResetToken{}).
+			Where("user_id = ? AND used_at IS NULL", token.UserID).
+			Update("used_at", time.Now()).Error; err != nil {
+			return err
+		}
+
+		model := ToPasswordResetTokenModel(token)
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		token.ID = model.ID
+		token.CreatedAt = model.CreatedAt
+		return nil
+	})
+}
+
+// FindByHash finds a reset token by its hash
+func (r *PasswordResetRepository) FindByHash(hash string) (*domain.PasswordResetToken, error) {
+	var model PasswordResetToken
+	if err := r.db.Where("token_hash = ?", hash).First(&model).Error; err != nil {
+		return nil, err
+	}
+	return ToDomainPasswordResetToken(&model), nil
+}
+
+// Consume marks the token as used, sets the user's new password hash and revokes the user's sessions in one transaction.
+// 同じトークンで同時にリクエストされても、パスワードが更新されるのは一度だけ。
+// revokeBefore 以前に発行したアクセストークンと、すべてのリフレッシュトークンを失効させる
+func (r *PasswordResetRepository) Consume(token *domain.PasswordResetToken, hashedPassword string, revokeBefore time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&PasswordResetToken{}).
+			Where("id = ? AND used_at IS NULL AND expires_at > ?", token.ID, now).
+			Update("used_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrPasswordResetTokenUsed
+		}
+
+		if err := tx.Model(&User{}).
+			Where("id = ?", token.UserID).
+			Updates(map[string]interface{}{"password": hashedPassword, "updated_at": now, "version": nextVersion}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+		}).Create(&UserTokenRevocation{UserID: token.UserID, RevokedBefore: revokeBefore}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&RefreshToken{}).
+			Where("user_id = ? AND revoked_at IS NULL", token.UserID).
+			Update("revoked_at", now).Error
+	})
+}
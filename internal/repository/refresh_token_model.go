@@ -0,0 +1,14 @@
+package repository
+
+import "time"
+
+type RefreshToken struct {
+	ID           uint   `gorm:"primaryKey;autoIncrement"`
+	UserID       uint   `gorm:"index"`
+	FamilyID     string `gorm:"index"`
+	TokenHash    string `gorm:"uniqueIndex"`
+	ExpiresAt    time.Time
+	RevokedAt    *time.Time
+	ReplacedByID *uint
+	CreatedAt    time.Time
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"gorm.io/gorm"
+)
+
+// ErrRefreshTokenRevoked はローテーション対象のトークンが既に失効していた場合のエラー
+var ErrRefreshTokenRevoked = errors.New("refresh token already revoked")
+
+type RefreshTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenRepository(db *gorm.DB) *RefreshTokenRepository {
+	return &RefreshTokenRepository{db: db}
+}
+
+// Create inserts a new refresh token
+func (r *RefreshTokenRepository) Create(token *domain.RefreshToken) error {
+	model := ToRefreshTokenModel(token)
+	if err := r.db.Create(model).Error; err != nil {
+		return err
+	}
+	token.ID = model.ID
+	token.CreatedAt = model.CreatedAt
+	return nil
+}
+
+// FindByHash finds a refresh token by its hash
+func (r *RefreshTokenRepository) FindByHash(hash string) (*domain.RefreshToken, error) {
+	var model RefreshToken
+	if err := r.db.Where("token_hash = ?", hash).First(&model).Error; err != nil {
+		return nil, err
+	}
+	return ToDomainRefreshToken(&model), nil
+}
+
+// Rotate revokes the current token and stores its replacement in one transaction.
+// 同じトークンで同時にリフレッシュされた場合、片方だけが成功する
+func (r *RefreshTokenRepository) Rotate(current, next *domain.RefreshToken) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", current.ID).
+			Update("revoked_at", now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrRefreshTokenRevoked
+		}
+
+		model := ToRefreshTokenModel(next)
+		if err := tx.Create(model).Error; err != nil {
+			return err
+		}
+		next.ID = model.ID
+		next.CreatedAt = model.CreatedAt
+
+		current.RevokedAt = &now
+		current.ReplacedByID = &model.ID
+		return tx.Model(&RefreshToken{}).
+			Where("id = ?", current.ID).
+			Update("replaced_by_id", model.ID).Error
+	})
+}
+
+// RevokeFamily revokes every active token that belongs to the family
+func (r *RefreshTokenRepository) RevokeFamily(familyID string) error {
+	return r.db.Model(&RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// RevokeAllForUser revokes every active refresh token of the user
+func (r *RefreshTokenRepository) RevokeAllForUser(userID uint) error {
+	return r.db.Model(&RefreshToken{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", time.Now()).Error
+}
+
+// FindByUser returns every refresh token of the user (including revoked and expired ones), oldest first
+func (r *RefreshTokenRepository) FindByUser(userID uint) ([]*domain.RefreshToken, error) {
+	var models []RefreshToken
+	if err := r.db.Where("user_id = ?", userID).Order("id").Find(&models).Error; err != nil {
+		return nil, err
+	}
+	tokens := make([]*domain.RefreshToken, 0, len(models))
+	for i := range models {
+		tokens = append(tokens, ToDomainRefreshToken(&models[i]))
+	}
+	return tokens, nil
+}
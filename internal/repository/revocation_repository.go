@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevocationRepository struct {
+	db *gorm.DB
+}
+
+func NewRevocationRepository(db *gorm.DB) *RevocationRepository {
+	return &RevocationRepository{db: db}
+}
+
+// RevokeToken stores the jti of an access token until it expires
+func (r *RevocationRepository) RevokeToken(jti string, userID uint, expiresAt time.Time) error {
+	model := RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model).Error
+}
+
+// ConsumeToken records the jti of a single-use token until it expires and reports whether this is its first use.
+// 同じ jti で同時に使われても、一意制約により true になるのは1回だけ
+func (r *RevocationRepository) ConsumeToken(jti string, userID uint, expiresAt time.Time) (bool, error) {
+	model := RevokedToken{
+		JTI:       jti,
+		UserID:    userID,
+		ExpiresAt: expiresAt,
+	}
+	result := r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RevokeAllForUser invalidates every token of the user issued at or before the given time
+func (r *RevocationRepository) RevokeAllForUser(userID uint, before time.Time) error {
+	model := UserTokenRevocation{
+		UserID:        userID,
+		RevokedBefore: before,
+	}
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"revoked_before", "updated_at"}),
+	}).Create(&model).Error
+}
+
+// IsRevoked reports whether the token identified by jti, user and issue time has been revoked
+func (r *RevocationRepository) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	var count int64
+	if err := r.db.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, err
+	}
+	if count > 0 {
+		return true, nil
+	}
+
+	var revocation UserTokenRevocation
+	err := r.db.Where("user_id = ?", userID).First(&revocation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return !issuedAt.After(revocation.RevokedBefore), nil
+}
+
+// DeleteExpired removes revoked jti entries whose tokens have already expired
+func (r *RevocationRepository) DeleteExpired(now time.Time) error {
+	return r.db.Where("expires_at < ?", now).Delete(&RevokedToken{}).Error
+}
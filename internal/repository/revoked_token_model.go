@@ -0,0 +1,19 @@
+package repository
+
+import "time"
+
+// RevokedToken は個別に失効させたアクセストークン（jti単位）
+type RevokedToken struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	JTI       string `gorm:"uniqueIndex"`
+	UserID    uint   `gorm:"index"`
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
+
+// UserTokenRevocation はユーザー単位の一括失効（これ以前に発行されたトークンは無効）
+type UserTokenRevocation struct {
+	UserID        uint `gorm:"primaryKey"`
+	RevokedBefore time.Time
+	UpdatedAt     time.Time
+}
@@ -0,0 +1,531 @@
+// Package storetest は domain.UserStore の実装が満たすべき振る舞いを検証する共通のテストスイート
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RunUserStore は newStore で作った空のストアに対して、サブテストごとにスイートを実行する
+func RunUserStore(t *testing.T, newStore func(t *testing.T) domain.UserStore) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store domain.UserStore)
+	}{
+		{"CreateAndFind", testCreateAndFind},
+		{"DuplicateEmail", testDuplicateEmail},
+		{"CreateBatch", testCreateBatch},
+		{"Update", testUpdate},
+		{"Patch", testPatch},
+		{"UpdateRole", testUpdateRole},
+		{"FindAll", testFindAll},
+		{"FindPage", testFindPage},
+		{"FilterAndSort", testFilterAndSort},
+		{"FindPageSorted", testFindPageSorted},
+		{"Search", testSearch},
+		{"Version", testVersion},
+		{"DeleteAndRestore", testDeleteAndRestore},
+		{"Purge", testPurge},
+		{"ReturnsCopies", testReturnsCopies},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.fn(t, newStore(t))
+		})
+	}
+}
+
+func newUser(name string) *domain.User {
+	return &domain.User{Name: name, Email: name + "@example.com", Password: "hashed-" + name}
+}
+
+func testCreateAndFind(t *testing.T, store domain.UserStore) {
+	user := newUser("alice")
+	require.NoError(t, store.Create(user))
+	assert.NotZero(t, user.ID)
+	assert.Equal(t, domain.RoleMember, user.Role, "default role")
+	assert.False(t, user.CreatedAt.IsZero())
+
+	byID, err := store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", byID.Name)
+	assert.Equal(t, "alice@example.com", byID.Email)
+	assert.Equal(t, "hashed-alice", byID.Password)
+	assert.Nil(t, byID.DeletedAt)
+
+	byEmail, err := store.FindByEmail("alice@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, byEmail.ID)
+
+	admin := newUser("root")
+	admin.Role = domain.RoleAdmin
+	require.NoError(t, store.Create(admin))
+	assert.NotEqual(t, user.ID, admin.ID)
+	assert.Equal(t, domain.RoleAdmin, admin.Role)
+
+	_, err = store.FindByID(9999)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	_, err = store.FindByEmail("nobody@example.com")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
+
+func testDuplicateEmail(t *testing.T, store domain.UserStore) {
+	alice := newUser("alice")
+	bob := newUser("bob")
+	require.NoError(t, store.Create(alice))
+	require.NoError(t, store.Create(bob))
+
+	assert.ErrorIs(t, store.Create(newUser("alice")), domain.ErrEmailTaken)
+	// 大文字小文字や前後の空白だけが違うアドレスも同じアドレスとして扱う
+	shouting := newUser("alice")
+	shouting.Email = " ALICE@Example.com "
+	assert.ErrorIs(t, store.Create(shouting), domain.ErrEmailTaken)
+
+	found, err := store.FindByEmail("Alice@EXAMPLE.com")
+	require.NoError(t, err)
+	assert.Equal(t, alice.ID, found.ID)
+
+	bob.Email = alice.Email
+	assert.ErrorIs(t, store.Update(bob), domain.ErrEmailTaken)
+
+	bob.Email = "Alice@example.com"
+	assert.ErrorIs(t, store.Update(bob), domain.ErrEmailTaken)
+
+	found, err = store.FindByID(bob.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "bob@example.com", found.Email, "failed update leaves the user unchanged")
+
+	// 保存するアドレスは正規化される
+	carol := newUser("carol")
+	carol.Email = "  Carol@Example.com"
+	require.NoError(t, store.Create(carol))
+	assert.Equal(t, "carol@example.com", carol.Email)
+}
+
+func testCreateBatch(t *testing.T, store domain.UserStore) {
+	require.NoError(t, store.Create(newUser("alice")))
+
+	// 重複するユーザーだけが登録できず、他のユーザーは登録される
+	users := []*domain.User{newUser("bob"), newUser("alice"), newUser("carol"), newUser("bob")}
+	users[2].Role = domain.RoleSupport
+	errs, err := store.CreateBatch(users)
+	require.NoError(t, err)
+	require.Len(t, errs, 4)
+	assert.NoError(t, errs[0])
+	assert.ErrorIs(t, errs[1], domain.ErrEmailTaken)
+	assert.NoError(t, errs[2])
+	assert.ErrorIs(t, errs[3], domain.ErrEmailTaken)
+
+	for _, i := range []int{0, 2} {
+		found, err := store.FindByID(users[i].ID)
+		require.NoError(t, err)
+		assert.Equal(t, users[i].Email, found.Email)
+		assert.Equal(t, users[i].Role, found.Role)
+	}
+	assert.Equal(t, domain.RoleMember, users[0].Role)
+
+	count, err := store.Count(domain.UserFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+}
+
+func testUpdate(t *testing.T, store domain.UserStore) {
+	user := newUser("alice")
+	require.NoError(t, store.Create(user))
+
+	user.Name = "Alice Liddell"
+	user.Email = "liddell@example.com"
+	user.Password = "new-hash"
+	require.NoError(t, store.Update(user))
+
+	found, err := store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Liddell", found.Name)
+	assert.Equal(t, "liddell@example.com", found.Email)
+	assert.Equal(t, "new-hash", found.Password)
+	assert.False(t, found.UpdatedAt.Before(found.CreatedAt))
+
+	assert.ErrorIs(t, store.Update(&domain.User{ID: 9999, Name: "x"}), domain.ErrUserNotFound)
+}
+
+func testPatch(t *testing.T, store domain.UserStore) {
+	user := newUser("alice")
+	require.NoError(t, store.Create(user))
+	require.NoError(t, store.Create(newUser("bob")))
+
+	// 指定したフィールドだけが変わり、他の列はそのまま残る
+	name := "Alice Liddell"
+	require.NoError(t, store.Patch(user.ID, user.Version, domain.UserPatch{Name: &name}))
+	found, err := store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, name, found.Name)
+	assert.Equal(t, user.Email, found.Email)
+	assert.Equal(t, user.Password, found.Password)
+	assert.Equal(t, user.Version+1, found.Version)
+
+	email, password := " Liddell@Example.com", "new-hash"
+	require.NoError(t, store.Patch(user.ID, found.Version, domain.UserPatch{Email: &email, Password: &password}))
+	found, err = store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, name, found.Name)
+	assert.Equal(t, "liddell@example.com", found.Email)
+	assert.Equal(t, "new-hash", found.Password)
+
+	taken := "BOB@example.com"
+	assert.ErrorIs(t, store.Patch(user.ID, found.Version, domain.UserPatch{Email: &taken}), domain.ErrEmailTaken)
+	assert.ErrorIs(t, store.Patch(user.ID, user.Version, domain.UserPatch{Name: &name}), domain.ErrVersionMismatch)
+	assert.ErrorIs(t, store.Patch(9999, 1, domain.UserPatch{Name: &name}), domain.ErrUserNotFound)
+}
+
+func testUpdateRole(t *testing.T, store domain.UserStore) {
+	user := newUser("alice")
+	require.NoError(t, store.Create(user))
+
+	require.NoError(t, store.UpdateRole(user.ID, domain.RoleSupport))
+	found, err := store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleSupport, found.Role)
+
+	assert.ErrorIs(t, store.UpdateRole(9999, domain.RoleAdmin), domain.ErrUserNotFound)
+}
+
+func testFindAll(t *testing.T, store domain.UserStore) {
+	var ids []uint
+	for _, name := range []string{"a", "b", "c", "d"} {
+		user := newUser(name)
+		require.NoError(t, store.Create(user))
+		ids = append(ids, user.ID)
+	}
+	deleteUser(t, store, ids[1])
+
+	users, err := store.FindAll(domain.UserListQuery{Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, users, 3, "deleted users are excluded")
+	assert.Equal(t, []uint{ids[0], ids[2], ids[3]}, []uint{users[0].ID, users[1].ID, users[2].ID})
+	for _, u := range users {
+		assert.Empty(t, u.Password, "password is not listed")
+	}
+
+	page, err := store.FindAll(domain.UserListQuery{Offset: 1, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page, 1)
+	assert.Equal(t, ids[2], page[0].ID)
+
+	empty, err := store.FindAll(domain.UserListQuery{Offset: 10, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func testFindPage(t *testing.T, store domain.UserStore) {
+	var ids []uint
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		user := newUser(name)
+		require.NoError(t, store.Create(user))
+		ids = append(ids, user.ID)
+	}
+	deleteUser(t, store, ids[2])
+
+	pageIDs := func(users []*domain.User) []uint {
+		result := []uint{}
+		for _, u := range users {
+			assert.Empty(t, u.Password, "password is not listed")
+			result = append(result, u.ID)
+		}
+		return result
+	}
+
+	first, err := store.FindPage(domain.UserPageQuery{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[0], ids[1]}, pageIDs(first))
+
+	// 論理削除されたユーザーは飛ばす
+	after := domain.KeyOf(first[1])
+	second, err := store.FindPage(domain.UserPageQuery{After: &after, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[3], ids[4]}, pageIDs(second))
+
+	last := domain.KeyOf(second[1])
+	end, err := store.FindPage(domain.UserPageQuery{After: &last, Limit: 2})
+	require.NoError(t, err)
+	assert.Empty(t, end)
+
+	// Before は位置の直前の件数を昇順で返す
+	before := domain.KeyOf(second[1])
+	prev, err := store.FindPage(domain.UserPageQuery{Before: &before, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[1], ids[3]}, pageIDs(prev))
+
+	head := domain.KeyOf(first[0])
+	none, err := store.FindPage(domain.UserPageQuery{Before: &head, Limit: 2})
+	require.NoError(t, err)
+	assert.Empty(t, none)
+}
+
+func testFilterAndSort(t *testing.T, store domain.UserStore) {
+	users := map[string]*domain.User{}
+	for _, u := range []struct{ name, email string }{
+		{"carol", "carol@Example.com"},
+		{"alice", "alice@example.org"},
+		{"bob", "bob@example.com"},
+		{"dave_100%", "dave@test.example.com"},
+	} {
+		user := &domain.User{Name: u.name, Email: u.email, Password: "hashed"}
+		require.NoError(t, store.Create(user))
+		users[u.name] = user
+	}
+	require.NoError(t, store.UpdateRole(users["bob"].ID, domain.RoleSupport))
+
+	list := func(filter domain.UserFilter, sort domain.UserSort) []string {
+		found, err := store.FindAll(domain.UserListQuery{Filter: filter, Sort: sort, Limit: 10})
+		require.NoError(t, err)
+		names := []string{}
+		for _, u := range found {
+			names = append(names, u.Name)
+		}
+		return names
+	}
+	all := domain.UserFilter{}
+
+	assert.Equal(t, []string{"carol", "alice", "bob", "dave_100%"}, list(all, domain.UserSort{}), "created_at asc by default")
+	assert.Equal(t, []string{"dave_100%", "bob", "alice", "carol"}, list(all, domain.UserSort{Desc: true}))
+	assert.Equal(t, []string{"alice", "bob", "carol", "dave_100%"}, list(all, domain.UserSort{Field: domain.UserSortName}))
+	assert.Equal(t, []string{"dave_100%", "carol", "bob", "alice"}, list(all, domain.UserSort{Field: domain.UserSortName, Desc: true}))
+	assert.Equal(t, []string{"alice", "bob", "carol", "dave_100%"}, list(all, domain.UserSort{Field: domain.UserSortEmail}))
+
+	byName := domain.UserSort{Field: domain.UserSortName}
+	assert.Equal(t, []string{"bob", "carol"}, list(domain.UserFilter{EmailDomain: "EXAMPLE.com"}, byName), "domain is case-insensitive and exact")
+	assert.Equal(t, []string{"bob"}, list(domain.UserFilter{Role: domain.RoleSupport}, byName))
+	assert.Equal(t, []string{"alice"}, list(domain.UserFilter{Query: "ALI"}, byName), "q matches name")
+	assert.Equal(t, []string{"alice"}, list(domain.UserFilter{Query: "EXAMPLE.org"}, byName), "q matches email")
+	assert.Equal(t, []string{"dave_100%"}, list(domain.UserFilter{Query: "_100%"}, byName), "wildcards are literal")
+	assert.Empty(t, list(domain.UserFilter{Query: "a%"}, byName))
+	assert.Equal(t, []string{"bob"}, list(domain.UserFilter{Query: "b", Role: domain.RoleSupport}, byName), "filters are combined")
+
+	verified, unverified := true, false
+	assert.Empty(t, list(domain.UserFilter{Verified: &verified}, byName))
+	assert.Len(t, list(domain.UserFilter{Verified: &unverified}, byName), 4)
+
+	from := time.Now().Add(-time.Hour)
+	to := time.Now().Add(time.Hour)
+	assert.Len(t, list(domain.UserFilter{CreatedFrom: &from, CreatedTo: &to}, byName), 4)
+	assert.Empty(t, list(domain.UserFilter{CreatedFrom: &to}, byName))
+	assert.Empty(t, list(domain.UserFilter{CreatedTo: &from}, byName))
+
+	count, err := store.Count(all)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), count)
+	count, err = store.Count(domain.UserFilter{EmailDomain: "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+	deleteUser(t, store, users["bob"].ID)
+	count, err = store.Count(domain.UserFilter{EmailDomain: "example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count, "deleted users are not counted")
+}
+
+func testFindPageSorted(t *testing.T, store domain.UserStore) {
+	for _, name := range []string{"c", "a", "e", "b", "d"} {
+		require.NoError(t, store.Create(newUser(name)))
+	}
+	sort := domain.UserSort{Field: domain.UserSortName, Desc: true}
+	filter := domain.UserFilter{Query: "example.com"}
+
+	names := func(users []*domain.User) []string {
+		result := []string{}
+		for _, u := range users {
+			result = append(result, u.Name)
+		}
+		return result
+	}
+
+	first, err := store.FindPage(domain.UserPageQuery{Filter: filter, Sort: sort, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"e", "d"}, names(first))
+
+	after := domain.KeyOf(first[1])
+	second, err := store.FindPage(domain.UserPageQuery{Filter: filter, Sort: sort, After: &after, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "b"}, names(second))
+
+	before := domain.KeyOf(second[1])
+	prev, err := store.FindPage(domain.UserPageQuery{Filter: filter, Sort: sort, Before: &before, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"d", "c"}, names(prev))
+}
+
+func testSearch(t *testing.T, store domain.UserStore) {
+	create := func(name, email string) *domain.User {
+		user := &domain.User{Name: name, Email: email, Password: "hashed"}
+		require.NoError(t, store.Create(user))
+		return user
+	}
+	smith := create("Anna Smith", "anna@example.com")
+	bob := create("Bob", "smithers@example.org")
+	create("Carol Jones", "carol@example.net")
+	deleted := create("Smitty", "smitty@example.com")
+	deleteUser(t, store, deleted.ID)
+	tag := create("<b>Bob</b>", "tag@example.com")
+
+	search := func(q string) []*domain.UserSearchHit {
+		hits, err := store.Search(domain.SearchTerms(q), 10)
+		require.NoError(t, err)
+		return hits
+	}
+	ids := func(hits []*domain.UserSearchHit) []uint {
+		result := []uint{}
+		for _, h := range hits {
+			assert.Empty(t, h.User.Password, "password is not returned")
+			result = append(result, h.User.ID)
+		}
+		return result
+	}
+
+	// 前方一致し、名前での一致がメールアドレスでの一致より上位になる
+	hits := search("smi")
+	assert.Equal(t, []uint{smith.ID, bob.ID}, ids(hits), "deleted users are excluded")
+	assert.Equal(t, "Anna <mark>Smith</mark>", hits[0].NameHighlight)
+	assert.Equal(t, "anna@example.com", hits[0].EmailHighlight)
+	assert.Equal(t, "<mark>smithers</mark>@example.org", hits[1].EmailHighlight)
+	assert.Greater(t, hits[0].Score, hits[1].Score)
+
+	// すべての単語に一致したユーザーだけを返す
+	assert.Equal(t, []uint{bob.ID}, ids(search("smith example.ORG")))
+	assert.Empty(t, search("smith nobody"))
+	assert.Empty(t, search("ith"), "only prefixes match")
+
+	// ハイライトは HTML エスケープされる
+	hits = search("bob tag")
+	require.Len(t, hits, 1)
+	assert.Equal(t, tag.ID, hits[0].User.ID)
+	assert.Equal(t, "&lt;b&gt;<mark>Bob</mark>&lt;/b&gt;", hits[0].NameHighlight)
+
+	// 更新・物理削除が索引に反映される
+	smith.Name = "Anna Jones"
+	require.NoError(t, store.Update(smith))
+	assert.Equal(t, []uint{bob.ID}, ids(search("smi")))
+	assert.Len(t, search("jones"), 2)
+
+	hits, err := store.Search(domain.SearchTerms("example"), 2)
+	require.NoError(t, err)
+	assert.Len(t, hits, 2, "limit")
+
+	empty, err := store.Search(nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, empty)
+}
+
+func testDeleteAndRestore(t *testing.T, store domain.UserStore) {
+	user := newUser("alice")
+	require.NoError(t, store.Create(user))
+
+	_, err := store.FindDeletedByID(user.ID)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound, "not deleted yet")
+	assert.ErrorIs(t, store.Restore(user.ID, time.Now().Add(-time.Hour)), domain.ErrUserNotFound, "not deleted yet")
+
+	require.NoError(t, store.Delete(user.ID, user.Version))
+	assert.ErrorIs(t, store.Delete(user.ID, user.Version+1), domain.ErrUserNotFound)
+	assert.ErrorIs(t, store.Delete(9999, 1), domain.ErrUserNotFound)
+
+	_, err = store.FindByID(user.ID)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	_, err = store.FindByEmail(user.Email)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	assert.ErrorIs(t, store.Update(user), domain.ErrUserNotFound)
+
+	deleted, err := store.FindDeletedByID(user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, deleted.DeletedAt)
+
+	// 猶予期間（deletedAfter）より前に削除されたものは復元できない
+	assert.ErrorIs(t, store.Restore(user.ID, time.Now().Add(time.Hour)), domain.ErrUserNotFound)
+
+	require.NoError(t, store.Restore(user.ID, time.Now().Add(-time.Hour)))
+	restored, err := store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, user.Email, restored.Email)
+	assert.Nil(t, restored.DeletedAt)
+}
+
+func testPurge(t *testing.T, store domain.UserStore) {
+	kept := newUser("kept")
+	deleted := newUser("deleted")
+	require.NoError(t, store.Create(kept))
+	require.NoError(t, store.Create(deleted))
+	deleteUser(t, store, deleted.ID)
+
+	purged, err := store.Purge(time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Empty(t, purged, "still within the grace period")
+
+	purged, err = store.Purge(time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []uint{deleted.ID}, purged)
+
+	_, err = store.FindDeletedByID(deleted.ID)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	_, err = store.FindByID(kept.ID)
+	assert.NoError(t, err)
+
+	// 物理削除されたアドレスは再び使える
+	assert.NoError(t, store.Create(newUser("deleted")))
+}
+
+func testVersion(t *testing.T, store domain.UserStore) {
+	user := newUser("alice")
+	require.NoError(t, store.Create(user))
+	assert.Equal(t, uint(1), user.Version)
+
+	stale := *user
+	user.Name = "Alice Liddell"
+	require.NoError(t, store.Update(user))
+	assert.Equal(t, uint(2), user.Version)
+
+	// 古いバージョンからの更新・削除は、上書きせずに ErrVersionMismatch になる
+	stale.Name = "Overwritten"
+	err := store.Update(&stale)
+	assert.ErrorIs(t, err, domain.ErrVersionMismatch)
+	assert.ErrorIs(t, err, domain.ErrPreconditionFailed)
+	assert.ErrorIs(t, store.Delete(user.ID, stale.Version), domain.ErrVersionMismatch)
+
+	found, err := store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice Liddell", found.Name)
+	assert.Equal(t, uint(2), found.Version)
+
+	// ロールの変更・削除・復元もバージョンを進める
+	require.NoError(t, store.UpdateRole(user.ID, domain.RoleSupport))
+	found, err = store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(3), found.Version)
+
+	deleteUser(t, store, user.ID)
+	require.NoError(t, store.Restore(user.ID, time.Now().Add(-time.Hour)))
+	found, err = store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, uint(5), found.Version)
+}
+
+// deleteUser は現在のバージョンを読み込んでユーザーを論理削除する
+func deleteUser(t *testing.T, store domain.UserStore, id uint) {
+	t.Helper()
+	user, err := store.FindByID(id)
+	require.NoError(t, err)
+	require.NoError(t, store.Delete(id, user.Version))
+}
+
+func testReturnsCopies(t *testing.T, store domain.UserStore) {
+	user := newUser("alice")
+	require.NoError(t, store.Create(user))
+
+	found, err := store.FindByID(user.ID)
+	require.NoError(t, err)
+	found.Name = "changed"
+
+	again, err := store.FindByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", again.Name, "changes are only persisted through Update")
+}
@@ -1,12 +1,20 @@
 package repository
 
-import "time"
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
 
 type User struct {
-	ID        uint `gorm:"primaryKey;autoIncrement"`
-	Name      string
-	Email     string `gorm:"uniqueIndex"`
-	Password  string
-	CreatedAt time.Time
-	UpdatedAt time.Time
+	ID              uint `gorm:"primaryKey;autoIncrement;index:idx_users_created_at_id,priority:2"`
+	Name            string
+	Email           string `gorm:"uniqueIndex"`
+	Password        string
+	Role            string `gorm:"not null;default:member"`
+	EmailVerifiedAt *time.Time
+	CreatedAt       time.Time `gorm:"index:idx_users_created_at_id,priority:1"` // 一覧のキーセットページネーション用
+	UpdatedAt       time.Time
+	DeletedAt       gorm.DeletedAt `gorm:"index"`              // 論理削除（通常のクエリからは自動的に除外される）
+	Version         uint           `gorm:"not null;default:1"` // 楽観的排他制御のバージョン（行を更新するたびに増やす）
 }
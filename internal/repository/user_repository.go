@@ -1,65 +1,154 @@
 package repository
 
 import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/okamuuu/go-user-app/internal/domain"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// UserRepository は domain.UserStore の GORM による実装
 type UserRepository struct {
 	db *gorm.DB
+
+	searchOnce sync.Once
+	search     searchBackend // 検索に使う索引（最初の検索時に判定する）
 }
 
+var _ domain.UserStore = (*UserRepository)(nil)
+
 func NewUserRepository(db *gorm.DB) *UserRepository {
 	return &UserRepository{db: db}
 }
 
-func (r *UserRepository) FindAll(offset, limit int) ([]*domain.User, error) {
+// FindAll returns users matching the filter, ordered by the sort and paged by offset
+func (r *UserRepository) FindAll(query domain.UserListQuery) ([]*domain.User, error) {
 	var models []User
-	result := r.db.Offset(offset).Limit(limit).Find(&models)
+	result := r.db.Scopes(filterUsers(query.Filter), orderUsers(query.Sort, false)).
+		Offset(query.Offset).Limit(query.Limit).Find(&models)
 	if result.Error != nil {
 		return nil, result.Error
 	}
+	return toListedUsers(models), nil
+}
 
-	var users []*domain.User
-	for _, m := range models {
-		users = append(users, &domain.User{
-			ID:        m.ID,
-			Name:      m.Name,
-			Email:     m.Email,
-			CreatedAt: m.CreatedAt,
-			UpdatedAt: m.UpdatedAt,
-		})
+// FindPage returns users matching the filter using keyset pagination on (sort column, id)
+func (r *UserRepository) FindPage(query domain.UserPageQuery) ([]*domain.User, error) {
+	// Before の場合は逆順で直前の Limit 件を取り、並べ直す
+	backward := query.After == nil && query.Before != nil
+	q := r.db.Scopes(filterUsers(query.Filter), orderUsers(query.Sort, backward)).Limit(query.Limit)
+
+	key := query.After
+	if backward {
+		key = query.Before
+	}
+	if key != nil {
+		// 並び順で key より後ろ（backward なら前）にある行
+		op := ">"
+		if query.Sort.Desc != backward {
+			op = "<"
+		}
+		column, value := userSortColumn(query.Sort.Field), userKeyValue(*key, query.Sort.Field)
+		q = q.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, op), value, value, key.ID)
 	}
-	return users, nil
+
+	var models []User
+	if err := q.Find(&models).Error; err != nil {
+		return nil, err
+	}
+	if backward {
+		slices.Reverse(models)
+	}
+	return toListedUsers(models), nil
+}
+
+// Count returns the number of users matching the filter
+func (r *UserRepository) Count(filter domain.UserFilter) (int64, error) {
+	var count int64
+	err := r.db.Model(&User{}).Scopes(filterUsers(filter)).Count(&count).Error
+	return count, err
 }
 
 // Save inserts a new user into the database
 func (r *UserRepository) Create(user *domain.User) error {
+	return createUser(r.db, user)
+}
+
+// CreateBatch inserts users in a single transaction and returns the error of each user (nil if created).
+// 登録できないユーザー（メールアドレスの重複など）があっても他のユーザーは登録し、
+// トランザクション自体が失敗した場合だけ err を返す（このときはどのユーザーも登録されない）
+func (r *UserRepository) CreateBatch(users []*domain.User) ([]error, error) {
+	errs := make([]error, len(users))
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		for i, user := range users {
+			// PostgreSQL はエラーの後のトランザクションを使えなくなるので、ユーザーごとにセーブポイントまで戻す
+			if err := tx.SavePoint("create_user").Error; err != nil {
+				return err
+			}
+			if err := createUser(tx, user); err != nil {
+				if err := tx.RollbackTo("create_user").Error; err != nil {
+					return err
+				}
+				errs[i] = err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return errs, nil
+}
+
+func createUser(db *gorm.DB, user *domain.User) error {
 	model := User{
 		Name:     user.Name,
-		Email:    user.Email,
+		Email:    domain.NormalizeEmail(user.Email),
 		Password: user.Password,
+		Role:     string(user.Role),
+		// 管理者の初期ユーザーなど、確認済みとして登録する場合だけ指定される
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		Version:         1,
 	}
-	return r.db.Create(&model).Error
+	if model.Role == "" {
+		model.Role = string(domain.RoleMember)
+	}
+	if err := db.Create(&model).Error; err != nil {
+		return userWriteError(err)
+	}
+	user.ID = model.ID
+	user.Email = model.Email
+	user.CreatedAt = model.CreatedAt
+	user.UpdatedAt = model.UpdatedAt
+	user.Role = domain.Role(model.Role)
+	user.Version = model.Version
+	return nil
 }
 
 // FindByEmail finds a user by email
 func (r *UserRepository) FindByEmail(email string) (*domain.User, error) {
 	var model User
-	result := r.db.Where("email = ?", email).First(&model)
+	result := r.db.Where("email = ?", domain.NormalizeEmail(email)).First(&model)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, notFound(result.Error)
 	}
 
 	return &domain.User{
-		ID:        model.ID,
-		Name:      model.Name,
-		Email:     model.Email,
-		Password:  model.Password,
-		CreatedAt: model.CreatedAt,
-		UpdatedAt: model.UpdatedAt,
+		ID:              model.ID,
+		Name:            model.Name,
+		Email:           model.Email,
+		Password:        model.Password,
+		Role:            domain.Role(model.Role),
+		CreatedAt:       model.CreatedAt,
+		UpdatedAt:       model.UpdatedAt,
+		EmailVerifiedAt: model.EmailVerifiedAt,
+		Version:         model.Version,
 	}, nil
 }
 
@@ -68,35 +157,286 @@ func (r *UserRepository) FindByID(id uint) (*domain.User, error) {
 	var model User
 	result := r.db.First(&model, id)
 	if result.Error != nil {
-		return nil, result.Error
+		return nil, notFound(result.Error)
 	}
 
 	return &domain.User{
-		ID:        model.ID,
-		Name:      model.Name,
-		Email:     model.Email,
-		Password:  model.Password,
-		CreatedAt: model.CreatedAt,
-		UpdatedAt: model.UpdatedAt,
+		ID:              model.ID,
+		Name:            model.Name,
+		Email:           model.Email,
+		Password:        model.Password,
+		Role:            domain.Role(model.Role),
+		CreatedAt:       model.CreatedAt,
+		UpdatedAt:       model.UpdatedAt,
+		EmailVerifiedAt: model.EmailVerifiedAt,
+		Version:         model.Version,
 	}, nil
 }
 
-// Update updates an existing user in the database
+// Update updates an existing user in the database if its version still matches user.Version
 func (r *UserRepository) Update(user *domain.User) error {
+	// 読み込んでから保存するのではなく、バージョンを条件にした1文で更新する
+	email := domain.NormalizeEmail(user.Email)
+	now := time.Now()
+	result := r.db.Model(&User{}).
+		Where("id = ? AND version = ?", user.ID, user.Version).
+		Updates(map[string]interface{}{
+			"name":       user.Name,
+			"email":      email,
+			"password":   user.Password,
+			"updated_at": now,
+			"version":    nextVersion,
+		})
+	if result.Error != nil {
+		return userWriteError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return r.versionError(user.ID)
+	}
+	user.Email = email
+	user.UpdatedAt = now
+	user.Version++
+	return nil
+}
+
+// Patch updates only the fields set in patch if the user's version still matches
+func (r *UserRepository) Patch(id uint, version uint, patch domain.UserPatch) error {
+	changes := map[string]interface{}{"updated_at": time.Now(), "version": nextVersion}
+	if patch.Name != nil {
+		changes["name"] = *patch.Name
+	}
+	if patch.Email != nil {
+		changes["email"] = domain.NormalizeEmail(*patch.Email)
+	}
+	if patch.Password != nil {
+		changes["password"] = *patch.Password
+	}
+	result := r.db.Model(&User{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(changes)
+	if result.Error != nil {
+		return userWriteError(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return r.versionError(id)
+	}
+	return nil
+}
+
+// Delete soft-deletes a user by ID if its version still matches (restorable until purged)
+func (r *UserRepository) Delete(id uint, version uint) error {
+	result := r.db.Model(&User{}).
+		Where("id = ? AND version = ?", id, version).
+		Updates(map[string]interface{}{"deleted_at": time.Now(), "version": nextVersion})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return r.versionError(id)
+	}
+	return nil
+}
+
+// FindDeletedByID finds a soft-deleted user by ID
+func (r *UserRepository) FindDeletedByID(id uint) (*domain.User, error) {
 	var model User
-	if err := r.db.First(&model, "id = ?", user.ID).Error; err != nil {
-		return err
+	if err := r.db.Unscoped().Where("deleted_at IS NOT NULL").First(&model, id).Error; err != nil {
+		return nil, notFound(err)
 	}
+	return ToDomainUser(&model), nil
+}
 
-	model.Name = user.Name
-	model.Email = user.Email
-	model.Password = user.Password
-	model.UpdatedAt = time.Now()
+// Restore clears the deletion mark of a user deleted after the given time
+func (r *UserRepository) Restore(id uint, deletedAfter time.Time) error {
+	result := r.db.Unscoped().Model(&User{}).
+		Where("id = ? AND deleted_at IS NOT NULL AND deleted_at > ?", id, deletedAfter).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": nextVersion})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// Purge permanently removes users soft-deleted before the given time, along with their related records.
+// 削除したユーザーのIDを返す
+func (r *UserRepository) Purge(deletedBefore time.Time) ([]uint, error) {
+	var purged []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ids []uint
+		if err := tx.Unscoped().Model(&User{}).
+			Where("deleted_at IS NOT NULL AND deleted_at < ?", deletedBefore).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		// ユーザーに紐づくレコードも合わせて物理削除する
+		for _, model := range userRelatedModels() {
+			if err := tx.Where("user_id IN ?", ids).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Unscoped().Where("id IN ?", ids).Delete(&User{}).Error; err != nil {
+			return err
+		}
+
+		// 発行済みのアクセストークンが期限切れになるまで使えないよう、一括失効を作り直す
+		now := time.Now()
+		revocations := make([]UserTokenRevocation, 0, len(ids))
+		for _, id := range ids {
+			revocations = append(revocations, UserTokenRevocation{UserID: id, RevokedBefore: now})
+		}
+		if err := tx.Create(&revocations).Error; err != nil {
+			return err
+		}
+		purged = ids
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return purged, nil
+}
+
+// userRelatedModels はユーザーに紐づく（user_id 列を持つ）テーブル。ユーザーを物理削除するときに合わせて削除する
+func userRelatedModels() []interface{} {
+	return []interface{}{
+		&RefreshToken{},
+		&RevokedToken{},
+		&UserTokenRevocation{},
+		&PasswordResetToken{},
+		&EmailVerificationToken{},
+		&MFACredential{},
+		&MFARecoveryCode{},
+	}
+}
+
+// UpdateRole changes the role of the user
+func (r *UserRepository) UpdateRole(id uint, role domain.Role) error {
+	result := r.db.Model(&User{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"role":       string(role),
+		"updated_at": time.Now(),
+		"version":    nextVersion,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return domain.ErrUserNotFound
+	}
+	return nil
+}
+
+// userSortColumns は並び替えの項目と列の対応。ここにある列以外では並び替えない
+var userSortColumns = map[domain.UserSortField]string{
+	domain.UserSortCreatedAt: "created_at",
+	domain.UserSortName:      "name",
+	domain.UserSortEmail:     "email",
+}
+
+// userSortColumn は並び替えの項目の列名を返す（一覧にない項目なら作成日時）
+func userSortColumn(field domain.UserSortField) string {
+	if column, ok := userSortColumns[field]; ok {
+		return column
+	}
+	return userSortColumns[domain.UserSortCreatedAt]
+}
+
+// likeEscaper は LIKE のワイルドカードを文字として扱うためのエスケープ（ESCAPE '!' と合わせて使う）
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// filterUsers は一覧の絞り込み条件を WHERE 句にする
+func filterUsers(f domain.UserFilter) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if f.EmailDomain != "" {
+			db = db.Where("LOWER(email) LIKE ? ESCAPE '!'", "%@"+likeEscaper.Replace(strings.ToLower(f.EmailDomain)))
+		}
+		if f.CreatedFrom != nil {
+			db = db.Where("created_at >= ?", *f.CreatedFrom)
+		}
+		if f.CreatedTo != nil {
+			db = db.Where("created_at < ?", *f.CreatedTo)
+		}
+		if f.Role != "" {
+			db = db.Where("role = ?", string(f.Role))
+		}
+		if f.Verified != nil {
+			if *f.Verified {
+				db = db.Where("email_verified_at IS NOT NULL")
+			} else {
+				db = db.Where("email_verified_at IS NULL")
+			}
+		}
+		if f.Query != "" {
+			pattern := "%" + likeEscaper.Replace(strings.ToLower(f.Query)) + "%"
+			db = db.Where("(LOWER(name) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!')", pattern, pattern)
+		}
+		return db
+	}
+}
 
-	return r.db.Save(&model).Error
+// orderUsers は並び順を ORDER BY 句にする。同じ値の行は ID で順序を決める（reverse なら逆順）
+func orderUsers(sort domain.UserSort, reverse bool) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		desc := sort.Desc != reverse
+		return db.Order(clause.OrderByColumn{Column: clause.Column{Name: userSortColumn(sort.Field)}, Desc: desc}).
+			Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: desc})
+	}
 }
 
-// Delete removes a user from the database by ID
-func (r *UserRepository) Delete(id uint) error {
-	return r.db.Delete(&User{}, id).Error
+// userKeyValue は並び替えの項目に対応する位置の値を返す
+func userKeyValue(key domain.UserKey, field domain.UserSortField) interface{} {
+	switch field {
+	case domain.UserSortName:
+		return key.Name
+	case domain.UserSortEmail:
+		return key.Email
+	default:
+		return key.CreatedAt
+	}
+}
+
+// toListedUsers は一覧用にパスワードを除いたユーザーに変換する
+func toListedUsers(models []User) []*domain.User {
+	users := make([]*domain.User, 0, len(models))
+	for i := range models {
+		u := ToDomainUser(&models[i])
+		u.Password = ""
+		users = append(users, u)
+	}
+	return users
+}
+
+// nextVersion は users の行を更新するときにバージョンを進める式
+var nextVersion = gorm.Expr("version + 1")
+
+// versionError はバージョンを条件にした更新で行が無かった理由（ユーザーがいない・バージョンが違う）を返す
+func (r *UserRepository) versionError(id uint) error {
+	if _, err := r.FindByID(id); err != nil {
+		return err
+	}
+	return domain.ErrVersionMismatch
+}
+
+// userWriteError は users への書き込みのエラーを変換する。
+// users の一意制約はメールアドレスだけなので、一意制約違反はどの DB でも domain.ErrEmailTaken になる
+func userWriteError(err error) error {
+	if isUniqueViolation(err) {
+		return domain.ErrEmailTaken
+	}
+	return err
+}
+
+// notFound は GORM のレコード未検出エラーを domain.ErrUserNotFound に変換する
+func notFound(err error) error {
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return domain.ErrUserNotFound
+	}
+	return err
 }
@@ -2,68 +2,138 @@ package repository_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/okamuuu/go-user-app/internal/domain"
 	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/testdb"
 	"github.com/stretchr/testify/assert"
-	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
-func setupTestDB(t *testing.T) *gorm.DB {
-	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	if err != nil {
-		t.Fatalf("failed to connect to test database: %v", err)
-	}
-	err = db.AutoMigrate(&repository.User{})
-	if err != nil {
-		t.Fatalf("failed to migrate test database: %v", err)
-	}
-	return db
+func TestUserRepository_CRUD(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewUserRepository(db)
+
+		// --- Create ---
+		user := &domain.User{
+			Name:     "John Doe",
+			Email:    "john@example.com",
+			Password: "secure123",
+		}
+
+		err := repo.Create(user)
+		assert.NoError(t, err, "save user")
+
+		// --- Read (FindByEmail) ---
+		found, err := repo.FindByEmail("john@example.com")
+		assert.NoError(t, err, "find user by email")
+		assert.Equal(t, user.Email, found.Email)
+		assert.Equal(t, user.Name, found.Name)
+
+		// --- Read (FindByID) ---
+		byID, err := repo.FindByID(found.ID)
+		assert.NoError(t, err, "find user by ID")
+		assert.Equal(t, found.ID, byID.ID)
+
+		// --- Update ---
+		byID.Name = "Updated Name"
+		byID.Password = "newpass"
+		err = repo.Update(byID)
+		assert.NoError(t, err, "update user")
+
+		updated, err := repo.FindByID(byID.ID)
+		assert.NoError(t, err, "read updated user")
+		assert.Equal(t, "Updated Name", updated.Name)
+		assert.Equal(t, "newpass", updated.Password)
+		assert.True(t, updated.UpdatedAt.After(updated.CreatedAt), "UpdatedAt should be after CreatedAt")
+
+		// --- Delete ---
+		err = repo.Delete(byID.ID, byID.Version)
+		assert.NoError(t, err, "delete user")
+
+		deleted, err := repo.FindByID(byID.ID)
+		assert.Error(t, err, "should not find deleted user")
+		assert.Nil(t, deleted)
+	})
 }
 
-func TestUserRepository_CRUD(t *testing.T) {
-	db := setupTestDB(t)
-	repo := repository.NewUserRepository(db)
-
-	// --- Create ---
-	user := &domain.User{
-		Name:     "John Doe",
-		Email:    "john@example.com",
-		Password: "secure123",
-	}
-
-	err := repo.Create(user)
-	assert.NoError(t, err, "save user")
-
-	// --- Read (FindByEmail) ---
-	found, err := repo.FindByEmail("john@example.com")
-	assert.NoError(t, err, "find user by email")
-	assert.Equal(t, user.Email, found.Email)
-	assert.Equal(t, user.Name, found.Name)
-
-	// --- Read (FindByID) ---
-	byID, err := repo.FindByID(found.ID)
-	assert.NoError(t, err, "find user by ID")
-	assert.Equal(t, found.ID, byID.ID)
-
-	// --- Update ---
-	byID.Name = "Updated Name"
-	byID.Password = "newpass"
-	err = repo.Update(byID)
-	assert.NoError(t, err, "update user")
-
-	updated, err := repo.FindByID(byID.ID)
-	assert.NoError(t, err, "read updated user")
-	assert.Equal(t, "Updated Name", updated.Name)
-	assert.Equal(t, "newpass", updated.Password)
-	assert.True(t, updated.UpdatedAt.After(updated.CreatedAt), "UpdatedAt should be after CreatedAt")
-
-	// --- Delete ---
-	err = repo.Delete(byID.ID)
-	assert.NoError(t, err, "delete user")
-
-	deleted, err := repo.FindByID(byID.ID)
-	assert.Error(t, err, "should not find deleted user")
-	assert.Nil(t, deleted)
+func TestUserRepository_SoftDeleteAndRestore(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewUserRepository(db)
+
+		user := &domain.User{Name: "Jane", Email: "jane@example.com", Password: "secure123"}
+		assert.NoError(t, repo.Create(user))
+
+		assert.NoError(t, repo.Delete(user.ID, user.Version))
+		assert.ErrorIs(t, repo.Delete(user.ID, user.Version), domain.ErrUserNotFound, "already deleted")
+
+		// 論理削除なのでレコードは残っている
+		deleted, err := repo.FindDeletedByID(user.ID)
+		assert.NoError(t, err)
+		assert.NotNil(t, deleted.DeletedAt)
+
+		// 猶予期間を過ぎていれば復元できない
+		assert.ErrorIs(t, repo.Restore(user.ID, time.Now().Add(time.Hour)), domain.ErrUserNotFound)
+
+		assert.NoError(t, repo.Restore(user.ID, time.Now().Add(-time.Hour)))
+		restored, err := repo.FindByID(user.ID)
+		assert.NoError(t, err)
+		assert.Nil(t, restored.DeletedAt)
+	})
+}
+
+func TestUserRepository_Purge(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewUserRepository(db)
+
+		kept := &domain.User{Name: "Kept", Email: "kept@example.com", Password: "secure123"}
+		recent := &domain.User{Name: "Recent", Email: "recent@example.com", Password: "secure123"}
+		expired := &domain.User{Name: "Expired", Email: "expired@example.com", Password: "secure123"}
+		for _, u := range []*domain.User{kept, recent, expired} {
+			assert.NoError(t, repo.Create(u))
+		}
+		assert.NoError(t, repo.Delete(recent.ID, recent.Version))
+		assert.NoError(t, repo.Delete(expired.ID, expired.Version))
+		assert.NoError(t, db.Unscoped().Model(&repository.User{}).Where("id = ?", expired.ID).
+			Update("deleted_at", time.Now().Add(-48*time.Hour)).Error)
+		assert.NoError(t, db.Create(&repository.RefreshToken{
+			UserID: expired.ID, TokenHash: "hash", FamilyID: "family", ExpiresAt: time.Now().Add(time.Hour),
+		}).Error)
+
+		purged, err := repo.Purge(time.Now().Add(-24 * time.Hour))
+		assert.NoError(t, err)
+		assert.Equal(t, []uint{expired.ID}, purged)
+
+		_, err = repo.FindDeletedByID(expired.ID)
+		assert.ErrorIs(t, err, domain.ErrUserNotFound)
+		_, err = repo.FindDeletedByID(recent.ID)
+		assert.NoError(t, err, "still within grace period")
+		_, err = repo.FindByID(kept.ID)
+		assert.NoError(t, err)
+
+		var tokens int64
+		db.Model(&repository.RefreshToken{}).Where("user_id = ?", expired.ID).Count(&tokens)
+		assert.Zero(t, tokens, "related records are purged")
+
+		// 発行済みのアクセストークンは期限切れまで失効したまま
+		revoked, err := repository.NewRevocationRepository(db).IsRevoked("jti", expired.ID, time.Now().Add(-time.Minute))
+		assert.NoError(t, err)
+		assert.True(t, revoked)
+	})
+}
+
+func TestUserRepository_DuplicateEmail(t *testing.T) {
+	testdb.Run(t, func(t *testing.T, db *gorm.DB) {
+		repo := repository.NewUserRepository(db)
+
+		first := &domain.User{Name: "First", Email: "dup@example.com", Password: "secure123"}
+		second := &domain.User{Name: "Second", Email: "dup@example.com", Password: "secure123"}
+		assert.NoError(t, repo.Create(first))
+		assert.ErrorIs(t, repo.Create(second), repository.ErrEmailTaken)
+
+		// 論理削除済みのユーザーのアドレスも使えない
+		assert.NoError(t, repo.Delete(first.ID, first.Version))
+		assert.ErrorIs(t, repo.Create(second), repository.ErrEmailTaken)
+	})
 }
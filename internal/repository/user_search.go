@@ -0,0 +1,250 @@
+package repository
+
+import (
+	"html"
+	"sort"
+	"strings"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+)
+
+// searchBackend はユーザー検索に使う仕組み（マイグレーションで作られた索引による）
+type searchBackend int
+
+const (
+	searchLike     searchBackend = iota // 索引なし。LIKE で候補を絞り、アプリ側で判定する
+	searchFTS5                          // SQLite FTS5
+	searchTSVector                      // PostgreSQL tsvector
+)
+
+// LIKE で検索する場合に、アプリ側で判定する候補の上限
+const searchCandidateLimit = 1000
+
+// FTS5 の highlight() に渡す目印。HTML エスケープした後で <mark> に置き換える
+const (
+	markStart = "\x02"
+	markEnd   = "\x03"
+)
+
+var markReplacer = strings.NewReplacer(markStart, "<mark>", markEnd, "</mark>")
+
+// searchHit は検索結果の行
+type searchHit struct {
+	User
+	Score          float64
+	NameHighlight  string
+	EmailHighlight string
+}
+
+// Search finds users whose name or email has words starting with every term, best matches first
+func (r *UserRepository) Search(terms []string, limit int) ([]*domain.UserSearchHit, error) {
+	if len(terms) == 0 {
+		return []*domain.UserSearchHit{}, nil
+	}
+	switch r.searchBackend() {
+	case searchFTS5:
+		return r.searchFTS5(terms, limit)
+	case searchTSVector:
+		return r.searchTSVector(terms, limit)
+	default:
+		return r.searchLike(terms, limit)
+	}
+}
+
+func (r *UserRepository) searchBackend() searchBackend {
+	r.searchOnce.Do(func() {
+		switch r.db.Dialector.Name() {
+		case "sqlite":
+			if r.db.Migrator().HasTable("users_fts") {
+				r.search = searchFTS5
+			}
+		case "postgres":
+			if r.db.Migrator().HasColumn(&User{}, "search_vector") {
+				r.search = searchTSVector
+			}
+		}
+	})
+	return r.search
+}
+
+func (r *UserRepository) searchFTS5(terms []string, limit int) ([]*domain.UserSearchHit, error) {
+	// 各単語を "term"* の前方一致にして AND で結ぶ（単語は英数字だけなので引用符で囲めば安全）
+	match := make([]string, len(terms))
+	for i, term := range terms {
+		match[i] = `"` + term + `"*`
+	}
+
+	var rows []searchHit
+	err := r.db.Table("users_fts").
+		Select("users.*, -bm25(users_fts, 10.0, 5.0) AS score, "+
+			"highlight(users_fts, 0, ?, ?) AS name_highlight, highlight(users_fts, 1, ?, ?) AS email_highlight",
+			markStart, markEnd, markStart, markEnd).
+		Joins("JOIN users ON users.id = users_fts.rowid").
+		Where("users_fts MATCH ? AND users.deleted_at IS NULL", strings.Join(match, " ")).
+		Order("score DESC, users.id").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	hits := make([]*domain.UserSearchHit, 0, len(rows))
+	for i := range rows {
+		hit := toSearchHit(&rows[i].User, rows[i].Score)
+		hit.NameHighlight = markReplacer.Replace(html.EscapeString(rows[i].NameHighlight))
+		hit.EmailHighlight = markReplacer.Replace(html.EscapeString(rows[i].EmailHighlight))
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+func (r *UserRepository) searchTSVector(terms []string, limit int) ([]*domain.UserSearchHit, error) {
+	query := make([]string, len(terms))
+	for i, term := range terms {
+		query[i] = term + ":*"
+	}
+	tsquery := strings.Join(query, " & ")
+
+	var rows []searchHit
+	err := r.db.Table("users").
+		Select("users.*, ts_rank(search_vector, to_tsquery('simple', ?)) AS score", tsquery).
+		Where("search_vector @@ to_tsquery('simple', ?) AND deleted_at IS NULL", tsquery).
+		Order("score DESC, id").
+		Limit(limit).
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// ts_headline はメールアドレスを1つの単語として扱うため、ハイライトはアプリ側で付ける
+	hits := make([]*domain.UserSearchHit, 0, len(rows))
+	for i := range rows {
+		hit := toSearchHit(&rows[i].User, rows[i].Score)
+		hit.NameHighlight = highlightWords(hit.User.Name, terms)
+		hit.EmailHighlight = highlightWords(hit.User.Email, terms)
+		hits = append(hits, hit)
+	}
+	return hits, nil
+}
+
+func (r *UserRepository) searchLike(terms []string, limit int) ([]*domain.UserSearchHit, error) {
+	q := r.db.Model(&User{})
+	for _, term := range terms {
+		pattern := "%" + likeEscaper.Replace(term) + "%"
+		q = q.Where("(LOWER(name) LIKE ? ESCAPE '!' OR LOWER(email) LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+	var models []User
+	if err := q.Order("id").Limit(searchCandidateLimit).Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	users := make([]*domain.User, len(models))
+	for i := range models {
+		users[i] = ToDomainUser(&models[i])
+	}
+	return rankUsers(users, terms, limit), nil
+}
+
+func toSearchHit(m *User, score float64) *domain.UserSearchHit {
+	u := ToDomainUser(m)
+	u.Password = ""
+	return &domain.UserSearchHit{User: u, Score: score}
+}
+
+// rankUsers は FTS の索引を使わずに、検索語に合うユーザーをスコア順に limit 件返す。
+// 名前での一致をメールアドレスでの一致より、単語全体の一致を前方一致より高く評価する
+func rankUsers(users []*domain.User, terms []string, limit int) []*domain.UserSearchHit {
+	hits := []*domain.UserSearchHit{}
+	for _, u := range users {
+		nameWords := domain.SearchTerms(u.Name)
+		emailWords := domain.SearchTerms(u.Email)
+
+		var score float64
+		matched := true
+		for _, term := range terms {
+			name, email := wordScore(nameWords, term), wordScore(emailWords, term)
+			if name == 0 && email == 0 {
+				matched = false
+				break
+			}
+			score += 2*name + email
+		}
+		if !matched {
+			continue
+		}
+
+		c := copyUser(u)
+		c.Password = ""
+		hits = append(hits, &domain.UserSearchHit{
+			User:           c,
+			Score:          score,
+			NameHighlight:  highlightWords(u.Name, terms),
+			EmailHighlight: highlightWords(u.Email, terms),
+		})
+	}
+
+	sort.SliceStable(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].User.ID < hits[j].User.ID
+	})
+	if len(hits) > limit {
+		hits = hits[:limit]
+	}
+	return hits
+}
+
+// wordScore は単語の一覧に term が単語全体で一致すれば 1.5、前方一致すれば 1、しなければ 0 を返す
+func wordScore(words []string, term string) float64 {
+	var score float64
+	for _, w := range words {
+		if w == term {
+			return 1.5
+		}
+		if strings.HasPrefix(w, term) {
+			score = 1
+		}
+	}
+	return score
+}
+
+// highlightWords は text を HTML エスケープし、terms のいずれかで前方一致する単語を <mark> で囲む
+// （FTS5 の highlight() と同じく単語全体を囲む）
+func highlightWords(text string, terms []string) string {
+	var b strings.Builder
+	for len(text) > 0 {
+		// 単語の区切り文字はそのまま出力する
+		i := strings.IndexFunc(text, domain.IsSearchWordRune)
+		if i < 0 {
+			i = len(text)
+		}
+		b.WriteString(html.EscapeString(text[:i]))
+		text = text[i:]
+
+		j := strings.IndexFunc(text, func(r rune) bool { return !domain.IsSearchWordRune(r) })
+		if j < 0 {
+			j = len(text)
+		}
+		word := text[:j]
+		text = text[j:]
+		if word == "" {
+			continue
+		}
+		if matchesAnyPrefix(strings.ToLower(word), terms) {
+			b.WriteString("<mark>" + html.EscapeString(word) + "</mark>")
+		} else {
+			b.WriteString(html.EscapeString(word))
+		}
+	}
+	return b.String()
+}
+
+func matchesAnyPrefix(word string, terms []string) bool {
+	for _, term := range terms {
+		if strings.HasPrefix(word, term) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,53 @@
+package repository_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/repository/storetest"
+	"github.com/okamuuu/go-user-app/internal/testdb"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository_UserStore(t *testing.T) {
+	for _, backend := range testdb.Backends() {
+		t.Run(backend.Name, func(t *testing.T) {
+			storetest.RunUserStore(t, func(t *testing.T) domain.UserStore {
+				return repository.NewUserRepository(testdb.Migrated(t, backend))
+			})
+		})
+	}
+}
+
+func TestMemoryUserStore_UserStore(t *testing.T) {
+	storetest.RunUserStore(t, func(t *testing.T) domain.UserStore {
+		return repository.NewMemoryUserStore()
+	})
+}
+
+func TestMemoryUserStore_Concurrent(t *testing.T) {
+	store := repository.NewMemoryUserStore()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := &domain.User{Name: "user", Email: string(rune('a'+i%26)) + string(rune('a'+i/26)) + "@example.com"}
+			if err := store.Create(user); err != nil {
+				t.Error(err)
+				return
+			}
+			store.FindByID(user.ID)
+			store.FindAll(domain.UserListQuery{Limit: 10})
+		}(i)
+	}
+	wg.Wait()
+
+	users, err := store.FindAll(domain.UserListQuery{Limit: 100})
+	require.NoError(t, err)
+	assert.Len(t, users, 50)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/bxcodec/faker/v4"
 	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
 	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
@@ -16,6 +17,7 @@ func SeedUsers(db *gorm.DB, count int) {
 			Name:      faker.Name(),
 			Email:     faker.Email(),
 			Password:  faker.Password(), // 必要なら hash に変換
+			Role:      domain.RoleMember,
 			CreatedAt: time.Now(),
 			UpdatedAt: time.Now(),
 		}
@@ -31,3 +33,33 @@ func hash(pw string) string {
 	h, _ := bcrypt.GenerateFromPassword([]byte(pw), bcrypt.DefaultCost)
 	return string(h)
 }
+
+// SeedAdmin は管理者ユーザーを作成する。
+// メールアドレスは他のユーザーと同じく正規化し、リポジトリ経由で登録する
+func SeedAdmin(db *gorm.DB, email, password string) {
+	if password == "" {
+		log.Printf("ADMIN_PASSWORD is empty, skipping admin seed")
+		return
+	}
+	email = domain.NormalizeEmail(email)
+
+	// 既に登録済みなら何もしない（起動のたびに呼ばれるため）
+	repo := repository.NewUserRepository(db)
+	if _, err := repo.FindByEmail(email); err == nil {
+		return
+	}
+
+	now := time.Now()
+	admin := &domain.User{
+		Name:            "Administrator",
+		Email:           email,
+		Password:        hash(password),
+		Role:            domain.RoleAdmin,
+		EmailVerifiedAt: &now,
+	}
+	if err := repo.Create(admin); err != nil {
+		log.Printf("Failed to create admin user: %v", err)
+		return
+	}
+	log.Printf("Seeded admin user %s", email)
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"log"
+	"reflect"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+)
+
+// 監査ログで伏せ字にする項目
+var secretFields = map[string]bool{
+	"password": true,
+}
+
+const redacted = "[REDACTED]"
+
+// Actor は操作を行った主体（監査ログ用）
+type Actor struct {
+	UserID    *uint // 未認証のリクエストやシステムによる操作なら nil
+	IP        string
+	UserAgent string
+}
+
+type actorKey struct{}
+
+// WithActor は操作の主体を context に載せる（ハンドラーから渡す）
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFrom は context から操作の主体を取り出す。無ければシステムによる操作とみなす
+func ActorFrom(ctx context.Context) Actor {
+	actor, _ := ctx.Value(actorKey{}).(Actor)
+	return actor
+}
+
+// withActorUser は未認証のリクエスト（ログインなど）で、本人確認できたユーザーを主体として設定する
+func withActorUser(ctx context.Context, userID uint) context.Context {
+	actor := ActorFrom(ctx)
+	if actor.UserID == nil {
+		actor.UserID = &userID
+	}
+	return WithActor(ctx, actor)
+}
+
+type AuditService struct {
+	repo *repository.AuditRepository
+}
+
+func NewAuditService(repo *repository.AuditRepository) *AuditService {
+	return &AuditService{repo: repo}
+}
+
+// Record はユーザーに対する操作を記録する。
+// before / after には変更前後の値を渡し、変更のあった項目だけを秘密情報を伏せて保存する。
+// 記録に失敗しても元の操作は失敗させず、ログに残すだけにする。
+func (s *AuditService) Record(ctx context.Context, action string, targetID *uint, before, after map[string]interface{}) {
+	before, after = diffChanges(before, after)
+
+	actor := ActorFrom(ctx)
+	event := &domain.AuditEvent{
+		ActorID:    actor.UserID,
+		Action:     action,
+		TargetType: domain.AuditTargetUser,
+		TargetID:   targetID,
+		IP:         actor.IP,
+		UserAgent:  actor.UserAgent,
+		Before:     redactSecrets(before),
+		After:      redactSecrets(after),
+	}
+	if err := s.repo.Create(event); err != nil {
+		log.Printf("[ERROR] failed to record audit event: action=%s: %v", action, err)
+	}
+}
+
+// List は条件に合う監査ログを新しい順に返す
+func (s *AuditService) List(filter domain.AuditFilter, page, limit int) ([]*domain.AuditEvent, error) {
+	offset := (page - 1) * limit
+	return s.repo.Find(filter, offset, limit)
+}
+
+// userSnapshot は監査ログに残すユーザーの状態
+func userSnapshot(u *domain.User) map[string]interface{} {
+	return map[string]interface{}{
+		"name":              u.Name,
+		"email":             u.Email,
+		"password":          u.Password,
+		"role":              string(u.Role),
+		"email_verified_at": timeValue(u.EmailVerifiedAt),
+		"deleted_at":        timeValue(u.DeletedAt),
+	}
+}
+
+// mfaSnapshot は監査ログに残す MFA の状態（シークレットやリカバリーコードは含めない）
+func mfaSnapshot(enabled bool) map[string]interface{} {
+	return map[string]interface{}{"mfa_enabled": enabled}
+}
+
+func timeValue(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+// diffChanges は before と after の両方がある場合に、値が変わった項目だけを残す
+func diffChanges(before, after map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	if before == nil || after == nil {
+		return before, after
+	}
+	b := map[string]interface{}{}
+	a := map[string]interface{}{}
+	for key, value := range after {
+		if old, ok := before[key]; !ok || !reflect.DeepEqual(old, value) {
+			b[key] = before[key]
+			a[key] = value
+		}
+	}
+	for key, value := range before {
+		if _, ok := after[key]; !ok {
+			b[key] = value
+		}
+	}
+	return b, a
+}
+
+func redactSecrets(changes map[string]interface{}) map[string]interface{} {
+	for key, value := range changes {
+		if secretFields[key] && value != nil && value != "" {
+			changes[key] = redacted
+		}
+	}
+	return changes
+}
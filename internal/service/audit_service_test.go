@@ -0,0 +1,73 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditService_RecordsAuthEvents(t *testing.T) {
+	db := setupTestDB()
+	authService := newAuthService(db)
+	auditService := newAuditService(db)
+	ctx := service.WithActor(context.Background(), service.Actor{IP: "192.0.2.1", UserAgent: "test-agent"})
+
+	user := &domain.User{Name: "Test", Email: "test@example.com", Password: "secret123"}
+	require.NoError(t, authService.SignUp(ctx, user))
+	_, err := authService.Login(ctx, "test@example.com", "wrongpassword")
+	assert.Error(t, err)
+	_, err = authService.Login(ctx, "test@example.com", "secret123")
+	require.NoError(t, err)
+
+	events, err := auditService.List(domain.AuditFilter{TargetID: &user.ID}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+
+	// 新しい順に返る
+	assert.Equal(t, domain.AuditLogin, events[0].Action)
+	assert.Equal(t, domain.AuditLoginFailed, events[1].Action)
+	assert.Nil(t, events[1].ActorID, "failed login has no authenticated actor")
+	assert.Equal(t, domain.AuditSignup, events[2].Action)
+
+	login := events[0]
+	require.NotNil(t, login.ActorID)
+	assert.Equal(t, user.ID, *login.ActorID)
+	assert.Equal(t, "192.0.2.1", login.IP)
+	assert.Equal(t, "test-agent", login.UserAgent)
+
+	// サインアップ時のパスワード（ハッシュ）は残さない
+	assert.Equal(t, "[REDACTED]", events[2].After["password"])
+	assert.Equal(t, "test@example.com", events[2].After["email"])
+}
+
+func TestAuditService_RecordsOnlyChangedFields(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userService := newUserService(db, time.Hour)
+	auditService := newAuditService(db)
+
+	user := &domain.User{Name: "Before", Email: "test@example.com", Password: "secret123"}
+	require.NoError(t, repository.NewUserRepository(db).Create(user))
+	name, password := "After", "newsecret456"
+	_, err := userService.PatchUser(ctx, user.ID, user.Version, domain.UserPatch{Name: &name, Password: &password})
+	require.NoError(t, err)
+
+	events, err := auditService.List(domain.AuditFilter{Action: domain.AuditUserUpdated}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Nil(t, events[0].ActorID, "system operation")
+	// 変更のないメールアドレスなどは含めず、パスワードは伏せる
+	assert.Equal(t, map[string]interface{}{"name": "Before", "password": "[REDACTED]"}, events[0].Before)
+	assert.Equal(t, map[string]interface{}{"name": "After", "password": "[REDACTED]"}, events[0].After)
+
+	from := time.Now().Add(time.Hour)
+	events, err = auditService.List(domain.AuditFilter{From: &from}, 1, 10)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
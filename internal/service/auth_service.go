@@ -1,70 +1,398 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
+	"log"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/okamuuu/go-user-app/internal/domain"
 	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/signing"
 	"golang.org/x/crypto/bcrypt"
 )
 
+var (
+	ErrInvalidCredentials  = domain.NewError(domain.ErrUnauthorized, "invalid_credentials", "invalid email or password")
+	ErrInvalidRefreshToken = domain.NewError(domain.ErrUnauthorized, "invalid_refresh_token", "invalid refresh token")
+	ErrRefreshTokenReused  = domain.NewError(domain.ErrUnauthorized, "refresh_token_reused", "refresh token reuse detected")
+	ErrInvalidMFAChallenge = domain.NewError(domain.ErrUnauthorized, "invalid_mfa_challenge", "invalid MFA challenge")
+)
+
+// MFA チャレンジトークンの有効期限
+const mfaChallengeExpiry = 5 * time.Minute
+
 type AuthService struct {
-	repo        *repository.UserRepository
-	jwtSecret   []byte
-	tokenExpiry time.Duration
+	repo              domain.UserStore
+	emailVerification *EmailVerificationService
+	mfa               *MFAService
+	audit             *AuditService
+	refreshRepo       *repository.RefreshTokenRepository
+	revocationRepo    *repository.RevocationRepository
+	keys              *signing.KeySet
+	tokenExpiry       time.Duration
+	refreshExpiry     time.Duration
+}
+
+// TokenPair はログイン・リフレッシュ時に返すトークンの組
+type TokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int64 // アクセストークンの有効秒数
+}
+
+// LoginResult はログインの結果
+// MFA が有効なユーザーの場合、Tokens は nil で MFAChallenge が設定される
+type LoginResult struct {
+	Tokens       *TokenPair
+	MFAChallenge string
+}
+
+// MFARequired は二段階目の認証が必要かどうかを返す
+func (r *LoginResult) MFARequired() bool {
+	return r.MFAChallenge != ""
 }
 
-func NewAuthService(repo *repository.UserRepository, jwtSecret []byte, tokenExpiry time.Duration) *AuthService {
+func NewAuthService(
+	repo domain.UserStore,
+	emailVerification *EmailVerificationService,
+	mfa *MFAService,
+	audit *AuditService,
+	refreshRepo *repository.RefreshTokenRepository,
+	revocationRepo *repository.RevocationRepository,
+	keys *signing.KeySet,
+	tokenExpiry, refreshExpiry time.Duration,
+) *AuthService {
 	return &AuthService{
-		repo:        repo,
-		jwtSecret:   jwtSecret,
-		tokenExpiry: tokenExpiry,
+		repo:              repo,
+		emailVerification: emailVerification,
+		mfa:               mfa,
+		audit:             audit,
+		refreshRepo:       refreshRepo,
+		revocationRepo:    revocationRepo,
+		keys:              keys,
+		tokenExpiry:       tokenExpiry,
+		refreshExpiry:     refreshExpiry,
 	}
 }
 
-func (s *AuthService) SignUp(user *domain.User) error {
-	user.Password = HashPassword(user.Password)
-	return s.repo.Create(user)
+func (s *AuthService) SignUp(ctx context.Context, user *domain.User) error {
+	hashed, err := HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	if err := s.repo.Create(user); err != nil {
+		return err
+	}
+	s.audit.Record(withActorUser(ctx, user.ID), domain.AuditSignup, &user.ID, nil, userSnapshot(user))
+
+	// 確認メールの送信に失敗しても登録自体は成功とし、再送で対応する
+	if err := s.emailVerification.SendVerification(user, user.Email); err != nil {
+		log.Printf("[ERROR] failed to send verification email: user_id=%d: %v", user.ID, err)
+	}
+	return nil
 }
 
-func (s *AuthService) Login(email, password string) (string, error) {
+// Login はメールアドレスとパスワードで認証する。
+// MFA が有効なユーザーにはトークンを発行せず、CompleteMFALogin に渡すチャレンジトークンを返す。
+func (s *AuthService) Login(ctx context.Context, email, password string) (*LoginResult, error) {
 	user, err := s.repo.FindByEmail(email)
 	if err != nil {
-		return "", err
+		if errors.Is(err, domain.ErrUserNotFound) {
+			s.audit.Record(ctx, domain.AuditLoginFailed, nil, nil, map[string]interface{}{"email": email})
+			// 登録されているアドレスかどうかを区別できないようにする
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("invalid credentials")
+		s.audit.Record(ctx, domain.AuditLoginFailed, &user.ID, nil, map[string]interface{}{"email": email})
+		return nil, ErrInvalidCredentials
+	}
+	ctx = withActorUser(ctx, user.ID)
+
+	mfaEnabled, err := s.mfa.IsEnabled(user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if mfaEnabled {
+		challenge, err := s.generateMFAChallenge(user)
+		if err != nil {
+			return nil, err
+		}
+		s.audit.Record(ctx, domain.AuditMFAChallenged, &user.ID, nil, nil)
+		return &LoginResult{MFAChallenge: challenge}, nil
+	}
+
+	tokens, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditLogin, &user.ID, nil, nil)
+	return &LoginResult{Tokens: tokens}, nil
+}
+
+// CompleteMFALogin はチャレンジトークンと MFA コード（TOTP またはリカバリーコード）を検証してトークンを発行する。
+// チャレンジは1回しか使えない。コードを間違えた場合はパスワードからやり直す。
+func (s *AuthService) CompleteMFALogin(ctx context.Context, challenge, code string) (*TokenPair, error) {
+	userID, jti, expiresAt, err := s.parseMFAChallenge(challenge)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	first, err := s.revocationRepo.ConsumeToken(jti, userID, expiresAt)
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		return nil, ErrInvalidMFAChallenge
+	}
+
+	if err := s.mfa.VerifyCode(userID, code); err != nil {
+		s.audit.Record(ctx, domain.AuditLoginFailed, &userID, nil, nil)
+		return nil, err
 	}
 
-	token, err := s.GenerateJWT(user)
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, ErrInvalidMFAChallenge
+	}
+	tokens, err := s.startSession(user)
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(withActorUser(ctx, user.ID), domain.AuditMFALogin, &user.ID, nil, nil)
+	return tokens, nil
+}
+
+// startSession は新しいリフレッシュトークンの系列を作ってトークンを発行する
+func (s *AuthService) startSession(user *domain.User) (*TokenPair, error) {
+	familyID, err := randomToken(16)
+	if err != nil {
+		return nil, err
+	}
+	return s.issueTokens(user, familyID, nil)
+}
+
+// generateMFAChallenge はパスワード認証済みであることを示す短命のトークンを発行する。
+// user_id クレームを持たないため、アクセストークンとしては使えない。
+func (s *AuthService) generateMFAChallenge(user *domain.User) (string, error) {
+	// 使い終わったチャレンジを記録して再利用を防ぐための ID
+	jti, err := randomToken(16)
 	if err != nil {
 		return "", err
 	}
+	claims := jwt.MapClaims{
+		"typ": "mfa",
+		"jti": jti,
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+		"exp": time.Now().Add(mfaChallengeExpiry).Unix(),
+		"iat": time.Now().Unix(),
+	}
+	return s.keys.Sign(claims)
+}
 
-	return token, nil
+func (s *AuthService) parseMFAChallenge(challenge string) (userID uint, jti string, expiresAt time.Time, err error) {
+	claims := jwt.MapClaims{}
+	if _, err := s.keys.Parse(challenge, claims); err != nil {
+		return 0, "", time.Time{}, err
+	}
+	if typ, _ := claims["typ"].(string); typ != "mfa" {
+		return 0, "", time.Time{}, ErrInvalidMFAChallenge
+	}
+	jti, _ = claims["jti"].(string)
+	if jti == "" {
+		return 0, "", time.Time{}, ErrInvalidMFAChallenge
+	}
+	exp, err := claims.GetExpirationTime()
+	if err != nil || exp == nil {
+		return 0, "", time.Time{}, ErrInvalidMFAChallenge
+	}
+	sub, err := claims.GetSubject()
+	if err != nil {
+		return 0, "", time.Time{}, err
+	}
+	id, err := strconv.ParseUint(sub, 10, 32)
+	if err != nil {
+		return 0, "", time.Time{}, err
+	}
+	return uint(id), jti, exp.Time, nil
+}
+
+// Refresh はリフレッシュトークンをローテーションし、新しいトークンの組を発行する。
+// 既にローテーション済みのトークンが提示された場合は漏洩とみなし、系列ごと失効させる。
+func (s *AuthService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	current, err := s.refreshRepo.FindByHash(hashToken(refreshToken))
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if current.IsRevoked() {
+		s.revokeFamily(ctx, current)
+		return nil, ErrRefreshTokenReused
+	}
+	if current.IsExpired(time.Now()) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	user, err := s.repo.FindByID(current.UserID)
+	if err != nil {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	pair, err := s.issueTokens(user, current.FamilyID, current)
+	if errors.Is(err, repository.ErrRefreshTokenRevoked) {
+		// 並行リクエストで先にローテーションされた
+		s.revokeFamily(ctx, current)
+		return nil, ErrRefreshTokenReused
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.audit.Record(withActorUser(ctx, user.ID), domain.AuditTokenRefreshed, &user.ID, nil, nil)
+	return pair, nil
+}
+
+// issueTokens はアクセストークンと新しいリフレッシュトークンを発行する。
+// previous が指定された場合はそのトークンをローテーションする。
+func (s *AuthService) issueTokens(user *domain.User, familyID string, previous *domain.RefreshToken) (*TokenPair, error) {
+	accessToken, err := s.GenerateJWT(user)
+	if err != nil {
+		return nil, err
+	}
+
+	plain, err := randomToken(32)
+	if err != nil {
+		return nil, err
+	}
+
+	next := &domain.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyID,
+		TokenHash: hashToken(plain),
+		ExpiresAt: time.Now().Add(s.refreshExpiry),
+	}
+
+	if previous == nil {
+		err = s.refreshRepo.Create(next)
+	} else {
+		err = s.refreshRepo.Rotate(previous, next)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: plain,
+		ExpiresIn:    int64(s.tokenExpiry.Seconds()),
+	}, nil
+}
+
+// Logout は現在のアクセストークンを失効させる。
+// refreshToken が指定された場合は、その系列のリフレッシュトークンも失効させる。
+func (s *AuthService) Logout(ctx context.Context, userID uint, jti string, expiresAt time.Time, refreshToken string) error {
+	if err := s.revocationRepo.RevokeToken(jti, userID, expiresAt); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditLogout, &userID, nil, nil)
+
+	if refreshToken == "" {
+		return nil
+	}
+	token, err := s.refreshRepo.FindByHash(hashToken(refreshToken))
+	if err != nil || token.UserID != userID {
+		// 他人のトークンや存在しないトークンは無視する
+		return nil
+	}
+	return s.refreshRepo.RevokeFamily(token.FamilyID)
+}
+
+// LogoutAll はユーザーの全セッション（アクセストークン・リフレッシュトークン）を失効させる
+func (s *AuthService) LogoutAll(ctx context.Context, userID uint) error {
+	if _, err := s.repo.FindByID(userID); err != nil {
+		return err
+	}
+	if err := s.RevokeSessions(userID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditLogoutAll, &userID, nil, nil)
+	return nil
+}
+
+// RevokeSessions はユーザーのアクセストークンとリフレッシュトークンをすべて失効させる。
+// ユーザーの存在は確認せず、監査ログも記録しない（呼び出し側の操作として記録する）
+func (s *AuthService) RevokeSessions(userID uint) error {
+	if err := s.revocationRepo.RevokeAllForUser(userID, sessionRevocationTime()); err != nil {
+		return err
+	}
+	return s.refreshRepo.RevokeAllForUser(userID)
+}
+
+// sessionRevocationTime は一括失効の基準の時刻を返す。
+// iat はミリ秒精度なので、同じミリ秒に発行されたトークンも失効対象に含める
+func sessionRevocationTime() time.Time {
+	return time.Now().Truncate(time.Millisecond)
+}
+
+// ChangeRole はユーザーのロールを変更する。
+// トークンに含まれるロールを即座に反映させるため、既存のセッションは失効させる。
+func (s *AuthService) ChangeRole(ctx context.Context, userID uint, role domain.Role) error {
+	before, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.UpdateRole(userID, role); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditRoleChanged, &userID,
+		map[string]interface{}{"role": string(before.Role)},
+		map[string]interface{}{"role": string(role)},
+	)
+	return s.LogoutAll(ctx, userID)
+}
+
+// IsRevoked はアクセストークンが失効済みかどうかを返す（AuthMiddleware から利用）
+func (s *AuthService) IsRevoked(jti string, userID uint, issuedAt time.Time) (bool, error) {
+	return s.revocationRepo.IsRevoked(jti, userID, issuedAt)
+}
+
+func (s *AuthService) revokeFamily(ctx context.Context, token *domain.RefreshToken) {
+	log.Printf("[WARN] refresh token reuse detected: user_id=%d family=%s", token.UserID, token.FamilyID)
+	s.audit.Record(ctx, domain.AuditRefreshTokenReused, &token.UserID, nil, map[string]interface{}{"family_id": token.FamilyID})
+	if err := s.refreshRepo.RevokeFamily(token.FamilyID); err != nil {
+		log.Printf("[ERROR] failed to revoke token family %s: %v", token.FamilyID, err)
+	}
 }
 
 func (s *AuthService) GenerateJWT(user *domain.User) (string, error) {
+	jti, err := randomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
 	claims := jwt.MapClaims{
+		"jti":     jti,
 		"user_id": user.ID,
-		"exp":     time.Now().Add(s.tokenExpiry).Unix(),
-		"iat":     time.Now().Unix(),
+		"role":    string(user.Role),
+		"exp":     now.Add(s.tokenExpiry).Unix(),
+		// 一括失効の直後に発行したトークンを区別できるよう、iat はミリ秒まで持つ（秒の小数）
+		"iat": float64(now.UnixMilli()) / 1000,
 	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	return s.keys.Sign(claims)
 }
 
 func (s *AuthService) ValidateJWT(tokenString string) (*jwt.RegisteredClaims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &jwt.RegisteredClaims{}, func(token *jwt.Token) (interface{}, error) {
-		// 署名方法のチェック（HS256想定）
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, errors.New("unexpected signing method")
-		}
-		return s.jwtSecret, nil
-	})
+	// 署名方法のチェックは kid に対応する鍵の種類で行う
+	token, err := s.keys.Parse(tokenString, &jwt.RegisteredClaims{})
 	if err != nil {
 		return nil, err
 	}
@@ -76,10 +404,30 @@ func (s *AuthService) ValidateJWT(tokenString string) (*jwt.RegisteredClaims, er
 	return nil, errors.New("invalid token")
 }
 
-func HashPassword(password string) string {
+// HashPassword はパスワードを bcrypt でハッシュ化する。
+// domain.MaxPasswordBytes より長いパスワードは domain.ErrPasswordTooLong を返す
+func HashPassword(password string) (string, error) {
+	if len(password) > domain.MaxPasswordBytes {
+		return "", domain.ErrPasswordTooLong
+	}
 	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 	if err != nil {
-		panic(err)
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// randomToken は推測不能なランダム文字列を生成する
+func randomToken(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
-	return string(hashed)
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken はトークンをDB保存用にハッシュ化する
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
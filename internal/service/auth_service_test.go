@@ -1,12 +1,15 @@
 package service_test
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/notify"
 	"github.com/okamuuu/go-user-app/internal/repository"
 	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/okamuuu/go-user-app/internal/signing"
 	"github.com/stretchr/testify/assert"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
@@ -14,44 +17,237 @@ import (
 
 func setupTestDB() *gorm.DB {
 	db, _ := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
-	db.AutoMigrate(&repository.User{})
+	db.AutoMigrate(&repository.User{}, &repository.RefreshToken{}, &repository.RevokedToken{}, &repository.UserTokenRevocation{}, &repository.PasswordResetToken{}, &repository.EmailVerificationToken{}, &repository.MFACredential{}, &repository.MFARecoveryCode{}, &repository.AuditLog{}, &repository.ErasureRecord{})
 	return db
 }
 
+// hashPassword はテスト用にパスワードをハッシュ化する
+func hashPassword(password string) string {
+	hashed, err := service.HashPassword(password)
+	if err != nil {
+		panic(err)
+	}
+	return hashed
+}
+
+func newEmailVerificationService(db *gorm.DB, notifier notify.Notifier) *service.EmailVerificationService {
+	return service.NewEmailVerificationService(
+		repository.NewUserRepository(db),
+		repository.NewEmailVerificationRepository(db),
+		newAuditService(db),
+		notifier,
+		"http://localhost:3000/email/verify",
+		time.Hour,
+	)
+}
+
+func newMFAService(db *gorm.DB) *service.MFAService {
+	return service.NewMFAService(repository.NewUserRepository(db), repository.NewMFARepository(db), newAuditService(db), "Go User App")
+}
+
+func newAuditService(db *gorm.DB) *service.AuditService {
+	return service.NewAuditService(repository.NewAuditRepository(db))
+}
+
+func newAuthService(db *gorm.DB) *service.AuthService {
+	return service.NewAuthService(
+		repository.NewUserRepository(db),
+		newEmailVerificationService(db, &recordingNotifier{}),
+		newMFAService(db),
+		newAuditService(db),
+		repository.NewRefreshTokenRepository(db),
+		repository.NewRevocationRepository(db),
+		signing.NewHMACKeySet([]byte("testsecret")),
+		time.Minute,
+		time.Hour,
+	)
+}
+
 func TestAuthService_Login_Success(t *testing.T) {
 	db := setupTestDB()
+	ctx := context.Background()
 	userRepo := repository.NewUserRepository(db)
 
 	// 事前にユーザー作成しておく
 	userRepo.Create(&domain.User{
 		Name:     "test",
 		Email:    "test@example.com",
-		Password: service.HashPassword("secret123"),
+		Password: hashPassword("secret123"),
 	})
 
-	authService := service.NewAuthService(userRepo, []byte("testsecret"), time.Hour)
+	authService := newAuthService(db)
 
 	// 実行
-	token, err := authService.Login("test@example.com", "secret123")
+	result, err := authService.Login(ctx, "test@example.com", "secret123")
 
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+	assert.False(t, result.MFARequired())
+	assert.NotEmpty(t, result.Tokens.AccessToken)
+	assert.NotEmpty(t, result.Tokens.RefreshToken)
 }
 
 func TestAuthService_Login_InvalidPassword(t *testing.T) {
 	db := setupTestDB()
+	ctx := context.Background()
 	userRepo := repository.NewUserRepository(db)
 
 	userRepo.Create(&domain.User{
 		Name:     "test",
 		Email:    "test@example.com",
-		Password: service.HashPassword("secret123"),
+		Password: hashPassword("secret123"),
 	})
 
-	authService := service.NewAuthService(userRepo, []byte("testsecret"), time.Hour)
+	authService := newAuthService(db)
 
-	token, err := authService.Login("test@example.com", "wrongpassword")
+	result, err := authService.Login(ctx, "test@example.com", "wrongpassword")
 
 	assert.Error(t, err)
-	assert.Empty(t, token)
+	assert.Nil(t, result)
+}
+
+func TestAuthService_SignUp_DuplicateEmail(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	authService := newAuthService(db)
+
+	user := &domain.User{Name: "test", Email: "Test@Example.com", Password: "secret123"}
+	assert.NoError(t, authService.SignUp(ctx, user))
+	assert.Equal(t, "test@example.com", user.Email)
+
+	// 大文字小文字だけが違うアドレスでは登録できない
+	err := authService.SignUp(ctx, &domain.User{Name: "other", Email: " TEST@example.com", Password: "secret123"})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+	assert.ErrorIs(t, err, domain.ErrConflict)
+
+	// ログインもアドレスの大文字小文字を区別しない
+	result, err := authService.Login(ctx, "TEST@example.com", "secret123")
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+}
+
+func TestAuthService_Refresh_Rotation(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+
+	userRepo.Create(&domain.User{
+		Name:     "test",
+		Email:    "test@example.com",
+		Password: hashPassword("secret123"),
+	})
+
+	authService := newAuthService(db)
+
+	login, err := authService.Login(ctx, "test@example.com", "secret123")
+	assert.NoError(t, err)
+
+	// ローテーションされて別のトークンが返る
+	refreshed, err := authService.Refresh(ctx, login.Tokens.RefreshToken)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, refreshed.AccessToken)
+	assert.NotEqual(t, login.Tokens.RefreshToken, refreshed.RefreshToken)
+
+	// 新しいトークンでさらにリフレッシュできる
+	_, err = authService.Refresh(ctx, refreshed.RefreshToken)
+	assert.NoError(t, err)
+}
+
+func TestAuthService_Refresh_ReuseRevokesFamily(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+
+	userRepo.Create(&domain.User{
+		Name:     "test",
+		Email:    "test@example.com",
+		Password: hashPassword("secret123"),
+	})
+
+	authService := newAuthService(db)
+
+	login, err := authService.Login(ctx, "test@example.com", "secret123")
+	assert.NoError(t, err)
+
+	refreshed, err := authService.Refresh(ctx, login.Tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	// 使用済みトークンの再利用
+	_, err = authService.Refresh(ctx, login.Tokens.RefreshToken)
+	assert.ErrorIs(t, err, service.ErrRefreshTokenReused)
+
+	// 系列ごと失効しているので、最新のトークンも使えない
+	_, err = authService.Refresh(ctx, refreshed.RefreshToken)
+	assert.Error(t, err)
+
+	_, err = authService.Refresh(ctx, "unknown-token")
+	assert.ErrorIs(t, err, service.ErrInvalidRefreshToken)
+}
+
+func TestAuthService_Logout(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+
+	userRepo.Create(&domain.User{
+		Name:     "test",
+		Email:    "test@example.com",
+		Password: hashPassword("secret123"),
+	})
+	user, _ := userRepo.FindByEmail("test@example.com")
+
+	authService := newAuthService(db)
+
+	login, err := authService.Login(ctx, "test@example.com", "secret123")
+	assert.NoError(t, err)
+
+	err = authService.Logout(ctx, user.ID, "jti-1", time.Now().Add(time.Minute), login.Tokens.RefreshToken)
+	assert.NoError(t, err)
+
+	revoked, err := authService.IsRevoked("jti-1", user.ID, time.Now())
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = authService.IsRevoked("jti-2", user.ID, time.Now())
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	// リフレッシュトークンも失効している
+	_, err = authService.Refresh(ctx, login.Tokens.RefreshToken)
+	assert.Error(t, err)
+}
+
+func TestAuthService_LogoutAll(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+
+	userRepo.Create(&domain.User{
+		Name:     "test",
+		Email:    "test@example.com",
+		Password: hashPassword("secret123"),
+	})
+	user, _ := userRepo.FindByEmail("test@example.com")
+
+	authService := newAuthService(db)
+
+	login, err := authService.Login(ctx, "test@example.com", "secret123")
+	assert.NoError(t, err)
+
+	issuedAt := time.Now().Add(-time.Minute)
+	assert.NoError(t, authService.LogoutAll(ctx, user.ID))
+
+	// 一括失効以前に発行されたトークンはすべて無効
+	revoked, err := authService.IsRevoked("any-jti", user.ID, issuedAt)
+	assert.NoError(t, err)
+	assert.True(t, revoked)
+
+	// 一括失効後に発行されたトークンは有効
+	revoked, err = authService.IsRevoked("any-jti", user.ID, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.False(t, revoked)
+
+	_, err = authService.Refresh(ctx, login.Tokens.RefreshToken)
+	assert.Error(t, err)
+
+	assert.Error(t, authService.LogoutAll(ctx, 9999))
 }
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/notify"
+	"github.com/okamuuu/go-user-app/internal/repository"
+)
+
+var (
+	ErrInvalidVerificationToken = domain.NewError(domain.ErrValidation, "invalid_verification_token", "invalid or expired email verification token")
+	ErrEmailAlreadyVerified     = domain.NewError(domain.ErrConflict, "email_already_verified", "email already verified")
+	ErrEmailAlreadyExists       = domain.ErrEmailTaken
+)
+
+type EmailVerificationService struct {
+	repo        domain.UserStore
+	verifyRepo  *repository.EmailVerificationRepository
+	audit       *AuditService
+	mailer      notify.Notifier
+	verifyURL   string
+	tokenExpiry time.Duration
+}
+
+func NewEmailVerificationService(
+	repo domain.UserStore,
+	verifyRepo *repository.EmailVerificationRepository,
+	audit *AuditService,
+	mailer notify.Notifier,
+	verifyURL string,
+	tokenExpiry time.Duration,
+) *EmailVerificationService {
+	return &EmailVerificationService{
+		repo:        repo,
+		verifyRepo:  verifyRepo,
+		audit:       audit,
+		mailer:      mailer,
+		verifyURL:   verifyURL,
+		tokenExpiry: tokenExpiry,
+	}
+}
+
+// SendVerification は email 宛に所有確認用のリンクを送信する。
+// email がユーザーの現在のアドレスと異なる場合は、確認が済むまで変更は反映されない。
+func (s *EmailVerificationService) SendVerification(user *domain.User, email string) error {
+	plain, err := randomToken(32)
+	if err != nil {
+		return err
+	}
+
+	token := &domain.EmailVerificationToken{
+		UserID:    user.ID,
+		Email:     email,
+		TokenHash: hashToken(plain),
+		ExpiresAt: time.Now().Add(s.tokenExpiry),
+	}
+	if err := s.verifyRepo.Create(token); err != nil {
+		return err
+	}
+
+	return s.mailer.Send(notify.Message{
+		To:      email,
+		Subject: "メールアドレスの確認",
+		Body: fmt.Sprintf(
+			"以下のリンクからメールアドレスを確認してください（有効期限: %d時間）。\n%s\n\nお心当たりがない場合はこのメールを破棄してください。",
+			int(s.tokenExpiry.Hours()),
+			s.verifyLink(plain),
+		),
+	})
+}
+
+// Resend は現在のメールアドレスが未確認のユーザーに確認リンクを再送する
+func (s *EmailVerificationService) Resend(userID uint) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	if user.IsEmailVerified() {
+		return ErrEmailAlreadyVerified
+	}
+	return s.SendVerification(user, user.Email)
+}
+
+// Confirm はトークンを検証してメールアドレスを確認済みにする。
+// 新しいメールアドレスに切り替わった場合は監査ログに残す。
+func (s *EmailVerificationService) Confirm(ctx context.Context, plainToken string) error {
+	token, err := s.verifyRepo.FindByHash(hashToken(plainToken))
+	if err != nil || !token.IsUsable(time.Now()) {
+		return ErrInvalidVerificationToken
+	}
+	before, err := s.repo.FindByID(token.UserID)
+	if err != nil {
+		return ErrInvalidVerificationToken
+	}
+
+	err = s.verifyRepo.Consume(token)
+	switch {
+	case errors.Is(err, repository.ErrEmailVerificationTokenUsed):
+		return ErrInvalidVerificationToken
+	case errors.Is(err, repository.ErrEmailTaken):
+		return ErrEmailAlreadyExists
+	case err != nil:
+		return err
+	}
+
+	if before.Email != token.Email {
+		after, err := s.repo.FindByID(token.UserID)
+		if err != nil {
+			return err
+		}
+		s.audit.Record(withActorUser(ctx, token.UserID), domain.AuditEmailChanged, &token.UserID, userSnapshot(before), userSnapshot(after))
+	}
+	return nil
+}
+
+func (s *EmailVerificationService) verifyLink(token string) string {
+	return s.verifyURL + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,118 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailVerificationService_SignUp(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	notifier := &recordingNotifier{}
+	verification := newEmailVerificationService(db, notifier)
+	userService := service.NewUserService(userRepo, verification, newAuthService(db), newAuditService(db), newCursorCodec(), 30*24*time.Hour)
+
+	user := &domain.User{Name: "test", Email: "test@example.com", Password: "secret123"}
+	require.NoError(t, userService.CreateUser(ctx, user))
+
+	created, _ := userRepo.FindByEmail("test@example.com")
+	assert.False(t, created.IsEmailVerified())
+
+	require.Len(t, notifier.messages, 1)
+	assert.Equal(t, "test@example.com", notifier.messages[0].To)
+	token := extractToken(t, notifier.messages[0].Body)
+
+	require.NoError(t, verification.Confirm(ctx, token))
+
+	verified, _ := userRepo.FindByEmail("test@example.com")
+	assert.True(t, verified.IsEmailVerified())
+
+	// トークンは一度しか使えない
+	assert.ErrorIs(t, verification.Confirm(ctx, token), service.ErrInvalidVerificationToken)
+	// 確認済みなら再送しない
+	assert.ErrorIs(t, verification.Resend(verified.ID), service.ErrEmailAlreadyVerified)
+}
+
+func TestEmailVerificationService_EmailChange(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	notifier := &recordingNotifier{}
+	verification := newEmailVerificationService(db, notifier)
+	userService := service.NewUserService(userRepo, verification, newAuthService(db), newAuditService(db), newCursorCodec(), 30*24*time.Hour)
+
+	require.NoError(t, userService.CreateUser(ctx, &domain.User{Name: "a", Email: "a@example.com", Password: "secret123"}))
+	require.NoError(t, userService.CreateUser(ctx, &domain.User{Name: "b", Email: "b@example.com", Password: "secret123"}))
+	user, _ := userRepo.FindByEmail("a@example.com")
+
+	// 他のユーザーが使っているアドレスには変更できない
+	taken, email := "b@example.com", "new@example.com"
+	_, err := userService.PatchUser(ctx, user.ID, user.Version, domain.UserPatch{Email: &taken})
+	assert.ErrorIs(t, err, service.ErrEmailAlreadyExists)
+
+	_, err = userService.PatchUser(ctx, user.ID, user.Version, domain.UserPatch{Email: &email})
+	require.NoError(t, err)
+
+	// 確認前は古いアドレスのまま
+	pending, _ := userRepo.FindByID(user.ID)
+	assert.Equal(t, "a@example.com", pending.Email)
+
+	last := notifier.messages[len(notifier.messages)-1]
+	assert.Equal(t, "new@example.com", last.To)
+	require.NoError(t, verification.Confirm(ctx, extractToken(t, last.Body)))
+
+	changed, _ := userRepo.FindByID(user.ID)
+	assert.Equal(t, "new@example.com", changed.Email)
+	assert.True(t, changed.IsEmailVerified())
+
+	// アドレスが切り替わったことは本人の操作として監査ログに残る
+	events, err := newAuditService(db).List(domain.AuditFilter{Action: domain.AuditEmailChanged}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, &user.ID, events[0].ActorID)
+	assert.Equal(t, "a@example.com", events[0].Before["email"])
+	assert.Equal(t, "new@example.com", events[0].After["email"])
+	assert.NotContains(t, events[0].After, "password")
+}
+
+func TestEmailVerificationService_PurposesAreIndependent(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	notifier := &recordingNotifier{}
+	verification := newEmailVerificationService(db, notifier)
+	userService := service.NewUserService(userRepo, verification, newAuthService(db), newAuditService(db), newCursorCodec(), 30*24*time.Hour)
+
+	require.NoError(t, userService.CreateUser(ctx, &domain.User{Name: "a", Email: "a@example.com", Password: "secret123"}))
+	user, _ := userRepo.FindByEmail("a@example.com")
+	signupToken := extractToken(t, notifier.messages[0].Body)
+
+	// アドレス変更の確認を2回送ると、古い方の変更のリンクだけが使えなくなる
+	first, second := "first@example.com", "second@example.com"
+	_, err := userService.PatchUser(ctx, user.ID, user.Version, domain.UserPatch{Email: &first})
+	require.NoError(t, err)
+	firstToken := extractToken(t, notifier.messages[1].Body)
+	_, err = userService.PatchUser(ctx, user.ID, user.Version, domain.UserPatch{Email: &second})
+	require.NoError(t, err)
+	secondToken := extractToken(t, notifier.messages[2].Body)
+
+	// 今のアドレスの確認を送り直しても、変更のリンクは使えるまま
+	require.NoError(t, verification.Resend(user.ID))
+	resentToken := extractToken(t, notifier.messages[3].Body)
+
+	assert.ErrorIs(t, verification.Confirm(ctx, signupToken), service.ErrInvalidVerificationToken)
+	assert.ErrorIs(t, verification.Confirm(ctx, firstToken), service.ErrInvalidVerificationToken)
+	require.NoError(t, verification.Confirm(ctx, resentToken))
+	require.NoError(t, verification.Confirm(ctx, secondToken))
+
+	changed, _ := userRepo.FindByID(user.ID)
+	assert.Equal(t, "second@example.com", changed.Email)
+}
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/totp"
+	qrcode "github.com/skip2/go-qrcode"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrInvalidMFACode    = domain.NewError(domain.ErrValidation, "invalid_mfa_code", "invalid MFA code")
+	ErrMFAAlreadyEnabled = domain.NewError(domain.ErrConflict, "mfa_already_enabled", "MFA already enabled")
+	ErrMFANotEnrolled    = domain.NewError(domain.ErrValidation, "mfa_not_enrolled", "MFA not enrolled")
+	ErrMFALocked         = domain.NewError(domain.ErrTooManyRequests, "mfa_locked", "too many invalid MFA codes, try again later")
+)
+
+const (
+	recoveryCodeCount = 10
+	// 端末の時刻ずれを考慮して前後1ステップ（±30秒）まで許容する
+	totpSkew = 1
+	// 続けて mfaMaxAttempts 回コードを間違えると、mfaLockout の間コードを受け付けない
+	mfaMaxAttempts = 5
+	mfaLockout     = 15 * time.Minute
+)
+
+type MFAService struct {
+	repo    domain.UserStore
+	mfaRepo *repository.MFARepository
+	audit   *AuditService
+	issuer  string
+}
+
+// TOTPEnrollment は認証アプリへの登録に必要な情報
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+	QRCode []byte // URI を埋め込んだ QR コードの PNG
+}
+
+func NewMFAService(repo domain.UserStore, mfaRepo *repository.MFARepository, audit *AuditService, issuer string) *MFAService {
+	return &MFAService{repo: repo, mfaRepo: mfaRepo, audit: audit, issuer: issuer}
+}
+
+// Enroll は新しい TOTP シークレットを発行する。
+// Activate でコードが確認されるまでは MFA は有効にならない。
+func (s *MFAService) Enroll(userID uint) (*TOTPEnrollment, error) {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled, err := s.IsEnabled(userID); err != nil {
+		return nil, err
+	} else if enabled {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.SavePending(userID, secret); err != nil {
+		return nil, err
+	}
+
+	uri := totp.URI(s.issuer, user.Email, secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TOTPEnrollment{Secret: secret, URI: uri, QRCode: png}, nil
+}
+
+// Activate は認証アプリのコードを確認して MFA を有効にし、リカバリーコードを返す。
+// リカバリーコードの平文はここでしか取得できない。
+func (s *MFAService) Activate(ctx context.Context, userID uint, code string) ([]string, error) {
+	cred, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, ErrMFANotEnrolled
+	}
+	if cred.IsEnabled() {
+		return nil, ErrMFAAlreadyEnabled
+	}
+
+	step, ok := totp.Validate(cred.Secret, code, time.Now(), totpSkew)
+	if !ok {
+		return nil, ErrInvalidMFACode
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := s.mfaRepo.Enable(userID, step, hashes); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditMFAEnabled, &userID, mfaSnapshot(false), mfaSnapshot(true))
+	return codes, nil
+}
+
+// Disable はコードを確認して MFA を無効にする
+func (s *MFAService) Disable(ctx context.Context, userID uint, code string) error {
+	if err := s.VerifyCode(userID, code); err != nil {
+		return err
+	}
+	if err := s.mfaRepo.Disable(userID); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditMFADisabled, &userID, mfaSnapshot(true), mfaSnapshot(false))
+	return nil
+}
+
+// IsEnabled はユーザーの MFA が有効かどうかを返す
+func (s *MFAService) IsEnabled(userID uint) (bool, error) {
+	cred, err := s.mfaRepo.FindByUserID(userID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return cred.IsEnabled(), nil
+}
+
+// VerifyCode は TOTP コードまたはリカバリーコードを検証する。
+// どちらも一度使われたコードは再利用できない。
+func (s *MFAService) VerifyCode(userID uint, code string) error {
+	cred, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil || !cred.IsEnabled() {
+		return ErrMFANotEnrolled
+	}
+	// ロック中は正しいコードでも受け付けない（総当たりでロック中に当てられないようにする）
+	if cred.IsLocked(time.Now()) {
+		return ErrMFALocked
+	}
+
+	used, err := s.useCode(cred, code)
+	if err != nil {
+		return err
+	}
+	if !used {
+		locked, err := s.mfaRepo.RecordFailure(userID, mfaMaxAttempts, mfaLockout)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return ErrMFALocked
+		}
+		return ErrInvalidMFACode
+	}
+	return s.mfaRepo.ResetFailures(userID)
+}
+
+// useCode は TOTP またはリカバリーコードを検証し、使えた場合は使用済みにする
+func (s *MFAService) useCode(cred *domain.MFACredential, code string) (bool, error) {
+	if step, ok := totp.Validate(cred.Secret, code, time.Now(), totpSkew); ok {
+		return s.mfaRepo.UseStep(cred.UserID, step)
+	}
+	return s.mfaRepo.UseRecoveryCode(cred.UserID, hashToken(normalizeRecoveryCode(code)))
+}
+
+// リカバリーコードは読み間違えにくい文字だけで "XXXXX-XXXXX" 形式にする
+const recoveryCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	for i := 0; i < recoveryCodeCount; i++ {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		for j := range b {
+			b[j] = recoveryCodeAlphabet[int(b[j])%len(recoveryCodeAlphabet)]
+		}
+		code := string(b[:5]) + "-" + string(b[5:])
+		codes = append(codes, code)
+		hashes = append(hashes, hashToken(normalizeRecoveryCode(code)))
+	}
+	return codes, hashes, nil
+}
+
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,132 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/okamuuu/go-user-app/internal/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMFAService_TwoStepLogin(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	userRepo.Create(&domain.User{
+		Name:     "test",
+		Email:    "test@example.com",
+		Password: hashPassword("secret123"),
+	})
+	user, _ := userRepo.FindByEmail("test@example.com")
+
+	mfaService := newMFAService(db)
+	authService := newAuthService(db)
+
+	enrollment, err := mfaService.Enroll(user.ID)
+	require.NoError(t, err)
+	assert.Contains(t, enrollment.URI, "otpauth://totp/")
+	assert.NotEmpty(t, enrollment.QRCode)
+
+	// 登録途中ではログイン時に MFA を要求しない
+	result, err := authService.Login(ctx, "test@example.com", "secret123")
+	require.NoError(t, err)
+	assert.False(t, result.MFARequired())
+
+	_, err = mfaService.Activate(ctx, user.ID, "000000")
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+	// 有効化に使ったコードはログインに再利用できないよう、1ステップ前のコードで有効化する
+	code, _ := totp.Code(enrollment.Secret, time.Now().Add(-totp.Period))
+	recoveryCodes, err := mfaService.Activate(ctx, user.ID, code)
+	require.NoError(t, err)
+	assert.Len(t, recoveryCodes, 10)
+
+	// チャレンジは1回しか使えないため、試すたびにパスワードからログインし直す
+	challenge := func() string {
+		result, err := authService.Login(ctx, "test@example.com", "secret123")
+		require.NoError(t, err)
+		require.True(t, result.MFARequired())
+		assert.Nil(t, result.Tokens)
+		return result.MFAChallenge
+	}
+
+	used := challenge()
+	_, err = authService.CompleteMFALogin(ctx, used, "000000")
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+	_, err = authService.CompleteMFALogin(ctx, "invalid", code)
+	assert.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+
+	// 一度使ったチャレンジは正しいコードでも使えない
+	code, _ = totp.Code(enrollment.Secret, time.Now())
+	_, err = authService.CompleteMFALogin(ctx, used, code)
+	assert.ErrorIs(t, err, service.ErrInvalidMFAChallenge)
+
+	tokens, err := authService.CompleteMFALogin(ctx, challenge(), code)
+	require.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+
+	// 同じコードは再利用できない
+	_, err = authService.CompleteMFALogin(ctx, challenge(), code)
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+	// リカバリーコードは一度だけ使える（ハイフン・大文字小文字は問わない）
+	_, err = authService.CompleteMFALogin(ctx, challenge(), recoveryCodes[0])
+	assert.NoError(t, err)
+	_, err = authService.CompleteMFALogin(ctx, challenge(), recoveryCodes[0])
+	assert.ErrorIs(t, err, service.ErrInvalidMFACode)
+
+	require.NoError(t, mfaService.Disable(ctx, user.ID, recoveryCodes[1]))
+	result, err = authService.Login(ctx, "test@example.com", "secret123")
+	require.NoError(t, err)
+	assert.False(t, result.MFARequired())
+
+	// 有効化・無効化は監査ログに残り、シークレットやリカバリーコードは含まない
+	for action, enabled := range map[string]bool{domain.AuditMFAEnabled: true, domain.AuditMFADisabled: false} {
+		events, err := newAuditService(db).List(domain.AuditFilter{Action: action}, 1, 10)
+		require.NoError(t, err)
+		require.Len(t, events, 1, action)
+		assert.Equal(t, map[string]interface{}{"mfa_enabled": enabled}, events[0].After)
+		assert.Equal(t, map[string]interface{}{"mfa_enabled": !enabled}, events[0].Before)
+	}
+}
+
+func TestMFAService_LocksAfterRepeatedFailures(t *testing.T) {
+	db := setupTestDB()
+	userRepo := repository.NewUserRepository(db)
+	user := &domain.User{Name: "test", Email: "test@example.com", Password: "x"}
+	require.NoError(t, userRepo.Create(user))
+
+	mfaService := newMFAService(db)
+	enrollment, err := mfaService.Enroll(user.ID)
+	require.NoError(t, err)
+	code, _ := totp.Code(enrollment.Secret, time.Now().Add(-totp.Period))
+	recoveryCodes, err := mfaService.Activate(context.Background(), user.ID, code)
+	require.NoError(t, err)
+
+	// 成功すると間違えた回数は数え直す
+	for i := 0; i < 4; i++ {
+		assert.ErrorIs(t, mfaService.VerifyCode(user.ID, "000000"), service.ErrInvalidMFACode)
+	}
+	require.NoError(t, mfaService.VerifyCode(user.ID, recoveryCodes[0]))
+
+	for i := 0; i < 4; i++ {
+		assert.ErrorIs(t, mfaService.VerifyCode(user.ID, "000000"), service.ErrInvalidMFACode)
+	}
+	err = mfaService.VerifyCode(user.ID, "000000")
+	assert.ErrorIs(t, err, service.ErrMFALocked)
+	assert.ErrorIs(t, err, domain.ErrTooManyRequests)
+
+	// ロック中は正しいコードも受け付けず、リカバリーコードも使用済みにならない
+	assert.ErrorIs(t, mfaService.VerifyCode(user.ID, recoveryCodes[1]), service.ErrMFALocked)
+
+	// 期限が過ぎれば再び使える
+	require.NoError(t, db.Model(&repository.MFACredential{}).
+		Where("user_id = ?", user.ID).
+		Update("locked_until", time.Now().Add(-time.Second)).Error)
+	assert.NoError(t, mfaService.VerifyCode(user.ID, recoveryCodes[1]))
+}
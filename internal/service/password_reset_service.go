@@ -0,0 +1,140 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/notify"
+	"github.com/okamuuu/go-user-app/internal/repository"
+)
+
+var ErrInvalidResetToken = domain.NewError(domain.ErrValidation, "invalid_reset_token", "invalid or expired password reset token")
+
+type PasswordResetService struct {
+	repo        domain.UserStore
+	resetRepo   *repository.PasswordResetRepository
+	audit       *AuditService
+	notifier    notify.Notifier
+	resetURL    string
+	tokenExpiry time.Duration
+}
+
+func NewPasswordResetService(
+	repo domain.UserStore,
+	resetRepo *repository.PasswordResetRepository,
+	audit *AuditService,
+	notifier notify.Notifier,
+	resetURL string,
+	tokenExpiry time.Duration,
+) *PasswordResetService {
+	return &PasswordResetService{
+		repo:        repo,
+		resetRepo:   resetRepo,
+		audit:       audit,
+		notifier:    notifier,
+		resetURL:    resetURL,
+		tokenExpiry: tokenExpiry,
+	}
+}
+
+// RequestReset はパスワード再設定用のリンクを送信する。
+// メールアドレスの登録有無を推測されないよう、未登録の場合もエラーは返さない。
+func (s *PasswordResetService) RequestReset(email string) error {
+	user, err := s.repo.FindByEmail(email)
+	if err != nil {
+		log.Printf("[INFO] password reset requested for unknown email")
+		return nil
+	}
+
+	plain, err := s.issueToken(user.ID, s.tokenExpiry)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Send(notify.Message{
+		To:      user.Email,
+		Subject: "パスワード再設定のご案内",
+		Body: fmt.Sprintf(
+			"以下のリンクからパスワードを再設定してください（有効期限: %d分）。\n%s\n\nお心当たりがない場合はこのメールを破棄してください。",
+			int(s.tokenExpiry.Minutes()),
+			s.resetLink(plain),
+		),
+	})
+}
+
+// Invite は管理者が作成したユーザーに、パスワードを設定するための招待リンクを送る。
+// リンクはパスワード再設定と同じ仕組みで、有効期限は expiry（再設定より長くしてよい）
+func (s *PasswordResetService) Invite(user *domain.User, expiry time.Duration) error {
+	plain, err := s.issueToken(user.ID, expiry)
+	if err != nil {
+		return err
+	}
+
+	return s.notifier.Send(notify.Message{
+		To:      user.Email,
+		Subject: "アカウント作成のご案内",
+		Body: fmt.Sprintf(
+			"アカウントが作成されました。以下のリンクからパスワードを設定してください（有効期限: %d時間）。\n%s",
+			int(expiry.Hours()),
+			s.resetLink(plain),
+		),
+	})
+}
+
+// issueToken は新しいトークンを発行してハッシュを保存し、平文のトークンを返す。ユーザーの以前のトークンは使えなくなる
+func (s *PasswordResetService) issueToken(userID uint, expiry time.Duration) (string, error) {
+	plain, err := randomToken(32)
+	if err != nil {
+		return "", err
+	}
+
+	token := &domain.PasswordResetToken{
+		UserID:    userID,
+		TokenHash: hashToken(plain),
+		ExpiresAt: time.Now().Add(expiry),
+	}
+	if err := s.resetRepo.Create(token); err != nil {
+		return "", err
+	}
+	return plain, nil
+}
+
+// ResetPassword はトークンを検証して新しいパスワードを設定する。
+// パスワードの更新と同じトランザクションで、既存のセッションはすべて失効させる。
+func (s *PasswordResetService) ResetPassword(ctx context.Context, plainToken, newPassword string) error {
+	token, err := s.resetRepo.FindByHash(hashToken(plainToken))
+	if err != nil || !token.IsUsable(time.Now()) {
+		return ErrInvalidResetToken
+	}
+	before, err := s.repo.FindByID(token.UserID)
+	if err != nil {
+		return ErrInvalidResetToken
+	}
+
+	hashed, err := HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.resetRepo.Consume(token, hashed, sessionRevocationTime()); err != nil {
+		if errors.Is(err, repository.ErrPasswordResetTokenUsed) {
+			return ErrInvalidResetToken
+		}
+		return err
+	}
+
+	ctx = withActorUser(ctx, token.UserID)
+	after := *before
+	after.Password = hashed
+	s.audit.Record(ctx, domain.AuditPasswordReset, &token.UserID, userSnapshot(before), userSnapshot(&after))
+	s.audit.Record(ctx, domain.AuditLogoutAll, &token.UserID, nil, nil)
+	return nil
+}
+
+func (s *PasswordResetService) resetLink(token string) string {
+	return s.resetURL + "?token=" + url.QueryEscape(token)
+}
@@ -0,0 +1,134 @@
+package service_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/notify"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// 送信されたメッセージを保持するだけのテスト用 Notifier
+type recordingNotifier struct {
+	messages []notify.Message
+}
+
+func (n *recordingNotifier) Send(msg notify.Message) error {
+	n.messages = append(n.messages, msg)
+	return nil
+}
+
+// 本文に含まれるリンクから token パラメータを取り出す
+func extractToken(t *testing.T, body string) string {
+	for _, line := range strings.Split(body, "\n") {
+		if strings.HasPrefix(line, "http") {
+			u, err := url.Parse(line)
+			require.NoError(t, err)
+			return u.Query().Get("token")
+		}
+	}
+	t.Fatalf("no link found in body: %s", body)
+	return ""
+}
+
+func TestPasswordResetService_ResetPassword(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	userRepo.Create(&domain.User{
+		Name:     "test",
+		Email:    "test@example.com",
+		Password: hashPassword("secret123"),
+	})
+
+	authService := newAuthService(db)
+	notifier := &recordingNotifier{}
+	resetService := service.NewPasswordResetService(
+		userRepo,
+		repository.NewPasswordResetRepository(db),
+		newAuditService(db),
+		notifier,
+		"http://localhost:3000/password/reset",
+		time.Hour,
+	)
+
+	login, err := authService.Login(ctx, "test@example.com", "secret123")
+	require.NoError(t, err)
+
+	// 未登録のメールアドレスでもエラーにならず、通知も送られない
+	assert.NoError(t, resetService.RequestReset("unknown@example.com"))
+	assert.Empty(t, notifier.messages)
+
+	require.NoError(t, resetService.RequestReset("test@example.com"))
+	require.Len(t, notifier.messages, 1)
+	assert.Equal(t, "test@example.com", notifier.messages[0].To)
+	token := extractToken(t, notifier.messages[0].Body)
+
+	// bcrypt で扱えない長さのパスワードは検証エラーになり、トークンは使われない
+	err = resetService.ResetPassword(ctx, token, strings.Repeat("あ", 25))
+	assert.ErrorIs(t, err, domain.ErrPasswordTooLong)
+
+	require.NoError(t, resetService.ResetPassword(ctx, token, "newsecret456"))
+
+	// 新しいパスワードでログインでき、古いパスワードは使えない
+	_, err = authService.Login(ctx, "test@example.com", "newsecret456")
+	assert.NoError(t, err)
+	_, err = authService.Login(ctx, "test@example.com", "secret123")
+	assert.Error(t, err)
+
+	// 既存のセッションはパスワードの更新と一緒に失効している
+	_, err = authService.Refresh(ctx, login.Tokens.RefreshToken)
+	assert.Error(t, err)
+	user, err := userRepo.FindByEmail("test@example.com")
+	require.NoError(t, err)
+	revoked, err := authService.IsRevoked("any-jti", user.ID, time.Now().Add(-time.Minute))
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	// 再設定は監査ログに残り、パスワードは伏せられる
+	events, err := newAuditService(db).List(domain.AuditFilter{Action: domain.AuditPasswordReset}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, map[string]interface{}{"password": "[REDACTED]"}, events[0].Before)
+	assert.Equal(t, map[string]interface{}{"password": "[REDACTED]"}, events[0].After)
+
+	// トークンは一度しか使えない
+	err = resetService.ResetPassword(ctx, token, "another789")
+	assert.ErrorIs(t, err, service.ErrInvalidResetToken)
+}
+
+func TestPasswordResetService_OnlyLatestTokenIsValid(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	userRepo.Create(&domain.User{
+		Name:     "test",
+		Email:    "test@example.com",
+		Password: hashPassword("secret123"),
+	})
+
+	notifier := &recordingNotifier{}
+	resetService := service.NewPasswordResetService(
+		userRepo,
+		repository.NewPasswordResetRepository(db),
+		newAuditService(db),
+		notifier,
+		"http://localhost:3000/password/reset",
+		time.Hour,
+	)
+
+	require.NoError(t, resetService.RequestReset("test@example.com"))
+	require.NoError(t, resetService.RequestReset("test@example.com"))
+	first := extractToken(t, notifier.messages[0].Body)
+	second := extractToken(t, notifier.messages[1].Body)
+
+	assert.ErrorIs(t, resetService.ResetPassword(ctx, first, "newsecret456"), service.ErrInvalidResetToken)
+	assert.NoError(t, resetService.ResetPassword(ctx, second, "newsecret456"))
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+)
+
+// userDataAuditPageSize は本人のデータの書き出しで1回に読み込む監査ログの数
+const userDataAuditPageSize = 500
+
+// ErrErasureReasonRequired は消去の根拠が指定されていない場合のエラー
+var ErrErasureReasonRequired = domain.NewValidationError("reason", "required", "reason is required")
+
+// userDataProfile は書き出すプロフィール。パスワード（ハッシュ）は含めない
+type userDataProfile struct {
+	ID              uint       `json:"id"`
+	Name            string     `json:"name"`
+	Email           string     `json:"email"`
+	Role            string     `json:"role"`
+	EmailVerifiedAt *time.Time `json:"email_verified_at"`
+	MFAEnabled      bool       `json:"mfa_enabled"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// userDataSession は1回のログインから始まるセッション（リフレッシュトークンの系列）。トークンのハッシュは含めない
+type userDataSession struct {
+	FamilyID        string     `json:"family_id"`
+	StartedAt       time.Time  `json:"started_at"`
+	LastRefreshedAt time.Time  `json:"last_refreshed_at"`
+	ExpiresAt       time.Time  `json:"expires_at"`
+	RevokedAt       *time.Time `json:"revoked_at"`
+	Active          bool       `json:"active"`
+}
+
+// userDataAuditEvent は本人が操作した、または本人が対象になった監査ログ。
+// 他のユーザーが操作した記録では、そのユーザーの IP と User-Agent を含めない。
+// 本人が他のユーザーを操作した記録では、そのユーザーの変更前後の値を含めない
+type userDataAuditEvent struct {
+	ID        uint                   `json:"id"`
+	Action    string                 `json:"action"`
+	ActorID   *uint                  `json:"actor_id"`
+	TargetID  *uint                  `json:"target_id"`
+	IP        string                 `json:"ip,omitempty"`
+	UserAgent string                 `json:"user_agent,omitempty"`
+	Before    map[string]interface{} `json:"before,omitempty"`
+	After     map[string]interface{} `json:"after,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+type PrivacyService struct {
+	repo         domain.UserStore
+	refreshRepo  *repository.RefreshTokenRepository
+	erasureRepo  *repository.ErasureRepository
+	mfaService   *MFAService
+	auditService *AuditService
+}
+
+func NewPrivacyService(
+	repo domain.UserStore,
+	refreshRepo *repository.RefreshTokenRepository,
+	erasureRepo *repository.ErasureRepository,
+	mfaService *MFAService,
+	auditService *AuditService,
+) *PrivacyService {
+	return &PrivacyService{
+		repo:         repo,
+		refreshRepo:  refreshRepo,
+		erasureRepo:  erasureRepo,
+		mfaService:   mfaService,
+		auditService: auditService,
+	}
+}
+
+// ExportUserData writes a ZIP archive of the user's own data to w.
+// アーカイブには profile.json（プロフィール）、sessions.json（セッション）、audit_events.json（監査ログ）を入れる。
+// パスワードやトークンのハッシュ、MFA のシークレットは含めない
+func (s *PrivacyService) ExportUserData(ctx context.Context, userID uint, w io.Writer) error {
+	user, err := s.repo.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	mfaEnabled, err := s.mfaService.IsEnabled(userID)
+	if err != nil {
+		return err
+	}
+	sessions, err := s.sessions(userID)
+	if err != nil {
+		return err
+	}
+
+	archive := zip.NewWriter(w)
+	profile := userDataProfile{
+		ID:              user.ID,
+		Name:            user.Name,
+		Email:           user.Email,
+		Role:            string(user.Role),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		MFAEnabled:      mfaEnabled,
+		CreatedAt:       user.CreatedAt,
+		UpdatedAt:       user.UpdatedAt,
+	}
+	if err := writeZipJSON(archive, "profile.json", profile); err != nil {
+		return err
+	}
+	if err := writeZipJSON(archive, "sessions.json", sessions); err != nil {
+		return err
+	}
+	if err := s.writeAuditEvents(ctx, archive, userID); err != nil {
+		return err
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+
+	s.auditService.Record(ctx, domain.AuditUserDataExported, &userID, nil, nil)
+	return nil
+}
+
+// sessions はリフレッシュトークンを系列ごとにまとめる
+func (s *PrivacyService) sessions(userID uint) ([]userDataSession, error) {
+	tokens, err := s.refreshRepo.FindByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	sessions := []userDataSession{}
+	index := map[string]int{}
+	for _, t := range tokens {
+		i, ok := index[t.FamilyID]
+		if !ok {
+			i = len(sessions)
+			index[t.FamilyID] = i
+			sessions = append(sessions, userDataSession{FamilyID: t.FamilyID, StartedAt: t.CreatedAt})
+		}
+		// トークンは古い順なので、系列の最後のトークンが最新の状態を表す
+		session := &sessions[i]
+		session.LastRefreshedAt = t.CreatedAt
+		session.ExpiresAt = t.ExpiresAt
+		session.RevokedAt = t.RevokedAt
+		session.Active = !t.IsRevoked() && !t.IsExpired(now)
+	}
+	return sessions, nil
+}
+
+// writeAuditEvents は監査ログを userDataAuditPageSize 件ずつ読み込みながら JSON の配列として書き出す
+func (s *PrivacyService) writeAuditEvents(ctx context.Context, archive *zip.Writer, userID uint) error {
+	f, err := archive.Create("audit_events.json")
+	if err != nil {
+		return err
+	}
+	if _, err := io.WriteString(f, "["); err != nil {
+		return err
+	}
+	first := true
+	for page := 1; ; page++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		events, err := s.auditService.List(domain.AuditFilter{UserID: &userID}, page, userDataAuditPageSize)
+		if err != nil {
+			return err
+		}
+		for _, e := range events {
+			event := userDataAuditEvent{
+				ID:        e.ID,
+				Action:    e.Action,
+				ActorID:   e.ActorID,
+				TargetID:  e.TargetID,
+				CreatedAt: e.CreatedAt,
+			}
+			if e.TargetID != nil && *e.TargetID == userID {
+				event.Before = e.Before
+				event.After = e.After
+			}
+			if e.ActorID != nil && *e.ActorID == userID {
+				event.IP = e.IP
+				event.UserAgent = e.UserAgent
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if !first {
+				data = append([]byte{','}, data...)
+			}
+			first = false
+			if _, err := f.Write(data); err != nil {
+				return err
+			}
+		}
+		if len(events) < userDataAuditPageSize {
+			break
+		}
+	}
+	_, err = io.WriteString(f, "]")
+	return err
+}
+
+func writeZipJSON(archive *zip.Writer, name string, v interface{}) error {
+	f, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(f)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Erase deletes or anonymizes the personal data of the user across every table and returns the erasure record.
+// 論理削除済みのユーザーも消去できる。消去は取り消せない。
+// reason には消去の根拠（請求の受付番号など）を指定する。個人情報は書かないこと。
+// 消去したことは監査ログにも記録する（ユーザー ID と消去の記録の ID のみ）
+func (s *PrivacyService) Erase(ctx context.Context, userID uint, reason string) (*domain.ErasureRecord, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, ErrErasureReasonRequired
+	}
+
+	record := &domain.ErasureRecord{
+		RequestedBy: ActorFrom(ctx).UserID,
+		Reason:      reason,
+	}
+	if err := s.erasureRepo.Erase(userID, record); err != nil {
+		return nil, err
+	}
+
+	s.auditService.Record(ctx, domain.AuditUserErased, &userID, nil, map[string]interface{}{"erasure_id": record.ID})
+	return record, nil
+}
+
+// ListErasures は消去の記録を新しい順に返す
+func (s *PrivacyService) ListErasures(userID *uint, page, limit int) ([]*domain.ErasureRecord, error) {
+	offset := (page - 1) * limit
+	return s.erasureRepo.Find(userID, offset, limit)
+}
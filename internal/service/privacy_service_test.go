@@ -0,0 +1,154 @@
+package service_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"testing"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newPrivacyService(db *gorm.DB) *service.PrivacyService {
+	return service.NewPrivacyService(
+		repository.NewUserRepository(db),
+		repository.NewRefreshTokenRepository(db),
+		repository.NewErasureRepository(db),
+		newMFAService(db),
+		newAuditService(db),
+	)
+}
+
+// readZipJSON は ZIP の中のファイルを JSON として読む
+func readZipJSON(t *testing.T, archive *zip.Reader, name string, v interface{}) {
+	t.Helper()
+	f, err := archive.Open(name)
+	require.NoError(t, err)
+	defer f.Close()
+	data, err := io.ReadAll(f)
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(data, v), name)
+}
+
+func TestPrivacyService_ExportUserData(t *testing.T) {
+	db := setupTestDB()
+	userRepo := repository.NewUserRepository(db)
+	user := &domain.User{Name: "test", Email: "test@example.com", Password: hashPassword("secret123")}
+	require.NoError(t, userRepo.Create(user))
+	admin := &domain.User{Name: "admin", Email: "admin@example.com", Password: "x", Role: domain.RoleAdmin}
+	require.NoError(t, userRepo.Create(admin))
+
+	authService := newAuthService(db)
+	ctx := service.WithActor(context.Background(), service.Actor{IP: "192.0.2.1", UserAgent: "self-agent"})
+	login, err := authService.Login(ctx, "test@example.com", "secret123")
+	require.NoError(t, err)
+	_, err = authService.Refresh(ctx, login.Tokens.RefreshToken)
+	require.NoError(t, err)
+	_, err = authService.Login(ctx, "test@example.com", "secret123")
+	require.NoError(t, err)
+
+	// 他のユーザー（管理者）の操作では、そのユーザーの IP を書き出さない
+	adminCtx := service.WithActor(context.Background(), service.Actor{UserID: &admin.ID, IP: "198.51.100.1", UserAgent: "admin-agent"})
+	newAuditService(db).Record(adminCtx, domain.AuditUserUpdated, &user.ID,
+		map[string]interface{}{"name": "old"}, map[string]interface{}{"name": "test"})
+	// 本人が他のユーザーを操作した記録には、そのユーザーの値を書き出さない
+	selfCtx := service.WithActor(context.Background(), service.Actor{UserID: &user.ID})
+	newAuditService(db).Record(selfCtx, domain.AuditUserUpdated, &admin.ID,
+		map[string]interface{}{"name": "old-admin"}, map[string]interface{}{"name": "new-admin"})
+
+	var buf bytes.Buffer
+	require.NoError(t, newPrivacyService(db).ExportUserData(ctx, user.ID, &buf))
+	assert.NotContains(t, buf.String(), user.Password)
+
+	archive, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	require.NoError(t, err)
+
+	var profile map[string]interface{}
+	readZipJSON(t, archive, "profile.json", &profile)
+	assert.Equal(t, "test@example.com", profile["email"])
+	assert.Equal(t, false, profile["mfa_enabled"])
+	assert.NotContains(t, profile, "password")
+
+	var sessions []map[string]interface{}
+	readZipJSON(t, archive, "sessions.json", &sessions)
+	require.Len(t, sessions, 2, "one session per login")
+	assert.Equal(t, true, sessions[0]["active"])
+	assert.NotContains(t, sessions[0], "token_hash")
+
+	var events []map[string]interface{}
+	readZipJSON(t, archive, "audit_events.json", &events)
+	require.Len(t, events, 5)
+	assert.Equal(t, float64(admin.ID), events[0]["target_id"])
+	assert.NotContains(t, events[0], "before")
+	assert.NotContains(t, events[0], "after")
+	assert.NotContains(t, buf.String(), "new-admin")
+	assert.Equal(t, domain.AuditUserUpdated, events[1]["action"])
+	assert.Contains(t, events[1], "after", "changes to the user are included")
+	assert.NotContains(t, events[1], "ip")
+	assert.NotContains(t, buf.String(), "admin-agent")
+	assert.Equal(t, "192.0.2.1", events[2]["ip"], "own IP is included")
+
+	// 書き出したことは監査ログに残る
+	exported, err := newAuditService(db).List(domain.AuditFilter{Action: domain.AuditUserDataExported}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, exported, 1)
+	assert.Equal(t, &user.ID, exported[0].TargetID)
+}
+
+func TestPrivacyService_Erase(t *testing.T) {
+	db := setupTestDB()
+	userRepo := repository.NewUserRepository(db)
+	user := &domain.User{Name: "test", Email: "test@example.com", Password: hashPassword("secret123")}
+	require.NoError(t, userRepo.Create(user))
+	admin := &domain.User{Name: "admin", Email: "admin@example.com", Password: "x", Role: domain.RoleAdmin}
+	require.NoError(t, userRepo.Create(admin))
+
+	authService := newAuthService(db)
+	login, err := authService.Login(context.Background(), "test@example.com", "secret123")
+	require.NoError(t, err)
+
+	privacyService := newPrivacyService(db)
+	ctx := service.WithActor(context.Background(), service.Actor{UserID: &admin.ID})
+
+	_, err = privacyService.Erase(ctx, user.ID, " ")
+	assert.ErrorIs(t, err, service.ErrErasureReasonRequired)
+
+	record, err := privacyService.Erase(ctx, user.ID, "request #42")
+	require.NoError(t, err)
+	assert.Equal(t, &admin.ID, record.RequestedBy)
+	assert.Equal(t, int64(1), record.Deleted["users"])
+	assert.Equal(t, int64(1), record.Deleted["refresh_tokens"])
+
+	_, err = userRepo.FindByID(user.ID)
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+	_, err = authService.Refresh(context.Background(), login.Tokens.RefreshToken)
+	assert.Error(t, err, "sessions are gone")
+
+	// 消去の監査ログには個人データを含めない
+	events, err := newAuditService(db).List(domain.AuditFilter{TargetID: &user.ID}, 1, 10)
+	require.NoError(t, err)
+	require.NotEmpty(t, events)
+	assert.Equal(t, domain.AuditUserErased, events[0].Action)
+	assert.Equal(t, map[string]interface{}{"erasure_id": float64(record.ID)}, events[0].After)
+	for _, e := range events[1:] {
+		for _, v := range e.After {
+			assert.Equal(t, domain.ErasedValue, v)
+		}
+		assert.Empty(t, e.IP)
+	}
+
+	records, err := privacyService.ListErasures(nil, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, records, 1)
+	assert.Equal(t, record.ID, records[0].ID)
+
+	_, err = privacyService.Erase(ctx, user.ID, "request #42")
+	assert.ErrorIs(t, err, domain.ErrUserNotFound)
+}
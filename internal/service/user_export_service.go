@@ -0,0 +1,325 @@
+package service
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/parquet"
+)
+
+// エクスポートの出力形式
+const (
+	ExportFormatCSV     = "csv"
+	ExportFormatNDJSON  = "ndjson"
+	ExportFormatParquet = "parquet"
+)
+
+// exportPageSize はエクスポートで1回に読み込むユーザーの数
+const exportPageSize = 1000
+
+// ErrUnsupportedExportFormat はエクスポートの出力形式が対応していない場合のエラー
+var ErrUnsupportedExportFormat = domain.NewValidationError("format", "oneof", "must be one of csv, ndjson, parquet")
+
+// userExportColumn はエクスポートできる列。パスワード（ハッシュ）は列に含めない
+type userExportColumn struct {
+	name  string
+	kind  parquet.Kind
+	value func(u *domain.User) interface{} // null なら nil
+}
+
+var userExportColumns = []userExportColumn{
+	{"id", parquet.Int64, func(u *domain.User) interface{} { return int64(u.ID) }},
+	{"name", parquet.String, func(u *domain.User) interface{} { return u.Name }},
+	{"email", parquet.String, func(u *domain.User) interface{} { return u.Email }},
+	{"role", parquet.String, func(u *domain.User) interface{} { return string(u.Role) }},
+	{"email_verified_at", parquet.Timestamp, func(u *domain.User) interface{} { return optionalTime(u.EmailVerifiedAt) }},
+	{"created_at", parquet.Timestamp, func(u *domain.User) interface{} { return u.CreatedAt }},
+	{"updated_at", parquet.Timestamp, func(u *domain.User) interface{} { return u.UpdatedAt }},
+	{"version", parquet.Int64, func(u *domain.User) interface{} { return int64(u.Version) }},
+}
+
+func optionalTime(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return *t
+}
+
+// UserExportColumnNames はエクスポートできる列の名前
+func UserExportColumnNames() []string {
+	names := make([]string, len(userExportColumns))
+	for i, c := range userExportColumns {
+		names[i] = c.name
+	}
+	return names
+}
+
+// UserExportParams はエクスポートの条件
+type UserExportParams struct {
+	Format  string
+	Columns []string // 出力する列（空ならすべての列）
+	Filter  domain.UserFilter
+	Sort    domain.UserSort
+}
+
+// Validate は出力形式と列を検証する。エクスポートを始める前（レスポンスのヘッダーを書く前）に呼ぶ
+func (p UserExportParams) Validate() error {
+	_, err := p.columns()
+	return err
+}
+
+func (p UserExportParams) columns() ([]userExportColumn, error) {
+	switch p.Format {
+	case ExportFormatCSV, ExportFormatNDJSON, ExportFormatParquet:
+	default:
+		return nil, ErrUnsupportedExportFormat
+	}
+	if len(p.Columns) == 0 {
+		return userExportColumns, nil
+	}
+
+	columns := make([]userExportColumn, 0, len(p.Columns))
+	seen := map[string]bool{}
+	for _, name := range p.Columns {
+		column, ok := findExportColumn(name)
+		if !ok {
+			return nil, domain.NewValidationError("columns", "oneof",
+				fmt.Sprintf("unknown column %s, must be one of %s", name, strings.Join(UserExportColumnNames(), ", ")))
+		}
+		if seen[name] {
+			return nil, domain.NewValidationError("columns", "duplicate", "duplicate column "+name)
+		}
+		seen[name] = true
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func findExportColumn(name string) (userExportColumn, bool) {
+	for _, c := range userExportColumns {
+		if c.name == name {
+			return c, true
+		}
+	}
+	return userExportColumn{}, false
+}
+
+type UserExportService struct {
+	repo  domain.UserStore
+	audit *AuditService
+}
+
+func NewUserExportService(repo domain.UserStore, audit *AuditService) *UserExportService {
+	return &UserExportService{repo: repo, audit: audit}
+}
+
+// Export writes the users matching params to w and returns the number of exported users.
+// ユーザーは exportPageSize 件ずつキーセットページネーションで読み込みながら書き出すので、全件をメモリに載せない。
+// パスワード（ハッシュ）は出力せず、論理削除済みのユーザーは含めない。
+// 書き出し始めた後にエラーになった場合、w には途中までのデータが残る
+func (s *UserExportService) Export(ctx context.Context, params UserExportParams, w io.Writer) (int64, error) {
+	columns, err := params.columns()
+	if err != nil {
+		return 0, err
+	}
+	sort := params.Sort
+	if sort.Field == "" {
+		sort.Field = domain.UserSortCreatedAt
+	}
+
+	out := newExportWriter(params.Format, columns, w)
+	var count int64
+	query := domain.UserPageQuery{Filter: params.Filter, Sort: sort, Limit: exportPageSize}
+	for {
+		if err := ctx.Err(); err != nil {
+			return count, err
+		}
+		users, err := s.repo.FindPage(query)
+		if err != nil {
+			return count, err
+		}
+		for _, u := range users {
+			values := make([]interface{}, len(columns))
+			for i, c := range columns {
+				values[i] = c.value(u)
+			}
+			if err := out.write(values); err != nil {
+				return count, err
+			}
+			count++
+		}
+		if err := out.flush(); err != nil {
+			return count, err
+		}
+		// HTTP のレスポンスなら、読み込んだ分ずつクライアントに送る
+		if f, ok := w.(http.Flusher); ok {
+			f.Flush()
+		}
+		if len(users) < exportPageSize {
+			break
+		}
+		key := domain.KeyOf(users[len(users)-1])
+		query.After = &key
+	}
+	if err := out.close(); err != nil {
+		return count, err
+	}
+
+	names := make([]string, len(columns))
+	for i, c := range columns {
+		names[i] = c.name
+	}
+	s.audit.Record(ctx, domain.AuditUsersExported, nil, nil, map[string]interface{}{
+		"format":  params.Format,
+		"columns": strings.Join(names, ","),
+		"count":   count,
+	})
+	return count, nil
+}
+
+// exportWriter は出力形式ごとに行を書き出す
+type exportWriter interface {
+	write(values []interface{}) error
+	// flush は書き込んだ行を下位の io.Writer に書き出す（Parquet は行グループが溜まるまで保持する）
+	flush() error
+	close() error
+}
+
+func newExportWriter(format string, columns []userExportColumn, w io.Writer) exportWriter {
+	switch format {
+	case ExportFormatNDJSON:
+		return &ndjsonExportWriter{w: w, columns: columns}
+	case ExportFormatParquet:
+		pc := make([]parquet.Column, len(columns))
+		for i, c := range columns {
+			pc[i] = parquet.Column{Name: c.name, Kind: c.kind}
+		}
+		return &parquetExportWriter{w: parquet.NewWriter(w, pc)}
+	default:
+		return &csvExportWriter{w: csv.NewWriter(w), columns: columns}
+	}
+}
+
+// csvExportWriter は1行目に列の名前を書く。日時は RFC 3339（UTC）、null は空にする。
+// 表計算ソフトで開いたときに数式として実行されないよう、数式になる文字で始まる値は ' を前に付ける
+type csvExportWriter struct {
+	w             *csv.Writer
+	columns       []userExportColumn
+	headerWritten bool
+}
+
+func (e *csvExportWriter) writeHeader() error {
+	if e.headerWritten {
+		return nil
+	}
+	e.headerWritten = true
+	header := make([]string, len(e.columns))
+	for i, c := range e.columns {
+		header[i] = c.name
+	}
+	return e.w.Write(header)
+}
+
+func (e *csvExportWriter) write(values []interface{}) error {
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	record := make([]string, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case string:
+			record[i] = neutralizeCSVFormula(v)
+		case int64:
+			record[i] = strconv.FormatInt(v, 10)
+		case time.Time:
+			record[i] = v.UTC().Format(time.RFC3339)
+		}
+	}
+	return e.w.Write(record)
+}
+
+// csvFormulaPrefixes は表計算ソフトが数式として扱う先頭の文字
+const csvFormulaPrefixes = "=+-@\t\r"
+
+// neutralizeCSVFormula は数式として扱われる値の前に ' を付ける（CSV インジェクション対策）
+func neutralizeCSVFormula(s string) string {
+	if s != "" && strings.ContainsRune(csvFormulaPrefixes, rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func (e *csvExportWriter) flush() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+func (e *csvExportWriter) close() error {
+	// ユーザーがいなくてもヘッダーは書く
+	if err := e.writeHeader(); err != nil {
+		return err
+	}
+	return e.flush()
+}
+
+// ndjsonExportWriter は1行に1人のユーザーを、列の順のキーを持つ JSON オブジェクトとして書く
+type ndjsonExportWriter struct {
+	w       io.Writer
+	columns []userExportColumn
+	buf     []byte
+}
+
+func (e *ndjsonExportWriter) write(values []interface{}) error {
+	e.buf = append(e.buf, '{')
+	for i, v := range values {
+		if i > 0 {
+			e.buf = append(e.buf, ',')
+		}
+		e.buf = strconv.AppendQuote(e.buf, e.columns[i].name)
+		e.buf = append(e.buf, ':')
+		if t, ok := v.(time.Time); ok {
+			v = t.UTC()
+		}
+		data, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		e.buf = append(e.buf, data...)
+	}
+	e.buf = append(e.buf, '}', '\n')
+	return nil
+}
+
+func (e *ndjsonExportWriter) flush() error {
+	_, err := e.w.Write(e.buf)
+	e.buf = e.buf[:0]
+	return err
+}
+
+func (e *ndjsonExportWriter) close() error {
+	return e.flush()
+}
+
+type parquetExportWriter struct {
+	w *parquet.Writer
+}
+
+func (e *parquetExportWriter) write(values []interface{}) error {
+	return e.w.Write(values)
+}
+
+func (e *parquetExportWriter) flush() error {
+	return nil
+}
+
+func (e *parquetExportWriter) close() error {
+	return e.w.Close()
+}
@@ -0,0 +1,140 @@
+package service_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserExportService_Export(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+
+	// ページの区切りをまたぐ件数を登録する
+	users := make([]*domain.User, 1005)
+	for i := range users {
+		users[i] = &domain.User{Name: fmt.Sprintf("user%d", i), Email: fmt.Sprintf("user%d@example.com", i), Password: "hashed-secret"}
+		if i%2 == 1 {
+			users[i].Role = domain.RoleSupport
+		}
+	}
+	_, err := userRepo.CreateBatch(users)
+	require.NoError(t, err)
+	verified := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	db.Model(&repository.User{}).Where("id = ?", users[0].ID).Update("email_verified_at", verified)
+
+	auditService := newAuditService(db)
+	exportService := service.NewUserExportService(userRepo, auditService)
+
+	var buf bytes.Buffer
+	count, err := exportService.Export(ctx, service.UserExportParams{Format: service.ExportFormatCSV}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1005), count)
+	assert.NotContains(t, buf.String(), "hashed-secret")
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	require.Len(t, records, 1006)
+	assert.Equal(t, service.UserExportColumnNames(), records[0])
+	assert.Equal(t, []string{"1", "user0", "user0@example.com", "member", "2024-01-02T03:04:05Z"}, records[1][:5])
+	assert.Equal(t, "", records[2][4], "null is written as empty")
+	assert.Equal(t, "user1004", records[1005][1])
+
+	// 列の選択と絞り込み
+	buf.Reset()
+	params := service.UserExportParams{
+		Format:  service.ExportFormatNDJSON,
+		Columns: []string{"email", "id"},
+		Filter:  domain.UserFilter{Role: domain.RoleSupport},
+		Sort:    domain.UserSort{Field: domain.UserSortCreatedAt, Desc: true},
+	}
+	count, err = exportService.Export(ctx, params, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(502), count)
+	scanner := bufio.NewScanner(&buf)
+	require.True(t, scanner.Scan())
+	assert.Equal(t, `{"email":"user1003@example.com","id":1004}`, scanner.Text())
+
+	buf.Reset()
+	count, err = exportService.Export(ctx, service.UserExportParams{Format: service.ExportFormatParquet, Columns: []string{"id", "created_at"}}, &buf)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1005), count)
+	assert.True(t, strings.HasPrefix(buf.String(), "PAR1"))
+	assert.True(t, strings.HasSuffix(buf.String(), "PAR1"))
+
+	// エクスポートは監査ログに残る
+	events, err := auditService.List(domain.AuditFilter{Action: domain.AuditUsersExported}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	assert.Equal(t, "email,id", events[1].After["columns"])
+	assert.Equal(t, "ndjson", events[1].After["format"])
+}
+
+func TestUserExportService_InvalidParams(t *testing.T) {
+	exportService := service.NewUserExportService(repository.NewUserRepository(setupTestDB()), newAuditService(setupTestDB()))
+
+	for _, params := range []service.UserExportParams{
+		{Format: "xlsx"},
+		{Format: service.ExportFormatCSV, Columns: []string{"password"}},
+		{Format: service.ExportFormatCSV, Columns: []string{"id", "id"}},
+	} {
+		var buf bytes.Buffer
+		_, err := exportService.Export(context.Background(), params, &buf)
+		var ve *domain.ValidationError
+		assert.ErrorAs(t, err, &ve, params)
+		assert.Zero(t, buf.Len(), "nothing is written")
+		assert.Equal(t, err, params.Validate())
+	}
+}
+
+func TestUserExportService_NDJSONValues(t *testing.T) {
+	db := setupTestDB()
+	userRepo := repository.NewUserRepository(db)
+	require.NoError(t, userRepo.Create(&domain.User{Name: `"quoted" 名前`, Email: "a@example.com", Password: "x"}))
+
+	var buf bytes.Buffer
+	_, err := service.NewUserExportService(userRepo, newAuditService(db)).
+		Export(context.Background(), service.UserExportParams{Format: service.ExportFormatNDJSON}, &buf)
+	require.NoError(t, err)
+
+	var row map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &row))
+	assert.Equal(t, `"quoted" 名前`, row["name"])
+	assert.Nil(t, row["email_verified_at"])
+	assert.NotContains(t, row, "password")
+	assert.Len(t, row, len(service.UserExportColumnNames()))
+}
+
+func TestUserExportService_CSVNeutralizesFormulas(t *testing.T) {
+	db := setupTestDB()
+	userRepo := repository.NewUserRepository(db)
+	for i, name := range []string{`=HYPERLINK("http://evil.example","x")`, "+1", "-1", "@SUM(A1)", "\tTab", "Plain"} {
+		require.NoError(t, userRepo.Create(&domain.User{Name: name, Email: fmt.Sprintf("formula%d@example.com", i), Password: "x"}))
+	}
+
+	var buf bytes.Buffer
+	params := service.UserExportParams{Format: service.ExportFormatCSV, Columns: []string{"name"}}
+	_, err := service.NewUserExportService(userRepo, newAuditService(db)).Export(context.Background(), params, &buf)
+	require.NoError(t, err)
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	require.NoError(t, err)
+	var names []string
+	for _, r := range records[1:] {
+		names = append(names, r[0])
+	}
+	assert.Equal(t, []string{`'=HYPERLINK("http://evil.example","x")`, "'+1", "'-1", "'@SUM(A1)", "'\tTab", "Plain"}, names)
+}
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+)
+
+// 一括登録の入力形式
+const (
+	ImportFormatCSV    = "csv"
+	ImportFormatNDJSON = "ndjson"
+)
+
+// ErrUnsupportedImportFormat は一括登録の入力形式が対応していない場合のエラー
+var ErrUnsupportedImportFormat = domain.NewValidationError("format", "oneof", "must be one of csv, ndjson")
+
+// importColumns は入力で指定できる項目。password を省略（空に）すると招待リンクを送る
+var importColumns = []string{"name", "email", "password", "role"}
+
+// maxImportLineSize は NDJSON の1行の最大の長さ
+const maxImportLineSize = 64 * 1024
+
+// importRow は入力の1行
+type importRow struct {
+	line     int // 入力での行番号（CSV はヘッダーが1行目）
+	name     string
+	email    string
+	password string
+	role     string
+	err      error // 行を読み取れなかった場合のエラー
+}
+
+// importReader は入力を1行ずつ読む。最後まで読むと io.EOF を返す。
+// 1行の形式が不正な場合は importRow.err に記録して、続きの行を読めるようにする
+type importReader interface {
+	next() (*importRow, error)
+}
+
+func newImportReader(format string, r io.Reader) (importReader, error) {
+	switch format {
+	case ImportFormatCSV:
+		return newCSVImportReader(r)
+	case ImportFormatNDJSON:
+		s := bufio.NewScanner(r)
+		s.Buffer(make([]byte, 4096), maxImportLineSize)
+		return &ndjsonImportReader{scanner: s}, nil
+	default:
+		return nil, ErrUnsupportedImportFormat
+	}
+}
+
+// csvImportReader は1行目をヘッダーとして、列の名前で項目を読む
+type csvImportReader struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func newCSVImportReader(r io.Reader) (*csvImportReader, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, domain.NewValidationError("header", "required", "must have a header row")
+	}
+	if err != nil {
+		return nil, domain.NewValidationError("header", "invalid", err.Error())
+	}
+
+	columns := map[string]int{}
+	for i, name := range header {
+		// Excel などが付ける BOM は取り除く
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !isImportColumn(name) {
+			return nil, domain.NewValidationError("header", "unknown", "unknown column "+name+", must be one of "+strings.Join(importColumns, ", "))
+		}
+		if _, ok := columns[name]; ok {
+			return nil, domain.NewValidationError("header", "duplicate", "duplicate column "+name)
+		}
+		columns[name] = i
+	}
+	for _, required := range []string{"name", "email"} {
+		if _, ok := columns[required]; !ok {
+			return nil, domain.NewValidationError("header", "required", "must have a "+required+" column")
+		}
+	}
+	reader.FieldsPerRecord = len(header)
+	return &csvImportReader{reader: reader, columns: columns}, nil
+}
+
+func (r *csvImportReader) next() (*importRow, error) {
+	record, err := r.reader.Read()
+	if err == io.EOF {
+		return nil, io.EOF
+	}
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return &importRow{line: parseErr.StartLine, err: parseErr.Err}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	line, _ := r.reader.FieldPos(0)
+	return &importRow{
+		line:     line,
+		name:     r.field(record, "name"),
+		email:    r.field(record, "email"),
+		password: r.field(record, "password"),
+		role:     r.field(record, "role"),
+	}, nil
+}
+
+func (r *csvImportReader) field(record []string, name string) string {
+	i, ok := r.columns[name]
+	if !ok {
+		return ""
+	}
+	return record[i]
+}
+
+// ndjsonImportReader は1行に1つの JSON オブジェクトを読む。空行は読み飛ばす
+type ndjsonImportReader struct {
+	scanner *bufio.Scanner
+	line    int
+}
+
+func (r *ndjsonImportReader) next() (*importRow, error) {
+	for r.scanner.Scan() {
+		r.line++
+		text := bytes.TrimSpace(r.scanner.Bytes())
+		if len(text) == 0 {
+			continue
+		}
+
+		var v struct {
+			Name     string `json:"name"`
+			Email    string `json:"email"`
+			Password string `json:"password"`
+			Role     string `json:"role"`
+		}
+		dec := json.NewDecoder(bytes.NewReader(text))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(&v); err != nil {
+			return &importRow{line: r.line, err: err}, nil
+		}
+		return &importRow{line: r.line, name: v.Name, email: v.Email, password: v.Password, role: v.Role}, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, domain.NewValidationError("line", "max",
+				fmt.Sprintf("line %d must be at most %d bytes", r.line+1, maxImportLineSize))
+		}
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func isImportColumn(name string) bool {
+	for _, c := range importColumns {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
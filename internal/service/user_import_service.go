@@ -0,0 +1,282 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+)
+
+// importBatchSize は1つのトランザクションで登録するユーザーの数
+const importBatchSize = 100
+
+// 一括登録の行ごとの結果
+const (
+	ImportCreated = "created" // パスワードを指定して登録した
+	ImportInvited = "invited" // パスワードを指定せずに登録し、招待リンクを送った
+	ImportFailed  = "failed"  // 登録できなかった
+)
+
+// UserImportReport は一括登録の結果
+type UserImportReport struct {
+	Total   int                   `json:"total"`
+	Created int                   `json:"created"`
+	Invited int                   `json:"invited"`
+	Failed  int                   `json:"failed"`
+	Rows    []UserImportRowResult `json:"rows"`
+	// Error は途中で続けられなくなった理由。Rows の行までは登録を終えていて、それより後の行は登録していない
+	Error string `json:"error,omitempty"`
+}
+
+// UserImportRowResult は一括登録の1行の結果
+type UserImportRowResult struct {
+	Line    int                    `json:"line"`
+	Email   string                 `json:"email,omitempty"`
+	Status  string                 `json:"status"`
+	UserID  uint                   `json:"user_id,omitempty"`
+	Code    string                 `json:"code,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Errors  []UserImportFieldError `json:"errors,omitempty"`
+}
+
+// UserImportFieldError は行の項目ごとの検証エラー
+type UserImportFieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type UserImportService struct {
+	repo         domain.UserStore
+	invites      *PasswordResetService
+	audit        *AuditService
+	inviteExpiry time.Duration // 招待リンクの有効期限
+}
+
+func NewUserImportService(repo domain.UserStore, invites *PasswordResetService, audit *AuditService, inviteExpiry time.Duration) *UserImportService {
+	return &UserImportService{repo: repo, invites: invites, audit: audit, inviteExpiry: inviteExpiry}
+}
+
+// importItem は登録する1行と、その結果
+type importItem struct {
+	result   *UserImportRowResult
+	user     *domain.User
+	password string // 指定されたパスワード（空なら招待する）
+}
+
+// Import registers users read from r in the given format (csv or ndjson) and returns a per-row report.
+// 入力は1行ずつ読み、importBatchSize 行ごとに1つのトランザクションで登録する。
+// 各行は domain.NewUser と同じ規則で検証し、不正な行やメールアドレスが使われている行は失敗として報告して続きを登録する。
+// パスワードの無い行はランダムなパスワードで登録して招待リンクを送る。
+// 登録したユーザーはメールアドレスを確認済みとはしないが、確認メールは送らない（招待リンクか管理者からの連絡で案内する）
+//
+// 入力が読めなくなった（NDJSON の長すぎる行、I/O エラーなど）・登録に失敗した場合は、そこで止めてエラーと一緒にそれまでの結果を返す。
+// 読めなくなった場合はその手前の行まで登録し、登録に失敗した場合は失敗したバッチを取り消してその前のバッチまでを残す。
+// 返す結果の Rows には登録を終えた行だけが入る。ヘッダーや形式が不正で1行も読まなかった場合は結果を返さない
+func (s *UserImportService) Import(ctx context.Context, format string, r io.Reader) (*UserImportReport, error) {
+	reader, err := newImportReader(format, r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &UserImportReport{Rows: []UserImportRowResult{}}
+	batch := make([]*importRow, 0, importBatchSize)
+	for {
+		row, err := reader.next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// 読めた行までは登録してから止める
+			if len(batch) > 0 {
+				if batchErr := s.importBatch(ctx, batch, report); batchErr != nil {
+					return report, batchErr
+				}
+			}
+			return report, err
+		}
+		batch = append(batch, row)
+		if len(batch) < importBatchSize {
+			continue
+		}
+		if err := s.importBatch(ctx, batch, report); err != nil {
+			return report, err
+		}
+		batch = batch[:0]
+	}
+	if len(batch) > 0 {
+		if err := s.importBatch(ctx, batch, report); err != nil {
+			return report, err
+		}
+	}
+	return report, nil
+}
+
+func (s *UserImportService) importBatch(ctx context.Context, rows []*importRow, report *UserImportReport) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	items := make([]*importItem, len(rows))
+	var valid []*importItem
+	for i, row := range rows {
+		items[i] = s.validateRow(row)
+		if items[i].user != nil {
+			valid = append(valid, items[i])
+		}
+	}
+
+	if err := hashImportPasswords(valid); err != nil {
+		return err
+	}
+
+	users := make([]*domain.User, len(valid))
+	for i, item := range valid {
+		users[i] = item.user
+	}
+	if len(users) > 0 {
+		errs, err := s.repo.CreateBatch(users)
+		if err != nil {
+			return err
+		}
+		for i, item := range valid {
+			if errs[i] != nil {
+				failImportRow(item.result, errs[i])
+				continue
+			}
+			s.created(ctx, item)
+		}
+	}
+
+	for _, item := range items {
+		report.Total++
+		switch item.result.Status {
+		case ImportCreated:
+			report.Created++
+		case ImportInvited:
+			report.Invited++
+		default:
+			report.Failed++
+		}
+		report.Rows = append(report.Rows, *item.result)
+	}
+	return nil
+}
+
+// validateRow は1行を検証し、登録するユーザーを作る。不正な行は失敗として結果に記録する
+func (s *UserImportService) validateRow(row *importRow) *importItem {
+	item := &importItem{result: &UserImportRowResult{Line: row.line, Email: domain.NormalizeEmail(row.email)}}
+	if row.err != nil {
+		item.result.Status = ImportFailed
+		item.result.Code = "invalid_row"
+		item.result.Message = row.err.Error()
+		return item
+	}
+
+	ve := &domain.ValidationError{}
+	role := domain.RoleMember
+	if name := strings.ToLower(strings.TrimSpace(row.role)); name != "" {
+		parsed, err := domain.ParseRole(name)
+		if err != nil {
+			ve.Fields = append(ve.Fields, domain.FieldError{Field: "role", Code: "oneof", Message: "must be one of admin, support, member"})
+		}
+		role = parsed
+	}
+
+	// 招待するユーザーは本人がパスワードを設定するまでログインできないよう、推測できないパスワードを入れておく
+	password := row.password
+	if password == "" {
+		random, err := randomToken(32)
+		if err != nil {
+			ve.Fields = append(ve.Fields, domain.FieldError{Field: "password", Code: "invalid", Message: err.Error()})
+		}
+		password = random
+	}
+	user, err := domain.NewUser(strings.TrimSpace(row.name), row.email, password)
+	var userErr *domain.ValidationError
+	if errors.As(err, &userErr) {
+		ve.Fields = append(userErr.Fields, ve.Fields...)
+	}
+
+	if len(ve.Fields) > 0 {
+		failImportRow(item.result, ve)
+		return item
+	}
+	user.Role = role
+	item.user = user
+	item.password = row.password
+	return item
+}
+
+// created は登録できた行の結果を記録し、パスワードの無い行には招待リンクを送る
+func (s *UserImportService) created(ctx context.Context, item *importItem) {
+	user := item.user
+	item.result.UserID = user.ID
+	item.result.Status = ImportCreated
+	s.audit.Record(ctx, domain.AuditUserCreated, &user.ID, nil, userSnapshot(user))
+
+	if item.password != "" {
+		return
+	}
+	// 登録は済んでいるので、招待を送れなくても失敗にはしない（パスワード再設定で案内できる）
+	if err := s.invites.Invite(user, s.inviteExpiry); err != nil {
+		log.Printf("[ERROR] failed to send invitation: user_id=%d: %v", user.ID, err)
+		item.result.Message = "user was created but the invitation could not be sent"
+		return
+	}
+	item.result.Status = ImportInvited
+}
+
+// failImportRow は登録できなかった理由を結果に記録する
+func failImportRow(result *UserImportRowResult, err error) {
+	result.Status = ImportFailed
+
+	var ve *domain.ValidationError
+	var de *domain.Error
+	switch {
+	case errors.As(err, &ve):
+		result.Code = "validation_failed"
+		result.Message = "row has invalid fields"
+		for _, f := range ve.Fields {
+			result.Errors = append(result.Errors, UserImportFieldError{Field: f.Field, Code: f.Code, Message: f.Message})
+		}
+	case errors.As(err, &de):
+		result.Code = de.Code
+		result.Message = de.Message
+	default:
+		log.Printf("[ERROR] failed to import user: line=%d: %v", result.Line, err)
+		result.Code = "internal_error"
+		result.Message = "user could not be created"
+	}
+}
+
+// hashImportPasswords はパスワードをハッシュ化する。bcrypt は遅いので CPU の数まで並行して計算する
+func hashImportPasswords(items []*importItem) error {
+	var wg sync.WaitGroup
+	errs := make([]error, len(items))
+	sem := make(chan struct{}, runtime.NumCPU())
+	for i, item := range items {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, user *domain.User) {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			hashed, err := HashPassword(user.Password)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			user.Password = hashed
+		}(i, item.user)
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
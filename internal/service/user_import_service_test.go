@@ -0,0 +1,160 @@
+package service_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+func newUserImportService(db *gorm.DB, notifier *recordingNotifier) *service.UserImportService {
+	userRepo := repository.NewUserRepository(db)
+	resetService := service.NewPasswordResetService(
+		userRepo,
+		repository.NewPasswordResetRepository(db),
+		newAuditService(db),
+		notifier,
+		"http://localhost:3000/password/reset",
+		time.Hour,
+	)
+	return service.NewUserImportService(userRepo, resetService, newAuditService(db), 72*time.Hour)
+}
+
+func TestUserImportService_CSV(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	userRepo := repository.NewUserRepository(db)
+	userRepo.Create(&domain.User{Name: "taken", Email: "taken@example.com", Password: "x"})
+
+	notifier := &recordingNotifier{}
+	importService := newUserImportService(db, notifier)
+
+	input := "\ufeffName,Email,Password,Role\n" +
+		"Alice,Alice@Example.com,secret123,admin\n" +
+		"Bob,bob@example.com,,\n" +
+		",invalid,123,owner\n" +
+		"Carol,taken@example.com,secret123,\n" +
+		"Dave,dave@example.com\n" +
+		"Alice2,alice@example.com,secret123,member\n"
+	report, err := importService.Import(ctx, service.ImportFormatCSV, strings.NewReader(input))
+	require.NoError(t, err)
+
+	assert.Equal(t, 6, report.Total)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Invited)
+	assert.Equal(t, 4, report.Failed)
+	require.Len(t, report.Rows, 6)
+
+	alice := report.Rows[0]
+	assert.Equal(t, 2, alice.Line)
+	assert.Equal(t, service.ImportCreated, alice.Status)
+	assert.Equal(t, "alice@example.com", alice.Email)
+	user, err := userRepo.FindByID(alice.UserID)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleAdmin, user.Role)
+	assert.NotEqual(t, "secret123", user.Password, "password is hashed")
+	_, err = newAuthService(db).Login(ctx, "alice@example.com", "secret123")
+	assert.NoError(t, err)
+
+	// パスワードの無い行には招待リンクを送る
+	assert.Equal(t, service.ImportInvited, report.Rows[1].Status)
+	require.Len(t, notifier.messages, 1)
+	assert.Equal(t, "bob@example.com", notifier.messages[0].To)
+	assert.Equal(t, "アカウント作成のご案内", notifier.messages[0].Subject)
+	assert.NotEmpty(t, extractToken(t, notifier.messages[0].Body))
+
+	invalid := report.Rows[2]
+	assert.Equal(t, service.ImportFailed, invalid.Status)
+	assert.Equal(t, "validation_failed", invalid.Code)
+	fields := map[string]string{}
+	for _, e := range invalid.Errors {
+		fields[e.Field] = e.Code
+	}
+	assert.Equal(t, map[string]string{"name": "required", "email": "email", "password": "min", "role": "oneof"}, fields)
+
+	assert.Equal(t, "email_taken", report.Rows[3].Code)
+	assert.Equal(t, "invalid_row", report.Rows[4].Code)
+	assert.Equal(t, 6, report.Rows[4].Line)
+	// 同じ入力の中での重複も検出する
+	assert.Equal(t, "email_taken", report.Rows[5].Code)
+
+	total, err := userRepo.Count(domain.UserFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+}
+
+func TestUserImportService_NDJSON(t *testing.T) {
+	db := setupTestDB()
+	importService := newUserImportService(db, &recordingNotifier{})
+
+	// バッチの区切りをまたぐ件数を登録する
+	var b strings.Builder
+	for i := 0; i < 150; i++ {
+		fmt.Fprintf(&b, `{"name":"user%d","email":"user%d@example.com","password":"secret123"}`+"\n", i, i)
+	}
+	b.WriteString("\n")
+	b.WriteString(`{"name":"x","email":"x@example.com","admin":true}` + "\n")
+	b.WriteString("not json\n")
+
+	report, err := importService.Import(context.Background(), service.ImportFormatNDJSON, strings.NewReader(b.String()))
+	require.NoError(t, err)
+	assert.Equal(t, 152, report.Total)
+	assert.Equal(t, 150, report.Created)
+	assert.Equal(t, 2, report.Failed)
+	assert.Equal(t, 152, report.Rows[150].Line, "blank lines are counted")
+	assert.Equal(t, "invalid_row", report.Rows[150].Code)
+	assert.Equal(t, "invalid_row", report.Rows[151].Code)
+
+	total, err := repository.NewUserRepository(db).Count(domain.UserFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(150), total)
+}
+
+func TestUserImportService_InvalidInput(t *testing.T) {
+	importService := newUserImportService(setupTestDB(), &recordingNotifier{})
+	ctx := context.Background()
+
+	_, err := importService.Import(ctx, "xml", strings.NewReader(""))
+	assert.ErrorIs(t, err, service.ErrUnsupportedImportFormat)
+
+	for _, input := range []string{"", "name,phone\n", "name,name,email\n", "email,password\n"} {
+		_, err := importService.Import(ctx, service.ImportFormatCSV, strings.NewReader(input))
+		var ve *domain.ValidationError
+		require.ErrorAs(t, err, &ve, input)
+		assert.Equal(t, "header", ve.Fields[0].Field)
+	}
+}
+
+func TestUserImportService_StopsMidStream(t *testing.T) {
+	db := setupTestDB()
+	importService := newUserImportService(db, &recordingNotifier{})
+
+	// 長すぎる行の手前までは、バッチの途中でも登録してから止める
+	var b strings.Builder
+	for i := 0; i < 120; i++ {
+		fmt.Fprintf(&b, `{"name":"user%d","email":"user%d@example.com","password":"secret123"}`+"\n", i, i)
+	}
+	fmt.Fprintf(&b, `{"name":"%s","email":"long@example.com"}`+"\n", strings.Repeat("a", 70*1024))
+	b.WriteString(`{"name":"after","email":"after@example.com","password":"secret123"}` + "\n")
+
+	report, err := importService.Import(context.Background(), service.ImportFormatNDJSON, strings.NewReader(b.String()))
+	var ve *domain.ValidationError
+	require.ErrorAs(t, err, &ve)
+	assert.Equal(t, "line", ve.Fields[0].Field)
+	require.NotNil(t, report)
+	assert.Equal(t, 120, report.Total)
+	assert.Equal(t, 120, report.Created)
+	assert.Equal(t, 120, report.Rows[119].Line)
+
+	total, err := repository.NewUserRepository(db).Count(domain.UserFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), total)
+}
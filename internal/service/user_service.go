@@ -1,35 +1,213 @@
 package service
 
 import (
-	"fmt"
+	"context"
 	"log"
+	"time"
 
+	"github.com/okamuuu/go-user-app/internal/cursor"
 	"github.com/okamuuu/go-user-app/internal/domain"
-	"github.com/okamuuu/go-user-app/internal/repository"
-	"golang.org/x/crypto/bcrypt"
 )
 
+var ErrUserNotFound = domain.ErrUserNotFound
+
+// ErrInvalidCursor は一覧のカーソルが改ざんされている・形式が不正な場合のエラー
+var ErrInvalidCursor = domain.NewValidationError("cursor", "invalid", "is invalid or was issued for another sort order")
+
+// ErrEmptySearchQuery は検索語に単語（英数字）が含まれていない場合のエラー
+var ErrEmptySearchQuery = domain.NewValidationError("q", "required", "must contain at least one word")
+
 type UserService struct {
-	repo *repository.UserRepository
+	repo              domain.UserStore
+	emailVerification *EmailVerificationService
+	sessions          *AuthService
+	audit             *AuditService
+	cursors           *cursor.Codec
+	deleteGracePeriod time.Duration // 論理削除してから物理削除するまでの猶予期間
+}
+
+func NewUserService(repo domain.UserStore, emailVerification *EmailVerificationService, sessions *AuthService, audit *AuditService, cursors *cursor.Codec, deleteGracePeriod time.Duration) *UserService {
+	return &UserService{repo: repo, emailVerification: emailVerification, sessions: sessions, audit: audit, cursors: cursors, deleteGracePeriod: deleteGracePeriod}
+}
+
+// UserListParams はユーザー一覧の取得条件
+type UserListParams struct {
+	Filter domain.UserFilter
+	Sort   domain.UserSort
+	Limit  int
+	// SkipTotal なら件数を数えない（大きなテーブルで COUNT のコストを避けたい場合）
+	SkipTotal bool
+}
+
+// UserPage はユーザー一覧の1ページ。
+// カーソルで取得した場合、前後のページが無ければ NextCursor / PrevCursor が空になる
+type UserPage struct {
+	Users      []*domain.User
+	Total      *int64 // 条件に合うユーザーの総数（SkipTotal なら nil）
+	HasMore    bool   // このページより後ろにユーザーがいる
+	NextCursor string
+	PrevCursor string
+}
+
+// GetUsers は条件に合うユーザーを並び順の page ページ目から返す
+func (s *UserService) GetUsers(params UserListParams, page int) (*UserPage, error) {
+	offset := (page - 1) * params.Limit
+	// 1件多く取得して、続きのページがあるかを判定する
+	users, err := s.repo.FindAll(domain.UserListQuery{
+		Filter: params.Filter,
+		Sort:   params.Sort,
+		Offset: offset,
+		Limit:  params.Limit + 1,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &UserPage{Users: users, HasMore: len(users) > params.Limit}
+	if result.HasMore {
+		result.Users = users[:params.Limit]
+	}
+	if result.Total, err = s.countUsers(params); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// userCursor はカーソルに埋め込むページの境界。
+// 並び順が変わると境界の意味も変わるため、発行したときの並び順も持たせる
+type userCursor struct {
+	Sort   domain.UserSortField `json:"s"`
+	Desc   bool                 `json:"d,omitempty"`
+	Time   *time.Time           `json:"t,omitempty"` // 作成日時順の場合の境界
+	Value  string               `json:"v,omitempty"` // 名前・メールアドレス順の場合の境界
+	ID     uint                 `json:"id"`
+	Before bool                 `json:"b,omitempty"` // 境界より前のページを指す
+}
+
+func newUserCursor(u *domain.User, sort domain.UserSort, before bool) userCursor {
+	c := userCursor{Sort: sort.Field, Desc: sort.Desc, ID: u.ID, Before: before}
+	switch sort.Field {
+	case domain.UserSortName:
+		c.Value = u.Name
+	case domain.UserSortEmail:
+		c.Value = u.Email
+	default:
+		t := u.CreatedAt
+		c.Time = &t
+	}
+	return c
+}
+
+func (c userCursor) key() domain.UserKey {
+	key := domain.UserKey{ID: c.ID, Name: c.Value, Email: c.Value}
+	if c.Time != nil {
+		key.CreatedAt = *c.Time
+	}
+	return key
+}
+
+// ListUsers は条件に合うユーザーを並び順に、token が指す位置から返す。
+// token が空なら先頭のページを返す
+func (s *UserService) ListUsers(params UserListParams, token string) (*UserPage, error) {
+	filter, sort, limit := params.Filter, params.Sort, params.Limit
+	if sort.Field == "" {
+		sort.Field = domain.UserSortCreatedAt
+	}
+	// 1件多く取得して、続きのページがあるかを判定する
+	query := domain.UserPageQuery{Filter: filter, Sort: sort, Limit: limit + 1}
+	var backward bool
+	if token != "" {
+		var c userCursor
+		if err := s.cursors.Decode(token, &c); err != nil {
+			return nil, ErrInvalidCursor
+		}
+		// 別の並び順で発行されたカーソルは使えない
+		if c.Sort != sort.Field || c.Desc != sort.Desc {
+			return nil, ErrInvalidCursor
+		}
+		key := c.key()
+		if c.Before {
+			query.Before = &key
+			backward = true
+		} else {
+			query.After = &key
+		}
+	}
+
+	users, err := s.repo.FindPage(query)
+	if err != nil {
+		return nil, err
+	}
+	more := len(users) > limit
+	if more {
+		if backward {
+			users = users[1:]
+		} else {
+			users = users[:limit]
+		}
+	}
+
+	page := &UserPage{Users: users}
+	if page.Total, err = s.countUsers(params); err != nil {
+		return nil, err
+	}
+	if len(users) == 0 {
+		return page, nil
+	}
+	// カーソルで移動してきた方向には、元のページがある
+	hasNext := more || backward
+	hasPrev := (more && backward) || (!backward && token != "")
+	page.HasMore = hasNext
+	if hasNext {
+		if page.NextCursor, err = s.cursors.Encode(newUserCursor(users[len(users)-1], sort, false)); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = s.cursors.Encode(newUserCursor(users[0], sort, true)); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
 }
 
-func NewUserService(repo *repository.UserRepository) *UserService {
-	return &UserService{repo: repo}
+func (s *UserService) countUsers(params UserListParams) (*int64, error) {
+	if params.SkipTotal {
+		return nil, nil
+	}
+	total, err := s.repo.Count(params.Filter)
+	if err != nil {
+		return nil, err
+	}
+	return &total, nil
 }
 
-func (s *UserService) GetUsers(page, limit int) ([]*domain.User, error) {
-	offset := (page - 1) * limit
-	return s.repo.FindAll(offset, limit)
+// SearchUsers は名前・メールアドレスの全文検索で、よく合うユーザーから limit 件返す
+func (s *UserService) SearchUsers(q string, limit int) ([]*domain.UserSearchHit, error) {
+	terms := domain.SearchTerms(q)
+	if len(terms) == 0 {
+		return nil, ErrEmptySearchQuery
+	}
+	return s.repo.Search(terms, limit)
 }
 
-// CreateUser creates a new user
-func (s *UserService) CreateUser(user *domain.User) error {
-	// 例: 重複チェック（emailユニークの場合）
-	existing, _ := s.repo.FindByEmail(user.Email)
-	if existing != nil {
-		return fmt.Errorf("email already exists")
+// CreateUser creates a new user. パスワードは平文で受け取り、ハッシュ化して保存する
+func (s *UserService) CreateUser(ctx context.Context, user *domain.User) error {
+	hashed, err := HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hashed
+	// メールアドレスの重複は DB の一意制約で検出し、リポジトリが domain.ErrEmailTaken にする
+	if err := s.repo.Create(user); err != nil {
+		return err
+	}
+	s.audit.Record(ctx, domain.AuditUserCreated, &user.ID, nil, userSnapshot(user))
+
+	if err := s.emailVerification.SendVerification(user, user.Email); err != nil {
+		log.Printf("[ERROR] failed to send verification email: user_id=%d: %v", user.ID, err)
 	}
-	return s.repo.Create(user)
+	return nil
 }
 
 func (s *UserService) GetUserByID(id uint) (*domain.User, error) {
@@ -41,34 +219,103 @@ func (s *UserService) GetUserByEmail(email string) (*domain.User, error) {
 	return s.repo.FindByEmail(email)
 }
 
-// UpdateUser updates an existing user
-func (s *UserService) UpdateUser(user *domain.User) error {
-
-	existingUser, err := s.repo.FindByID(user.ID)
-	log.Printf("[DEBUG] Updating user: %+v", existingUser)
-
+// PatchUser updates the fields set in patch and returns the updated user.
+// version は読み込んだ時点のバージョンで、その後に他の更新があれば domain.ErrVersionMismatch を返す。
+// 今の値と同じフィールドは書き込まず、変わるフィールドが無ければ何も更新しない。
+// パスワードはハッシュ化して保存し、メールアドレスの変更は新しいアドレスの確認が済むまで反映しない
+func (s *UserService) PatchUser(ctx context.Context, id uint, version uint, patch domain.UserPatch) (*domain.User, error) {
+	existingUser, err := s.repo.FindByID(id)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	if existingUser.Version != version {
+		return nil, domain.ErrVersionMismatch
+	}
+	before := userSnapshot(existingUser)
 
-	if user.Name != "" {
-		existingUser.Name = user.Name
+	if patch.Name != nil && *patch.Name == existingUser.Name {
+		patch.Name = nil
 	}
-	if user.Email != "" {
-		existingUser.Email = user.Email
+
+	// 使われているアドレスは先に弾く。確認までの間に他のユーザーが使い始めた場合は確認時に ErrEmailAlreadyExists になる
+	var newEmail string
+	if patch.Email != nil {
+		if email := domain.NormalizeEmail(*patch.Email); email != existingUser.Email {
+			if other, _ := s.repo.FindByEmail(email); other != nil {
+				return nil, ErrEmailAlreadyExists
+			}
+			newEmail = email
+		}
+		patch.Email = nil
 	}
-	if user.Password != "" {
-		hashed, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
+
+	if patch.Password != nil {
+		hashed, err := HashPassword(*patch.Password)
 		if err != nil {
-			return err
+			return nil, err
+		}
+		patch.Password = &hashed
+	}
+
+	updated := existingUser
+	if !patch.IsEmpty() {
+		if err := s.repo.Patch(id, version, patch); err != nil {
+			return nil, err
 		}
-		user.Password = string(hashed)
+		if updated, err = s.repo.FindByID(id); err != nil {
+			return nil, err
+		}
+		s.audit.Record(ctx, domain.AuditUserUpdated, &id, before, userSnapshot(updated))
 	}
 
-	return s.repo.Update(user)
+	if newEmail != "" {
+		if err := s.emailVerification.SendVerification(existingUser, newEmail); err != nil {
+			return nil, err
+		}
+	}
+	return updated, nil
 }
 
-// DeleteUser deletes a user by ID
-func (s *UserService) DeleteUser(id uint) error {
-	return s.repo.Delete(id)
+// DeleteUser soft-deletes a user by ID if it is still at the given version.
+// 猶予期間内であれば RestoreUser で復元でき、過ぎると PurgeDeletedUsers で物理削除される。
+// 削除したユーザーのトークンが使われ続けないよう、先にセッションを失効させる（失効できなければ削除しない）
+func (s *UserService) DeleteUser(ctx context.Context, id uint, version uint) error {
+	current, err := s.repo.FindByID(id)
+	if err != nil {
+		return err
+	}
+	if current.Version != version {
+		return domain.ErrVersionMismatch
+	}
+	if err := s.sessions.RevokeSessions(id); err != nil {
+		return err
+	}
+	if err := s.repo.Delete(id, version); err != nil {
+		return err
+	}
+	if deleted, err := s.repo.FindDeletedByID(id); err == nil {
+		s.audit.Record(ctx, domain.AuditUserDeleted, &id, nil, map[string]interface{}{"deleted_at": timeValue(deleted.DeletedAt)})
+	}
+	return nil
+}
+
+// RestoreUser restores a soft-deleted user within the grace period
+func (s *UserService) RestoreUser(ctx context.Context, id uint) (*domain.User, error) {
+	if err := s.repo.Restore(id, time.Now().Add(-s.deleteGracePeriod)); err != nil {
+		return nil, err
+	}
+	s.audit.Record(ctx, domain.AuditUserRestored, &id, nil, nil)
+	return s.repo.FindByID(id)
+}
+
+// PurgeDeletedUsers permanently removes users whose grace period has expired
+func (s *UserService) PurgeDeletedUsers(ctx context.Context) (int64, error) {
+	purged, err := s.repo.Purge(time.Now().Add(-s.deleteGracePeriod))
+	if err != nil {
+		return 0, err
+	}
+	for _, id := range purged {
+		s.audit.Record(ctx, domain.AuditUserPurged, &id, nil, nil)
+	}
+	return int64(len(purged)), nil
 }
@@ -0,0 +1,252 @@
+package service_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/cursor"
+	"github.com/okamuuu/go-user-app/internal/domain"
+	"github.com/okamuuu/go-user-app/internal/repository"
+	"github.com/okamuuu/go-user-app/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+func newUserService(db *gorm.DB, gracePeriod time.Duration) *service.UserService {
+	return service.NewUserService(
+		repository.NewUserRepository(db),
+		newEmailVerificationService(db, &recordingNotifier{}),
+		newAuthService(db),
+		newAuditService(db),
+		newCursorCodec(),
+		gracePeriod,
+	)
+}
+
+func newCursorCodec() *cursor.Codec {
+	return cursor.NewCodec([]byte("test-cursor-secret"))
+}
+
+func TestUserService_ListUsers(t *testing.T) {
+	store := repository.NewMemoryUserStore()
+	svc := service.NewUserService(store, nil, nil, nil, newCursorCodec(), time.Hour)
+
+	var ids []uint
+	for _, name := range []string{"a", "b", "c", "d", "e"} {
+		user := &domain.User{Name: name, Email: name + "@example.com", Password: "password123"}
+		store.Create(user)
+		ids = append(ids, user.ID)
+	}
+	pageIDs := func(page *service.UserPage) []uint {
+		var result []uint
+		for _, u := range page.Users {
+			result = append(result, u.ID)
+		}
+		return result
+	}
+
+	params := service.UserListParams{Limit: 2}
+	first, err := svc.ListUsers(params, "")
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[0], ids[1]}, pageIDs(first))
+	assert.Empty(t, first.PrevCursor, "first page has no prev")
+	assert.True(t, first.HasMore)
+	require.NotNil(t, first.Total)
+	assert.Equal(t, int64(5), *first.Total)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := svc.ListUsers(params, first.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[2], ids[3]}, pageIDs(second))
+	require.NotEmpty(t, second.PrevCursor)
+
+	last, err := svc.ListUsers(params, second.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{ids[4]}, pageIDs(last))
+	assert.Empty(t, last.NextCursor, "last page has no next")
+	assert.False(t, last.HasMore)
+
+	// prev で戻ると、同じページが同じ境界で返る
+	back, err := svc.ListUsers(params, second.PrevCursor)
+	require.NoError(t, err)
+	assert.Equal(t, pageIDs(first), pageIDs(back))
+	assert.Empty(t, back.PrevCursor)
+	assert.Equal(t, first.NextCursor, back.NextCursor)
+
+	// ページの間にユーザーが増えても、続きのページはずれない
+	store.Create(&domain.User{Name: "f", Email: "f@example.com", Password: "password123"})
+	again, err := svc.ListUsers(params, first.NextCursor)
+	require.NoError(t, err)
+	assert.Equal(t, pageIDs(second), pageIDs(again))
+
+	// 別の並び順のカーソルは使えない
+	_, err = svc.ListUsers(service.UserListParams{Sort: domain.UserSort{Desc: true}, Limit: 2}, first.NextCursor)
+	assert.ErrorIs(t, err, service.ErrInvalidCursor)
+	_, err = svc.ListUsers(params, first.NextCursor+"x")
+	assert.ErrorIs(t, err, service.ErrInvalidCursor)
+	_, err = svc.ListUsers(params, "garbage")
+	assert.ErrorIs(t, err, service.ErrInvalidCursor)
+}
+
+func TestUserService_GetUsers(t *testing.T) {
+	store := repository.NewMemoryUserStore()
+	svc := service.NewUserService(store, nil, nil, nil, newCursorCodec(), time.Hour)
+	for _, name := range []string{"a", "b", "c"} {
+		store.Create(&domain.User{Name: name, Email: name + "@example.com", Password: "password123"})
+	}
+
+	page, err := svc.GetUsers(service.UserListParams{Limit: 2}, 1)
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 2)
+	assert.True(t, page.HasMore)
+	require.NotNil(t, page.Total)
+	assert.Equal(t, int64(3), *page.Total)
+
+	page, err = svc.GetUsers(service.UserListParams{Limit: 2, SkipTotal: true}, 2)
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 1)
+	assert.False(t, page.HasMore)
+	assert.Nil(t, page.Total, "count is skipped")
+}
+
+func TestUserService_DeleteAndRestore(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	// ユーザーの保存先はメモリ上のストアで十分
+	store := repository.NewMemoryUserStore()
+	svc := service.NewUserService(store, newEmailVerificationService(db, &recordingNotifier{}), newAuthService(db), newAuditService(db), newCursorCodec(), time.Hour)
+
+	user := &domain.User{Name: "Test", Email: "test@example.com", Password: "password123"}
+	store.Create(user)
+
+	assert.NoError(t, svc.DeleteUser(ctx, user.ID, user.Version))
+	_, err := svc.GetUserByID(user.ID)
+	assert.Error(t, err, "deleted user is hidden")
+	assert.ErrorIs(t, svc.DeleteUser(ctx, user.ID, user.Version), service.ErrUserNotFound)
+
+	restored, err := svc.RestoreUser(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, user.Email, restored.Email)
+
+	_, err = svc.RestoreUser(ctx, user.ID)
+	assert.ErrorIs(t, err, service.ErrUserNotFound, "not deleted")
+}
+
+func TestUserService_DeleteUser_RevokesSessions(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	svc := newUserService(db, time.Hour)
+
+	user := &domain.User{Name: "Test", Email: "test@example.com", Password: hashPassword("password123")}
+	require.NoError(t, repository.NewUserRepository(db).Create(user))
+	login, err := newAuthService(db).Login(ctx, "test@example.com", "password123")
+	require.NoError(t, err)
+	issuedAt := time.Now().Add(-time.Second)
+
+	// バージョンが古ければ削除せず、セッションも失効させない
+	assert.ErrorIs(t, svc.DeleteUser(ctx, user.ID, user.Version+1), domain.ErrVersionMismatch)
+	revoked, err := repository.NewRevocationRepository(db).IsRevoked("jti", user.ID, issuedAt)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, svc.DeleteUser(ctx, user.ID, user.Version))
+
+	// 削除前に発行したアクセストークンもリフレッシュトークンも使えない
+	revoked, err = repository.NewRevocationRepository(db).IsRevoked("jti", user.ID, issuedAt)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = newAuthService(db).Refresh(ctx, login.Tokens.RefreshToken)
+	assert.Error(t, err)
+	tokens, err := repository.NewRefreshTokenRepository(db).FindByUser(user.ID)
+	require.NoError(t, err)
+	require.NotEmpty(t, tokens)
+	for _, token := range tokens {
+		assert.True(t, token.IsRevoked())
+	}
+}
+
+func TestUserService_PatchUser(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	notifier := &recordingNotifier{}
+	svc := service.NewUserService(repository.NewUserRepository(db), newEmailVerificationService(db, notifier), newAuthService(db), newAuditService(db), newCursorCodec(), time.Hour)
+
+	user := &domain.User{Name: "Test", Email: "test@example.com", Password: "hashed"}
+	require.NoError(t, repository.NewUserRepository(db).Create(user))
+
+	// 指定したフィールドだけが変わる。パスワードはハッシュ化して保存する
+	password := "newpassword"
+	updated, err := svc.PatchUser(ctx, user.ID, user.Version, domain.UserPatch{Password: &password})
+	require.NoError(t, err)
+	assert.Equal(t, "Test", updated.Name)
+	assert.Equal(t, user.Version+1, updated.Version)
+	assert.NotEqual(t, password, updated.Password)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(updated.Password), []byte(password)))
+
+	// 今の値と同じなら何も書き込まず、バージョンも変わらない
+	name := "Test"
+	same, err := svc.PatchUser(ctx, user.ID, updated.Version, domain.UserPatch{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, updated.Version, same.Version)
+
+	// メールアドレスは確認が済むまで変わらない
+	email := "New@Example.com"
+	pending, err := svc.PatchUser(ctx, user.ID, same.Version, domain.UserPatch{Email: &email})
+	require.NoError(t, err)
+	assert.Equal(t, "test@example.com", pending.Email)
+	require.Len(t, notifier.messages, 1)
+	assert.Equal(t, "new@example.com", notifier.messages[0].To)
+}
+
+func TestUserService_PatchUser_VersionMismatch(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	svc := newUserService(db, time.Hour)
+
+	user := &domain.User{Name: "Test", Email: "test@example.com", Password: "password123"}
+	require.NoError(t, repository.NewUserRepository(db).Create(user))
+	staleVersion := user.Version
+
+	// 先に更新した方だけが反映され、同じバージョンから更新した後の方は 412 になる
+	first, second := "First", "Second"
+	updated, err := svc.PatchUser(ctx, user.ID, staleVersion, domain.UserPatch{Name: &first})
+	require.NoError(t, err)
+	assert.Equal(t, staleVersion+1, updated.Version)
+
+	_, err = svc.PatchUser(ctx, user.ID, staleVersion, domain.UserPatch{Name: &second})
+	assert.ErrorIs(t, err, domain.ErrVersionMismatch)
+	assert.ErrorIs(t, svc.DeleteUser(ctx, user.ID, staleVersion), domain.ErrVersionMismatch)
+
+	found, err := svc.GetUserByID(user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "First", found.Name)
+}
+
+func TestUserService_RestoreAfterGracePeriod(t *testing.T) {
+	db := setupTestDB()
+	ctx := context.Background()
+	svc := newUserService(db, time.Hour)
+
+	user := &domain.User{Name: "Test", Email: "test@example.com", Password: "password123"}
+	repository.NewUserRepository(db).Create(user)
+	assert.NoError(t, svc.DeleteUser(ctx, user.ID, user.Version))
+
+	// 猶予期間より前に削除されたことにする
+	db.Unscoped().Model(&repository.User{}).Where("id = ?", user.ID).Update("deleted_at", time.Now().Add(-2*time.Hour))
+
+	_, err := svc.RestoreUser(ctx, user.ID)
+	assert.ErrorIs(t, err, service.ErrUserNotFound)
+
+	purged, err := svc.PurgeDeletedUsers(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	// 物理削除したユーザーのIDを監査ログに残す
+	events, err := newAuditService(db).List(domain.AuditFilter{Action: domain.AuditUserPurged}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, user.ID, *events[0].TargetID)
+}
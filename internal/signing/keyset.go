@@ -0,0 +1,131 @@
+package signing
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"errors"
+	"fmt"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownKey        = errors.New("unknown signing key")
+	ErrUnexpectedMethod  = errors.New("unexpected signing method")
+	ErrUnsupportedKey    = errors.New("unsupported key type")
+	ErrNoSigningKey      = errors.New("no signing key configured")
+	ErrDuplicateKeyID    = errors.New("duplicate key id")
+	ErrPrivateKeyMissing = errors.New("private key required for signing")
+)
+
+// Key はJWTの署名・検証に使う鍵
+// 検証専用の鍵（ローテーション前の公開鍵など）は Private が nil になる
+type Key struct {
+	ID      string
+	Method  jwt.SigningMethod
+	Private crypto.PrivateKey
+	Public  crypto.PublicKey
+}
+
+// NewKey は鍵の種類から署名アルゴリズムを決めて Key を作成する
+// RSA → RS256, Ed25519 → EdDSA, []byte → HS256
+func NewKey(id string, key interface{}) (*Key, error) {
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Private: k, Public: &k.PublicKey}, nil
+	case *rsa.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodRS256, Public: k}, nil
+	case ed25519.PrivateKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Private: k, Public: k.Public()}, nil
+	case ed25519.PublicKey:
+		return &Key{ID: id, Method: jwt.SigningMethodEdDSA, Public: k}, nil
+	case []byte:
+		// HMAC は共通鍵なので署名・検証ともに同じ値を使う
+		return &Key{ID: id, Method: jwt.SigningMethodHS256, Private: k, Public: k}, nil
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedKey, key)
+	}
+}
+
+// KeySet は署名用の鍵1つと、検証に使える鍵の集合を保持する
+// 古い鍵を検証用に残しておくことで、ログイン中のユーザーを維持したまま鍵をローテーションできる
+type KeySet struct {
+	signing *Key
+	keys    map[string]*Key
+	order   []string
+}
+
+// NewKeySet は署名用の鍵と追加の検証用の鍵から KeySet を作成する
+func NewKeySet(signingKey *Key, verificationKeys ...*Key) (*KeySet, error) {
+	if signingKey == nil {
+		return nil, ErrNoSigningKey
+	}
+	if signingKey.Private == nil {
+		return nil, ErrPrivateKeyMissing
+	}
+
+	ks := &KeySet{signing: signingKey, keys: map[string]*Key{}}
+	for _, k := range append([]*Key{signingKey}, verificationKeys...) {
+		if _, exists := ks.keys[k.ID]; exists {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicateKeyID, k.ID)
+		}
+		ks.keys[k.ID] = k
+		ks.order = append(ks.order, k.ID)
+	}
+	return ks, nil
+}
+
+// NewHMACKeySet は共通鍵（HS256）だけの KeySet を作成する
+func NewHMACKeySet(secret []byte) *KeySet {
+	key, _ := NewKey("default", secret)
+	ks, _ := NewKeySet(key)
+	return ks
+}
+
+// SigningKeyID は現在の署名鍵の kid を返す
+func (ks *KeySet) SigningKeyID() string {
+	return ks.signing.ID
+}
+
+// Sign はクレームに署名し、ヘッダーに kid を付与したトークンを返す
+func (ks *KeySet) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(ks.signing.Method, claims)
+	token.Header["kid"] = ks.signing.ID
+	return token.SignedString(ks.signing.Private)
+}
+
+// Keyfunc は jwt.Parse に渡す鍵の解決関数
+// kid に対応する鍵を探し、アルゴリズムが鍵の種類と一致することを検証する
+func (ks *KeySet) Keyfunc(token *jwt.Token) (interface{}, error) {
+	key := ks.signing
+	if kid, ok := token.Header["kid"].(string); ok {
+		key, ok = ks.keys[kid]
+		if !ok {
+			return nil, ErrUnknownKey
+		}
+	}
+
+	if token.Method.Alg() != key.Method.Alg() {
+		return nil, ErrUnexpectedMethod
+	}
+	return key.Public, nil
+}
+
+// Parse はトークンを検証してクレームを返す
+func (ks *KeySet) Parse(tokenString string, claims jwt.Claims) (*jwt.Token, error) {
+	return jwt.ParseWithClaims(tokenString, claims, ks.Keyfunc, jwt.WithValidMethods(ks.methods()))
+}
+
+func (ks *KeySet) methods() []string {
+	var methods []string
+	seen := map[string]bool{}
+	for _, id := range ks.order {
+		alg := ks.keys[id].Method.Alg()
+		if !seen[alg] {
+			seen[alg] = true
+			methods = append(methods, alg)
+		}
+	}
+	return methods
+}
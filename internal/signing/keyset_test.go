@@ -0,0 +1,128 @@
+package signing_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/okamuuu/go-user-app/internal/signing"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"user_id": 1,
+		"exp":     time.Now().Add(time.Minute).Unix(),
+	}
+}
+
+func TestKeySet_SignAndParse(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+
+	for name, raw := range map[string]interface{}{"RS256": rsaKey, "EdDSA": edKey, "HS256": []byte("secret")} {
+		t.Run(name, func(t *testing.T) {
+			key, err := signing.NewKey("k1", raw)
+			require.NoError(t, err)
+			ks, err := signing.NewKeySet(key)
+			require.NoError(t, err)
+
+			tokenString, err := ks.Sign(newClaims())
+			require.NoError(t, err)
+
+			token, err := ks.Parse(tokenString, jwt.MapClaims{})
+			require.NoError(t, err)
+			assert.True(t, token.Valid)
+			assert.Equal(t, "k1", token.Header["kid"])
+			assert.Equal(t, name, token.Method.Alg())
+		})
+	}
+}
+
+func TestKeySet_Rotation(t *testing.T) {
+	_, oldPriv, _ := ed25519.GenerateKey(rand.Reader)
+	_, newPriv, _ := ed25519.GenerateKey(rand.Reader)
+
+	oldKey, _ := signing.NewKey("old", oldPriv)
+	oldSet, _ := signing.NewKeySet(oldKey)
+	issued, err := oldSet.Sign(newClaims())
+	require.NoError(t, err)
+
+	// 新しい鍵で署名しつつ、古い鍵の公開鍵は検証用に残す
+	newKey, _ := signing.NewKey("new", newPriv)
+	oldPublic, _ := signing.NewKey("old", oldPriv.Public())
+	rotated, err := signing.NewKeySet(newKey, oldPublic)
+	require.NoError(t, err)
+
+	_, err = rotated.Parse(issued, jwt.MapClaims{})
+	assert.NoError(t, err, "token signed by the previous key should still verify")
+
+	// 検証鍵から外れた鍵で署名されたトークンは拒否される
+	onlyNew, _ := signing.NewKeySet(newKey)
+	_, err = onlyNew.Parse(issued, jwt.MapClaims{})
+	assert.ErrorIs(t, err, signing.ErrUnknownKey)
+
+	assert.Len(t, rotated.JWKS().Keys, 2)
+}
+
+func TestKeySet_RejectsAlgorithmConfusion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key, _ := signing.NewKey("k1", rsaKey)
+	ks, _ := signing.NewKeySet(key)
+
+	// 公開鍵を HMAC の共通鍵として使った偽造トークン
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, newClaims())
+	forged.Header["kid"] = "k1"
+	pubDER := x509.MarshalPKCS1PublicKey(&rsaKey.PublicKey)
+	tokenString, err := forged.SignedString(pubDER)
+	require.NoError(t, err)
+
+	_, err = ks.Parse(tokenString, jwt.MapClaims{})
+	assert.Error(t, err)
+}
+
+func TestKeySet_JWKSExcludesHMAC(t *testing.T) {
+	ks := signing.NewHMACKeySet([]byte("secret"))
+	assert.Empty(t, ks.JWKS().Keys)
+}
+
+func TestLoadKeySet(t *testing.T) {
+	dir := t.TempDir()
+
+	_, edPriv, _ := ed25519.GenerateKey(rand.Reader)
+	privDER, err := x509.MarshalPKCS8PrivateKey(edPriv)
+	require.NoError(t, err)
+	signingPath := filepath.Join(dir, "signing.pem")
+	require.NoError(t, os.WriteFile(signingPath, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privDER}), 0o600))
+
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	pubDER, err := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	require.NoError(t, err)
+	pubPath := filepath.Join(dir, "old.pub.pem")
+	require.NoError(t, os.WriteFile(pubPath, pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pubDER}), 0o600))
+
+	ks, err := signing.LoadKeySet("2025-01", signingPath, "2024-07="+pubPath)
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01", ks.SigningKeyID())
+
+	jwks := ks.JWKS()
+	require.Len(t, jwks.Keys, 2)
+	assert.Equal(t, "OKP", jwks.Keys[0].Kty)
+	assert.Equal(t, "EdDSA", jwks.Keys[0].Alg)
+	assert.Equal(t, "RSA", jwks.Keys[1].Kty)
+	assert.Equal(t, "AQAB", jwks.Keys[1].E)
+
+	// 検証専用の公開鍵は署名鍵にできない
+	_, err = signing.LoadKeySet("2024-07", pubPath, "")
+	assert.ErrorIs(t, err, signing.ErrPrivateKeyMissing)
+}
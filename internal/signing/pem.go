@@ -0,0 +1,80 @@
+package signing
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// LoadKeyFile は PEM ファイルから鍵を読み込む
+// 秘密鍵（PKCS#8 / PKCS#1）なら署名にも使え、公開鍵（PKIX）なら検証専用になる
+func LoadKeyFile(id, path string) (*Key, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParseKeyPEM(id, data)
+}
+
+// ParseKeyPEM は PEM エンコードされた鍵をパースする
+func ParseKeyPEM(id string, data []byte) (*Key, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("invalid PEM data")
+	}
+
+	var (
+		parsed interface{}
+		err    error
+	)
+	switch block.Type {
+	case "PRIVATE KEY":
+		parsed, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		parsed, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		parsed, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		parsed, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("%w: PEM block %q", ErrUnsupportedKey, block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return NewKey(id, parsed)
+}
+
+// LoadKeySet は環境変数で指定された形式の設定から KeySet を作成する
+//
+//	signingKID:  署名に使う鍵の kid
+//	signingPath: 署名に使う秘密鍵の PEM ファイル
+//	extra:       "kid=path,kid=path" 形式の検証用の鍵（ローテーション前の鍵など）
+func LoadKeySet(signingKID, signingPath, extra string) (*KeySet, error) {
+	signingKey, err := LoadKeyFile(signingKID, signingPath)
+	if err != nil {
+		return nil, fmt.Errorf("load signing key: %w", err)
+	}
+
+	var verificationKeys []*Key
+	for _, entry := range strings.Split(extra, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		kid, path, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid verification key entry %q (expected kid=path)", entry)
+		}
+		key, err := LoadKeyFile(strings.TrimSpace(kid), strings.TrimSpace(path))
+		if err != nil {
+			return nil, fmt.Errorf("load verification key %s: %w", kid, err)
+		}
+		verificationKeys = append(verificationKeys, key)
+	}
+
+	return NewKeySet(signingKey, verificationKeys...)
+}
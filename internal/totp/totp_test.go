@@ -0,0 +1,59 @@
+package totp_test
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+
+	"github.com/okamuuu/go-user-app/internal/totp"
+	"github.com/stretchr/testify/assert"
+)
+
+// RFC 6238 Appendix B のテストベクター（SHA1）の下位6桁
+func TestCode_RFC6238(t *testing.T) {
+	secret := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString([]byte("12345678901234567890"))
+
+	cases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range cases {
+		got, err := totp.Code(secret, time.Unix(unix, 0))
+		assert.NoError(t, err)
+		assert.Equal(t, want, got, "T=%d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := totp.GenerateSecret()
+	assert.NoError(t, err)
+
+	now := time.Now()
+	code, _ := totp.Code(secret, now)
+
+	step, ok := totp.Validate(secret, code, now, 1)
+	assert.True(t, ok)
+	assert.Equal(t, totp.Step(now), step)
+
+	// 1ステップ前のコードは skew=1 なら許容される
+	prev, _ := totp.Code(secret, now.Add(-totp.Period))
+	_, ok = totp.Validate(secret, prev, now, 1)
+	assert.True(t, ok)
+
+	// 大きくずれたコードは拒否される
+	old, _ := totp.Code(secret, now.Add(-10*totp.Period))
+	_, ok = totp.Validate(secret, old, now, 1)
+	assert.False(t, ok)
+
+	_, ok = totp.Validate(secret, "abc", now, 1)
+	assert.False(t, ok)
+}
+
+func TestURI(t *testing.T) {
+	uri := totp.URI("Go User App", "test@example.com", "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, "otpauth://totp/Go%20User%20App:test@example.com?algorithm=SHA1&digits=6&issuer=Go+User+App&period=30&secret=JBSWY3DPEHPK3PXP", uri)
+}